@@ -34,17 +34,116 @@ func Init(connectionString string) error {
 	}
 
 	log.Println("Database migrations completed")
+
+	if err := seedCategories(); err != nil {
+		return fmt.Errorf("failed to seed categories: %w", err)
+	}
+
 	return nil
 }
 
 // runMigrations runs GORM auto migrations for all models
 func runMigrations() error {
 	return DB.AutoMigrate(
+		&models.Category{},
+		&models.ProductFamily{},
 		&models.Product{},
+		&models.MediaAsset{},
+		&models.Asset{},
+		&models.AssetUpload{},
+		&models.AnchorTemplate{},
+		&models.AnchorTemplateVersion{},
 		&models.Build{},
+		&models.BuildRevision{},
+		&models.BuildShare{},
+		&models.Review{},
 	)
 }
 
+// defaultCategories mirrors the builder's step sequence and the anchor types
+// used by the 3D editor. It is only inserted into an empty categories table.
+var defaultCategories = []models.Category{
+	{
+		Slug: "CASE", DisplayName: "Case", SortOrder: 10, Icon: "box", Required: true,
+		RequiredSpecs: models.StringList{"max_gpu_length_mm", "max_cpu_cooler_height_mm"},
+		AnchorTypes:   models.StringList{"mobo_mount_area", "psu_bay", "fan_mount_120", "fan_mount_140", "drive_bay_25", "drive_bay_35"},
+	},
+	{
+		Slug: "MOTHERBOARD", DisplayName: "Motherboard", SortOrder: 20, Icon: "circuit-board", Required: true,
+		RequiredSpecs: models.StringList{"socket", "form_factor", "ram_type"},
+		AnchorTypes:   models.StringList{"cpu_socket", "ram_slot", "pcie_x16", "pcie_x4", "pcie_x1", "m2_slot", "sata_port", "mobo_backplate"},
+	},
+	{
+		Slug: "CPU", DisplayName: "CPU", SortOrder: 30, Icon: "cpu", Required: true,
+		RequiredSpecs: models.StringList{"socket", "tdp_watts"},
+		AnchorTypes:   models.StringList{"cpu_bottom", "cooler_plate", "LGA1700", "LGA1851", "AM4", "AM5"},
+	},
+	{
+		Slug: "CPU_COOLER", DisplayName: "CPU Cooler", SortOrder: 40, Icon: "fan", Required: true,
+		RequiredSpecs: models.StringList{"height_mm", "tdp_rating_watts"},
+		AnchorTypes:   models.StringList{"cooler_base", "fan_mount"},
+	},
+	{
+		Slug: "RAM", DisplayName: "RAM", SortOrder: 50, Icon: "memory-stick", Required: true,
+		RequiredSpecs: models.StringList{"type", "capacity_gb"},
+		AnchorTypes:   models.StringList{"ram_edge", "DDR4", "DDR5"},
+	},
+	{
+		Slug: "GPU", DisplayName: "GPU", SortOrder: 60, Icon: "monitor", Required: false,
+		RequiredSpecs: models.StringList{"length_mm"},
+		AnchorTypes:   models.StringList{"pcie_edge"},
+	},
+	{
+		Slug: "STORAGE", DisplayName: "Storage", SortOrder: 70, Icon: "hard-drive", Required: true,
+		RequiredSpecs: models.StringList{"type"},
+		AnchorTypes:   models.StringList{"m2_edge", "sata_plug", "drive_mount"},
+	},
+	{
+		Slug: "PSU", DisplayName: "PSU", SortOrder: 80, Icon: "plug-zap", Required: true,
+		RequiredSpecs: models.StringList{"wattage"},
+		AnchorTypes:   models.StringList{"psu_mount"},
+	},
+}
+
+// seedCategories inserts the default taxonomy when no categories exist yet.
+// Categories already used by existing products are added as well so that
+// editing legacy products does not fail referential validation.
+func seedCategories() error {
+	var count int64
+	if err := DB.Model(&models.Category{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	categories := make([]models.Category, len(defaultCategories))
+	copy(categories, defaultCategories)
+
+	known := make(map[string]bool, len(categories))
+	for _, category := range categories {
+		known[category.Slug] = true
+	}
+
+	var used []string
+	if err := DB.Model(&models.Product{}).Distinct("category").Where("category <> ''").Pluck("category", &used).Error; err != nil {
+		return err
+	}
+	for _, slug := range used {
+		if known[slug] {
+			continue
+		}
+		known[slug] = true
+		categories = append(categories, models.Category{
+			Slug:        slug,
+			DisplayName: slug,
+			SortOrder:   1000,
+		})
+	}
+
+	return DB.Create(&categories).Error
+}
+
 // GetDB returns the database instance
 func GetDB() *gorm.DB {
 	return DB
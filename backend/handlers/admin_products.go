@@ -1,14 +1,22 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"math"
 	"net/http"
+	"reflect"
 	"strconv"
+	"time"
 
 	"fit-pc/db"
+	"fit-pc/internal/jsonpatch"
 	"fit-pc/models"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ProductListQuery struct {
@@ -16,6 +24,8 @@ type ProductListQuery struct {
 	Limit    int    `form:"limit,default=10" binding:"min=1,max=100"`
 	Search   string `form:"search"`
 	Category string `form:"category"`
+	Status   string `form:"status" binding:"omitempty,oneof=draft in_review published archived scheduled"` // scheduled: published with a future publish_at
+	Model    string `form:"model" binding:"omitempty,oneof=flagged unanalyzed"`                            // flagged: invalid or over budget; unanalyzed: model never analysed
 }
 
 type PaginationMeta struct {
@@ -35,13 +45,17 @@ func GetAdminProduct(c *gin.Context) {
 	}
 
 	var product models.Product
-	if err := db.GetDB().First(&product, id).Error; err != nil {
+	if err := db.GetDB().Preload("Family").Preload("Media", orderedMedia).First(&product, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": "Product not found",
 		})
 		return
 	}
 
+	if notModified(c, product.Version) {
+		return
+	}
+
 	c.JSON(http.StatusOK, product)
 }
 
@@ -66,6 +80,21 @@ func GetAdminProducts(c *gin.Context) {
 		dbQuery = dbQuery.Where("category = ?", query.Category)
 	}
 
+	switch query.Status {
+	case "":
+	case "scheduled":
+		dbQuery = dbQuery.Where("status = ? AND publish_at > ?", models.ProductStatusPublished, time.Now())
+	default:
+		dbQuery = dbQuery.Where("status = ?", query.Status)
+	}
+
+	switch query.Model {
+	case "flagged":
+		dbQuery = dbQuery.Where("model_flagged = ?", true)
+	case "unanalyzed":
+		dbQuery = dbQuery.Where("model_url <> '' AND model_metadata IS NULL")
+	}
+
 	var total int64
 	if err := dbQuery.Count(&total).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -107,8 +136,13 @@ type AdminUpdateProductRequest struct {
 	ThumbnailURL   *string                `json:"thumbnail_url"`
 	TechnicalSpecs map[string]interface{} `json:"technical_specs"`
 	AnchorPoints   []models.AnchorPoint   `json:"anchor_points"`
+	FamilyID       *uint                  `json:"family_id"` // 0 detaches the product from its family
 }
 
+// UpdateAdminProduct updates a product (Admin only)
+// A new model is analysed once the update is committed.
+// An edit that would block publishing a published product is rejected with 422.
+// Requires If-Match with the product's current ETag.
 func UpdateAdminProduct(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -126,6 +160,15 @@ func UpdateAdminProduct(c *gin.Context) {
 		return
 	}
 
+	expected, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+	if product.Version != expected {
+		preconditionFailed(c, product.Version, "Product was modified by another user", product)
+		return
+	}
+
 	var req AdminUpdateProductRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -135,6 +178,19 @@ func UpdateAdminProduct(c *gin.Context) {
 		return
 	}
 
+	if req.Category != nil || req.AnchorPoints != nil {
+		category := ""
+		if req.Category != nil {
+			category = *req.Category
+		}
+		if !checkCategoryRefs(c, category, req.AnchorPoints) {
+			return
+		}
+	}
+	if !checkAssetURLs(c, req.ModelURL, req.ThumbnailURL, product.ModelURL, product.ThumbnailURL) {
+		return
+	}
+
 	updates := make(map[string]interface{})
 
 	if req.Name != nil {
@@ -154,12 +210,23 @@ func UpdateAdminProduct(c *gin.Context) {
 	}
 	if req.ThumbnailURL != nil {
 		updates["thumbnail_url"] = *req.ThumbnailURL
+		updates["thumbnail_sizes"] = thumbnailSizes(db.GetDB(), *req.ThumbnailURL)
 	}
 	if req.TechnicalSpecs != nil {
 		updates["technical_specs"] = models.TechnicalSpecs(req.TechnicalSpecs)
 	}
 	if req.AnchorPoints != nil {
 		updates["anchor_points"] = models.AnchorPoints(req.AnchorPoints)
+		withoutAnchorTemplate(updates)
+	}
+	if req.FamilyID != nil || req.Category != nil {
+		familyID, ok := familyUpdate(c, product, req.FamilyID, req.Category)
+		if !ok {
+			return
+		}
+		if req.FamilyID != nil {
+			updates["family_id"] = familyID
+		}
 	}
 
 	if len(updates) == 0 {
@@ -169,16 +236,314 @@ func UpdateAdminProduct(c *gin.Context) {
 		return
 	}
 
-	if err := db.GetDB().Model(&product).Updates(updates).Error; err != nil {
+	updated := false
+	err = db.GetDB().Transaction(func(tx *gorm.DB) error {
+		check, err := guardPublished(tx, "id = ?", product.ID)
+		if err != nil {
+			return err
+		}
+		updated, err = updateVersioned(tx, &product, expected, updates)
+		if err != nil || !updated {
+			return err
+		}
+		return check()
+	})
+	if respondPublishBlocked(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update product",
+			"details": err.Error(),
+		})
+		return
+	}
+	if updated && modelChanged(updates) {
+		reanalyzeModels("id = ?", product.ID)
+	}
+
+	db.GetDB().First(&product, id)
+
+	if !updated {
+		preconditionFailed(c, product.Version, "Product was modified by another user", product)
+		return
+	}
+
+	setETag(c, product.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Product updated successfully",
+		"data":    product,
+	})
+}
+
+// patchableProductFields maps the product document keys a patch may change to
+// their columns. Every other key of the document is read-only.
+var patchableProductFields = map[string]string{
+	"name":            "name",
+	"sku":             "sku",
+	"category":        "category",
+	"price":           "price",
+	"model_url":       "model_url",
+	"thumbnail_url":   "thumbnail_url",
+	"technical_specs": "technical_specs",
+	"anchor_points":   "anchor_points",
+	"family_id":       "family_id",
+}
+
+// patchedProduct holds the patchable fields of a patched product document
+type patchedProduct struct {
+	Name           string                `json:"name"`
+	SKU            string                `json:"sku"`
+	Category       string                `json:"category"`
+	Price          float64               `json:"price"`
+	ModelURL       string                `json:"model_url"`
+	ThumbnailURL   string                `json:"thumbnail_url"`
+	TechnicalSpecs models.TechnicalSpecs `json:"technical_specs"`
+	AnchorPoints   models.AnchorPoints   `json:"anchor_points"`
+	FamilyID       *uint                 `json:"family_id"`
+}
+
+var errProductModified = errors.New("product was modified by another user")
+
+// productPatchError is a patch that is well-formed but cannot be applied to the product
+type productPatchError struct {
+	err error
+}
+
+func (e productPatchError) Error() string { return e.err.Error() }
+
+// patchProductUpdates applies patch to the JSON document of product and returns
+// the column updates it implies, after rejecting changes to read-only keys and
+// validating the result.
+func patchProductUpdates(product models.Product, patch func(interface{}) (interface{}, error)) (map[string]interface{}, error) {
+	raw, err := json.Marshal(product)
+	if err != nil {
+		return nil, err
+	}
+	var original map[string]interface{}
+	if err := json.Unmarshal(raw, &original); err != nil {
+		return nil, err
+	}
+
+	result, err := patch(original)
+	if err != nil {
+		return nil, productPatchError{err}
+	}
+	patchedDoc, ok := result.(map[string]interface{})
+	if !ok {
+		return nil, productPatchError{errors.New("patched product must be a JSON object")}
+	}
+
+	changed := make(map[string]bool)
+	for key := range original {
+		if !reflect.DeepEqual(original[key], patchedDoc[key]) {
+			changed[key] = true
+		}
+	}
+	for key := range patchedDoc {
+		if _, ok := original[key]; !ok {
+			changed[key] = true
+		}
+	}
+	for key := range changed {
+		if _, ok := patchableProductFields[key]; !ok {
+			return nil, productPatchError{fmt.Errorf("field %q is read-only", key)}
+		}
+	}
+
+	raw, err = json.Marshal(patchedDoc)
+	if err != nil {
+		return nil, err
+	}
+	var patched patchedProduct
+	if err := json.Unmarshal(raw, &patched); err != nil {
+		return nil, productPatchError{err}
+	}
+
+	switch {
+	case patched.Name == "":
+		return nil, productPatchError{errors.New("name must not be empty")}
+	case patched.SKU == "":
+		return nil, productPatchError{errors.New("sku must not be empty")}
+	case patched.Category == "":
+		return nil, productPatchError{errors.New("category must not be empty")}
+	case patched.Price < 0:
+		return nil, productPatchError{errors.New("price must not be negative")}
+	}
+
+	if changed["category"] || changed["anchor_points"] {
+		t, err := loadTaxonomy()
+		if err != nil {
+			return nil, err
+		}
+		if err := t.validate(patched.Category, patched.AnchorPoints); err != nil {
+			return nil, productPatchError{err}
+		}
+	}
+	if changed["category"] || changed["family_id"] {
+		if err := validateFamilyAssignment(patched.FamilyID, patched.Category); err != nil {
+			return nil, productPatchError{err}
+		}
+		if patched.FamilyID != nil && *patched.FamilyID == 0 {
+			patched.FamilyID = nil
+		}
+	}
+	if changed["model_url"] {
+		if err := checkAssetURL(db.GetDB(), patched.ModelURL, models.MediaKindModel); err != nil {
+			return nil, productPatchError{err}
+		}
+	}
+	if changed["thumbnail_url"] {
+		if err := checkAssetURL(db.GetDB(), patched.ThumbnailURL, models.MediaKindImage); err != nil {
+			return nil, productPatchError{err}
+		}
+	}
+
+	values := map[string]interface{}{
+		"name":            patched.Name,
+		"sku":             patched.SKU,
+		"category":        patched.Category,
+		"price":           patched.Price,
+		"model_url":       patched.ModelURL,
+		"thumbnail_url":   patched.ThumbnailURL,
+		"technical_specs": patched.TechnicalSpecs,
+		"anchor_points":   patched.AnchorPoints,
+		"family_id":       patched.FamilyID,
+	}
+	updates := make(map[string]interface{}, len(changed))
+	for key := range changed {
+		updates[patchableProductFields[key]] = values[key]
+	}
+	if changed["thumbnail_url"] {
+		updates["thumbnail_sizes"] = thumbnailSizes(db.GetDB(), patched.ThumbnailURL)
+	}
+	if changed["anchor_points"] {
+		withoutAnchorTemplate(updates)
+	}
+	return updates, nil
+}
+
+// PatchAdminProduct partially updates a product (Admin only)
+// Accepts a JSON Merge Patch (application/merge-patch+json, RFC 7386) or a JSON Patch
+// (application/json-patch+json, RFC 6902) applied to the product document, so single
+// spec keys or anchors can be changed without resending the whole map.
+// The patch is applied to the product read under a row lock.
+// A patch that would block publishing a published product is rejected with 422.
+// A new model is analysed once the patch is committed. Requires If-Match.
+// PATCH /api/admin/products/:id
+func PatchAdminProduct(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	body, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Failed to read request body",
+		})
+		return
+	}
+
+	var patch func(interface{}) (interface{}, error)
+	switch c.ContentType() {
+	case jsonpatch.MediaTypeMergePatch:
+		var mergePatch interface{}
+		if err := json.Unmarshal(body, &mergePatch); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid patch document",
+				"details": err.Error(),
+			})
+			return
+		}
+		patch = func(doc interface{}) (interface{}, error) {
+			return jsonpatch.MergePatch(doc, mergePatch), nil
+		}
+	case jsonpatch.MediaTypeJSONPatch:
+		operations, err := jsonpatch.DecodePatch(body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid patch document",
+				"details": err.Error(),
+			})
+			return
+		}
+		patch = operations.Apply
+	default:
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{
+			"error":    "Unsupported patch format",
+			"accepted": []string{jsonpatch.MediaTypeMergePatch, jsonpatch.MediaTypeJSONPatch},
+		})
+		return
+	}
+
+	expected, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+
+	var product models.Product
+	analyze := false
+	err = db.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, id).Error; err != nil {
+			return err
+		}
+		if product.Version != expected {
+			return errProductModified
+		}
+
+		updates, err := patchProductUpdates(product, patch)
+		if err != nil {
+			return err
+		}
+
+		check, err := guardPublished(tx, "id = ?", product.ID)
+		if err != nil {
+			return err
+		}
+		if _, err := updateVersioned(tx, &product, expected, updates); err != nil {
+			return err
+		}
+		analyze = modelChanged(updates)
+		return check()
+	})
+
+	if respondPublishBlocked(c, err) {
+		return
+	}
+	var patchErr productPatchError
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Product not found",
+		})
+		return
+	case errors.Is(err, errProductModified):
+		preconditionFailed(c, product.Version, "Product was modified by another user", product)
+		return
+	case errors.As(err, &patchErr):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":   "Patch cannot be applied",
+			"details": patchErr.Error(),
+		})
+		return
+	case err != nil:
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to update product",
 			"details": err.Error(),
 		})
 		return
 	}
+	if analyze {
+		reanalyzeModels("id = ?", product.ID)
+	}
 
 	db.GetDB().First(&product, id)
 
+	setETag(c, product.Version)
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Product updated successfully",
 		"data":    product,
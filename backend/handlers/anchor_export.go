@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"fit-pc/db"
+	"fit-pc/internal/gltf"
+	"fit-pc/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportProductModel downloads the product's model with its anchor points written
+// into it as anchor_<label> nodes, replacing the anchor nodes the model had (Admin only).
+// The nodes carry the anchor metadata in their extras, so the file can be edited in
+// Blender and imported again with POST /api/admin/products/:id/anchors/import.
+// GET /api/admin/products/:id/anchors/export
+func ExportProductModel(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	var product models.Product
+	if err := db.GetDB().Preload("Family").First(&product, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Product not found",
+		})
+		return
+	}
+	resolved := product.Resolved()
+
+	if resolved.ModelURL == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Product has no 3D model",
+		})
+		return
+	}
+
+	data, err := fetchStoredModel(resolved.ModelURL)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":   "Failed to load the product's model",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	exported, err := gltf.WithAnchors(data, resolved.AnchorPoints)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, gltf.ErrInvalidModel) {
+			status = http.StatusUnprocessableEntity
+		}
+		c.JSON(status, gin.H{
+			"error":   "Failed to export the model",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	contentType, ext := gltf.MediaTypeJSON, ".gltf"
+	if gltf.IsBinary(exported) {
+		contentType, ext = gltf.MediaTypeBinary, ".glb"
+	}
+
+	setETag(c, product.Version)
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": product.SKU + "-anchors" + ext}))
+	c.Data(http.StatusOK, contentType, exported)
+}
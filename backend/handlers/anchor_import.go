@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
+
+	"fit-pc/db"
+	"fit-pc/internal/gltf"
+	"fit-pc/internal/storage"
+	"fit-pc/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxModelImportSize limits the size of a model parsed for anchor points
+const maxModelImportSize = 64 << 20
+
+// modelFetchTimeout bounds the download of a stored model
+const modelFetchTimeout = 30 * time.Second
+
+var errModelTooLarge = fmt.Errorf("model exceeds %d MB", maxModelImportSize>>20)
+
+// AnchorImportResponse is the proposal returned by ImportProductAnchors. AnchorPoints
+// is the product's anchor list with the imported anchors merged in by label; the
+// other lists name the labels in each merge outcome.
+type AnchorImportResponse struct {
+	AnchorPoints models.AnchorPoints `json:"anchor_points"`
+	Imported     models.AnchorPoints `json:"imported"`
+	Added        []string            `json:"added"`
+	Updated      []string            `json:"updated"`
+	Unchanged    []string            `json:"unchanged"`
+	Kept         []string            `json:"kept"`
+	Warnings     []string            `json:"warnings"`
+}
+
+// anchorKey identifies an anchor point by its label, or by its type for older
+// anchors without a label
+func anchorKey(anchor models.AnchorPoint) string {
+	if anchor.Label != "" {
+		return anchor.Label
+	}
+	return anchor.Name
+}
+
+// mergeImportedAnchors merges imported anchors into the current ones by label.
+// Imported anchors replace the transform of a current anchor with the same label;
+// its type, direction and compatible types are only replaced when the model sets
+// them. Current anchors missing from the model are kept, new ones are appended.
+func mergeImportedAnchors(current, imported models.AnchorPoints) AnchorImportResponse {
+	result := AnchorImportResponse{
+		AnchorPoints: cloneAnchorPoints(current),
+		Imported:     imported,
+		Added:        []string{},
+		Updated:      []string{},
+		Unchanged:    []string{},
+		Kept:         []string{},
+		Warnings:     []string{},
+	}
+	if result.AnchorPoints == nil {
+		result.AnchorPoints = models.AnchorPoints{}
+	}
+
+	index := make(map[string]int, len(current))
+	for i, anchor := range current {
+		index[anchorKey(anchor)] = i
+	}
+
+	matched := make(map[string]bool, len(imported))
+	for _, anchor := range imported {
+		i, ok := index[anchor.Label]
+		if !ok {
+			result.AnchorPoints = append(result.AnchorPoints, anchor)
+			result.Added = append(result.Added, anchor.Label)
+			continue
+		}
+		matched[anchor.Label] = true
+
+		merged := result.AnchorPoints[i]
+		merged.Label = anchor.Label
+		merged.Position = anchor.Position
+		merged.Rotation = anchor.Rotation
+		merged.ConnectionAxis = anchor.ConnectionAxis
+		if anchor.Name != "" {
+			merged.Name = anchor.Name
+		}
+		if anchor.Direction != "" {
+			merged.Direction = anchor.Direction
+		}
+		if len(anchor.CompatibleTypes) > 0 {
+			merged.CompatibleTypes = anchor.CompatibleTypes
+		}
+
+		if anchorsEqual(merged, current[i]) {
+			result.Unchanged = append(result.Unchanged, anchor.Label)
+		} else {
+			result.AnchorPoints[i] = merged
+			result.Updated = append(result.Updated, anchor.Label)
+		}
+	}
+
+	for _, anchor := range current {
+		if key := anchorKey(anchor); !matched[key] {
+			result.Kept = append(result.Kept, key)
+		}
+	}
+	return result
+}
+
+// anchorsEqual compares two anchor points, ignoring sub-micron differences
+func anchorsEqual(a, b models.AnchorPoint) bool {
+	near := func(u, v models.Vector3) bool {
+		return math.Abs(u.X-v.X) < 1e-6 && math.Abs(u.Y-v.Y) < 1e-6 && math.Abs(u.Z-v.Z) < 1e-6
+	}
+	if a.Name != b.Name || a.Label != b.Label || a.Direction != b.Direction || a.ConnectionAxis != b.ConnectionAxis ||
+		!near(a.Position, b.Position) || !near(a.Rotation, b.Rotation) || len(a.CompatibleTypes) != len(b.CompatibleTypes) {
+		return false
+	}
+	for i := range a.CompatibleTypes {
+		if a.CompatibleTypes[i] != b.CompatibleTypes[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// fetchStoredModel reads a model from the blob store by its URL
+func fetchStoredModel(modelURL string) ([]byte, error) {
+	parsed, err := url.Parse(modelURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid model URL")
+	}
+	blobName := path.Base(parsed.Path)
+	if !isValidBlobName(blobName) {
+		return nil, fmt.Errorf("model URL does not reference a stored model")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), modelFetchTimeout)
+	defer cancel()
+
+	body, info, err := storage.Get().Open(ctx, blobName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download model: %w", err)
+	}
+	defer body.Close()
+
+	if info.Size > maxModelImportSize {
+		return nil, errModelTooLarge
+	}
+	data, err := io.ReadAll(io.LimitReader(body, maxModelImportSize+1))
+	if err == nil && len(data) > maxModelImportSize {
+		return nil, errModelTooLarge
+	}
+	return data, err
+}
+
+// ImportProductAnchors reads anchor points from a glTF model and returns them as a
+// proposal to merge into the product's anchors, without changing the product (Admin only).
+// The model is the request body, sent as model/gltf-binary, model/gltf+json or
+// application/octet-stream; without a body the product's stored model is read. Anchors are
+// nodes named anchor_<label> or carrying anchor metadata in their extras.
+// The response carries the product's ETag, to apply the proposal with
+// PATCH /api/admin/parts/:id/anchors.
+// POST /api/admin/products/:id/anchors/import
+func ImportProductAnchors(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	var product models.Product
+	if err := db.GetDB().Preload("Family").First(&product, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Product not found",
+		})
+		return
+	}
+	resolved := product.Resolved()
+
+	var data []byte
+	mediaType, _, _ := mime.ParseMediaType(c.ContentType())
+	switch {
+	case c.Request.ContentLength == 0:
+		if resolved.ModelURL == "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Product has no 3D model; upload one in the request body",
+			})
+			return
+		}
+		if data, err = fetchStoredModel(resolved.ModelURL); err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{
+				"error":   "Failed to load the product's model",
+				"details": err.Error(),
+			})
+			return
+		}
+	case mediaType == gltf.MediaTypeBinary || mediaType == gltf.MediaTypeJSON || mediaType == "application/octet-stream":
+		data, err = io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxModelImportSize))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": fmt.Sprintf("Model exceeds %d MB", maxModelImportSize>>20),
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Failed to read model",
+				"details": err.Error(),
+			})
+			return
+		}
+	default:
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{
+			"error": fmt.Sprintf("Content-Type must be %s, %s or application/octet-stream", gltf.MediaTypeBinary, gltf.MediaTypeJSON),
+		})
+		return
+	}
+
+	doc, err := gltf.Decode(data)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":   "Invalid glTF model",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	imported, warnings := doc.Anchors()
+	if len(imported) == 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":    "Model contains no anchor nodes",
+			"warnings": warnings,
+		})
+		return
+	}
+
+	proposal := mergeImportedAnchors(resolved.AnchorPoints, imported)
+	if warnings != nil {
+		proposal.Warnings = warnings
+	}
+
+	setETag(c, product.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("Found %d anchor points: %d new, %d updated", len(imported), len(proposal.Added), len(proposal.Updated)),
+		"data":    proposal,
+	})
+}
@@ -0,0 +1,540 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"fit-pc/db"
+	"fit-pc/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreateAnchorTemplateRequest represents the request body for creating an anchor template.
+// The anchors are taken from SourceProductID or given directly in AnchorPoints; Origin
+// is the point of the source model that becomes the template's reference frame origin.
+type CreateAnchorTemplateRequest struct {
+	Name            string               `json:"name" binding:"required,max=100"`
+	Category        string               `json:"category" binding:"required,max=50"`
+	FormFactor      string               `json:"form_factor" binding:"max=50"`
+	Description     string               `json:"description" binding:"max=500"`
+	SourceProductID *uint                `json:"source_product_id"`
+	AnchorPoints    []models.AnchorPoint `json:"anchor_points"`
+	Origin          models.Vector3       `json:"origin"`
+}
+
+// CreateAnchorTemplateVersionRequest represents the request body for publishing a new template version
+type CreateAnchorTemplateVersionRequest struct {
+	SourceProductID *uint                `json:"source_product_id"`
+	AnchorPoints    []models.AnchorPoint `json:"anchor_points"`
+	Origin          models.Vector3       `json:"origin"`
+	Changelog       string               `json:"changelog" binding:"max=500"`
+}
+
+// ApplyAnchorTemplateRequest represents the request body for applying a template to a product.
+// Positions are scaled per axis, then offset into the product's model space. Version
+// defaults to the template's latest version and Scale to 1 on every axis.
+type ApplyAnchorTemplateRequest struct {
+	TemplateID uint            `json:"template_id" binding:"required"`
+	Version    *int            `json:"version" binding:"omitempty,min=1"`
+	Offset     models.Vector3  `json:"offset"`
+	Scale      *models.Vector3 `json:"scale"`
+}
+
+// withoutAnchorTemplate adds to product updates that write anchor points the
+// columns recording that they no longer come from a template. Only
+// ApplyAnchorTemplate records a template.
+func withoutAnchorTemplate(updates map[string]interface{}) map[string]interface{} {
+	updates["anchor_template_id"] = nil
+	updates["anchor_template_version"] = nil
+	return updates
+}
+
+// templateAnchors returns the anchors for a new template version, relative to origin.
+// It writes the error response and returns false when they cannot be determined.
+func templateAnchors(c *gin.Context, category string, sourceProductID *uint, anchors []models.AnchorPoint, origin models.Vector3) (models.AnchorPoints, bool) {
+	if (sourceProductID == nil) == (anchors == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Provide either source_product_id or anchor_points",
+		})
+		return nil, false
+	}
+
+	if sourceProductID != nil {
+		var source models.Product
+		if err := db.GetDB().Preload("Family").First(&source, *sourceProductID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Source product not found",
+			})
+			return nil, false
+		}
+		if source.Category != category {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Source product is a %s, template is for %s", source.Category, category),
+			})
+			return nil, false
+		}
+		anchors = source.Resolved().AnchorPoints
+	}
+
+	if len(anchors) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Template must contain at least one anchor point",
+		})
+		return nil, false
+	}
+
+	if !checkCategoryRefs(c, category, anchors) {
+		return nil, false
+	}
+
+	negated := models.Vector3{X: -origin.X, Y: -origin.Y, Z: -origin.Z}
+	return models.AnchorPoints(anchors).Transformed(negated, models.Vector3{X: 1, Y: 1, Z: 1}), true
+}
+
+// GetAnchorTemplates returns all anchor templates (Admin only)
+// GET /api/admin/anchor-templates?category=...
+func GetAnchorTemplates(c *gin.Context) {
+	query := db.GetDB().Model(&models.AnchorTemplate{})
+	if category := c.Query("category"); category != "" {
+		query = query.Where("category = ?", category)
+	}
+
+	var templates []models.AnchorTemplate
+	if err := query.Order("category ASC, name ASC").Find(&templates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch anchor templates",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  templates,
+		"count": len(templates),
+	})
+}
+
+// GetAnchorTemplate returns an anchor template with all of its versions (Admin only)
+// GET /api/admin/anchor-templates/:id
+func GetAnchorTemplate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid template ID",
+		})
+		return
+	}
+
+	var template models.AnchorTemplate
+	err = db.GetDB().Preload("Versions", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("version DESC")
+	}).First(&template, id).Error
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Anchor template not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": template,
+	})
+}
+
+// CreateAnchorTemplate creates an anchor template, usually captured from an existing product (Admin only)
+// POST /api/admin/anchor-templates
+func CreateAnchorTemplate(c *gin.Context) {
+	var req CreateAnchorTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+
+	var existing int64
+	db.GetDB().Model(&models.AnchorTemplate{}).Where("name = ?", req.Name).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Anchor template name already exists",
+		})
+		return
+	}
+
+	anchors, ok := templateAnchors(c, req.Category, req.SourceProductID, req.AnchorPoints, req.Origin)
+	if !ok {
+		return
+	}
+
+	template := models.AnchorTemplate{
+		Name:            req.Name,
+		Category:        req.Category,
+		FormFactor:      req.FormFactor,
+		Description:     req.Description,
+		LatestVersion:   1,
+		OriginProductID: req.SourceProductID,
+	}
+
+	err := db.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&template).Error; err != nil {
+			return err
+		}
+		version := models.AnchorTemplateVersion{
+			TemplateID:      template.ID,
+			Version:         1,
+			AnchorPoints:    anchors,
+			Changelog:       "Initial version",
+			SourceProductID: req.SourceProductID,
+		}
+		if err := tx.Create(&version).Error; err != nil {
+			return err
+		}
+		template.Versions = []models.AnchorTemplateVersion{version}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create anchor template",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Anchor template created successfully",
+		"data":    template,
+	})
+}
+
+// CreateAnchorTemplateVersion publishes a new version of an anchor template (Admin only)
+// Products keep the version they were built from until the template is applied again.
+// POST /api/admin/anchor-templates/:id/versions
+func CreateAnchorTemplateVersion(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid template ID",
+		})
+		return
+	}
+
+	var req CreateAnchorTemplateVersionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	var template models.AnchorTemplate
+	if err := db.GetDB().First(&template, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Anchor template not found",
+		})
+		return
+	}
+
+	anchors, ok := templateAnchors(c, template.Category, req.SourceProductID, req.AnchorPoints, req.Origin)
+	if !ok {
+		return
+	}
+
+	var version models.AnchorTemplateVersion
+	err = db.GetDB().Transaction(func(tx *gorm.DB) error {
+		// Bump the counter first so concurrent publishers get distinct version numbers
+		result := tx.Model(&template).UpdateColumn("latest_version", gorm.Expr("latest_version + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if err := tx.First(&template, id).Error; err != nil {
+			return err
+		}
+
+		version = models.AnchorTemplateVersion{
+			TemplateID:      template.ID,
+			Version:         template.LatestVersion,
+			AnchorPoints:    anchors,
+			Changelog:       req.Changelog,
+			SourceProductID: req.SourceProductID,
+		}
+		return tx.Create(&version).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create template version",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": fmt.Sprintf("Anchor template version %d created", version.Version),
+		"data":    version,
+	})
+}
+
+// GetAnchorTemplateProducts lists the products built from an anchor template and
+// whether they use an outdated version (Admin only)
+// GET /api/admin/anchor-templates/:id/products
+func GetAnchorTemplateProducts(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid template ID",
+		})
+		return
+	}
+
+	var template models.AnchorTemplate
+	if err := db.GetDB().First(&template, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Anchor template not found",
+		})
+		return
+	}
+
+	var products []models.Product
+	if err := db.GetDB().Where("anchor_template_id = ?", template.ID).Order("id ASC").Find(&products).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch products",
+		})
+		return
+	}
+
+	type templateProduct struct {
+		ID              uint   `json:"id"`
+		Name            string `json:"name"`
+		SKU             string `json:"sku"`
+		TemplateVersion int    `json:"template_version"`
+		Outdated        bool   `json:"outdated"`
+	}
+
+	result := make([]templateProduct, len(products))
+	outdated := 0
+	for i, product := range products {
+		version := 0
+		if product.AnchorTemplateVersion != nil {
+			version = *product.AnchorTemplateVersion
+		}
+		result[i] = templateProduct{
+			ID:              product.ID,
+			Name:            product.Name,
+			SKU:             product.SKU,
+			TemplateVersion: version,
+			Outdated:        version < template.LatestVersion,
+		}
+		if result[i].Outdated {
+			outdated++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":           result,
+		"count":          len(result),
+		"outdated_count": outdated,
+		"latest_version": template.LatestVersion,
+	})
+}
+
+// DeleteAnchorTemplate deletes an anchor template that no product was built from (Admin only)
+// DELETE /api/admin/anchor-templates/:id
+func DeleteAnchorTemplate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid template ID",
+		})
+		return
+	}
+
+	var template models.AnchorTemplate
+	if err := db.GetDB().First(&template, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Anchor template not found",
+		})
+		return
+	}
+
+	var usage int64
+	db.GetDB().Unscoped().Model(&models.Product{}).Where("anchor_template_id = ?", template.ID).Count(&usage)
+	if usage > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":       "Anchor template is used by products",
+			"usage_count": usage,
+		})
+		return
+	}
+
+	if err := db.GetDB().Delete(&template).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to delete anchor template",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Anchor template deleted successfully",
+	})
+}
+
+// ApplyAnchorTemplate replaces a product's anchor points with a template version,
+// transformed into the product's model space, and records which version was used (Admin only)
+// When the product inherits its anchors from a family, the family anchors are replaced
+// as UpdatePartAnchors does, and no version is recorded as families do not keep one;
+// pass ?scope=variant to store an override on this product instead. Anchors
+// that would block publishing a published product are rejected with 422.
+// Requires If-Match with the product's current ETag.
+// POST /api/admin/products/:id/anchors/template
+func ApplyAnchorTemplate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	var req ApplyAnchorTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	scale := models.Vector3{X: 1, Y: 1, Z: 1}
+	if req.Scale != nil {
+		scale = *req.Scale
+		if scale.X <= 0 || scale.Y <= 0 || scale.Z <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Scale must be positive on every axis",
+			})
+			return
+		}
+	}
+
+	var product models.Product
+	if err := db.GetDB().First(&product, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Product not found",
+		})
+		return
+	}
+
+	var template models.AnchorTemplate
+	if err := db.GetDB().First(&template, req.TemplateID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Anchor template not found",
+		})
+		return
+	}
+
+	expected, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+	if product.Version != expected {
+		preconditionFailed(c, product.Version, "Product was modified by another user", product)
+		return
+	}
+
+	if template.Category != product.Category {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Template is for %s, product is a %s", template.Category, product.Category),
+		})
+		return
+	}
+
+	versionNumber := template.LatestVersion
+	if req.Version != nil {
+		versionNumber = *req.Version
+	}
+
+	var version models.AnchorTemplateVersion
+	err = db.GetDB().Where("template_id = ? AND version = ?", template.ID, versionNumber).First(&version).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": fmt.Sprintf("Template version %d not found", versionNumber),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to load template version",
+		})
+		return
+	}
+
+	anchors := version.AnchorPoints.Transformed(req.Offset, scale)
+	message := fmt.Sprintf("Applied %s version %d", template.Name, version.Version)
+	updated := false
+	err = db.GetDB().Transaction(func(tx *gorm.DB) error {
+		if product.InheritsAnchors() && c.Query("scope") != "variant" {
+			message = fmt.Sprintf("Applied %s version %d to the family", template.Name, version.Version)
+
+			check, err := guardPublished(tx, "family_id = ?", *product.FamilyID)
+			if err != nil {
+				return err
+			}
+			if updated, err = updateVersioned(tx, &product, expected, map[string]interface{}{}); err != nil || !updated {
+				return err
+			}
+			family := models.ProductFamily{ID: *product.FamilyID}
+			if err := tx.Model(&family).Update("anchor_points", anchors).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.Product{}).
+				Where("family_id = ? AND id <> ?", family.ID, product.ID).
+				UpdateColumn("version", gorm.Expr("version + 1")).Error; err != nil {
+				return err
+			}
+			return check()
+		}
+
+		check, err := guardPublished(tx, "id = ?", product.ID)
+		if err != nil {
+			return err
+		}
+		updated, err = updateVersioned(tx, &product, expected, map[string]interface{}{
+			"anchor_points":           anchors,
+			"anchor_template_id":      template.ID,
+			"anchor_template_version": version.Version,
+		})
+		if err != nil || !updated {
+			return err
+		}
+		return check()
+	})
+	if respondPublishBlocked(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to apply anchor template",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	db.GetDB().Preload("Family").First(&product, id)
+
+	if !updated {
+		preconditionFailed(c, product.Version, "Product was modified by another user", product)
+		return
+	}
+
+	setETag(c, product.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"data":    product,
+	})
+}
@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"fit-pc/db"
+	"fit-pc/internal/blobgc"
+	"fit-pc/internal/storage"
+	"fit-pc/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// AssetMove is a referenced blob URL rewritten to the URL of its content-addressed asset
+type AssetMove struct {
+	From         string `json:"from"`
+	To           string `json:"to"`
+	SHA256       string `json:"sha256"`
+	SizeBytes    int64  `json:"size_bytes"`
+	Deduplicated bool   `json:"deduplicated"` // Another blob with the same content was already an asset
+}
+
+// AssetDedupeReport describes a migration of model, thumbnail and media references
+// to content-addressed assets, or what one would change when dry-running
+type AssetDedupeReport struct {
+	DryRun         bool              `json:"dry_run"`
+	Scanned        int               `json:"scanned"`   // Distinct model and thumbnail URLs of products and families, and media URLs
+	Addressed      int               `json:"addressed"` // URLs already referencing a content-addressed asset
+	External       []string          `json:"external"`  // URLs outside the blob store, left unchanged
+	Moves          []AssetMove       `json:"moves"`
+	Failed         map[string]string `json:"failed"` // Error by URL
+	SavedBytes     int64             `json:"saved_bytes"`
+	Resized        int               `json:"resized"`         // Images given list, card and detail sizes
+	ProductUpdates int64             `json:"product_updates"` // Product model, thumbnail and metadata references rewritten
+	FamilyUpdates  int64             `json:"family_updates"`
+	MediaUpdates   int64             `json:"media_updates"`
+}
+
+// referencedAssetURLs returns the distinct model and thumbnail URLs of products and
+// families, including soft-deleted ones so a restored item keeps a valid reference,
+// and the URLs of media assets
+func referencedAssetURLs(tx *gorm.DB) ([]string, error) {
+	set := map[string]bool{}
+	var media []string
+	if err := tx.Model(&models.MediaAsset{}).Where("url <> ''").Distinct().Pluck("url", &media).Error; err != nil {
+		return nil, err
+	}
+	for _, u := range media {
+		set[u] = true
+	}
+	for _, model := range []interface{}{&models.Product{}, &models.ProductFamily{}} {
+		for _, column := range []string{"model_url", "thumbnail_url"} {
+			var urls []string
+			if err := tx.Unscoped().Model(model).Where(column+" <> ''").Distinct().Pluck(column, &urls).Error; err != nil {
+				return nil, err
+			}
+			for _, u := range urls {
+				set[u] = true
+			}
+		}
+	}
+	urls := make([]string, 0, len(set))
+	for u := range set {
+		urls = append(urls, u)
+	}
+	sort.Strings(urls)
+	return urls, nil
+}
+
+// rewriteAssetURL replaces a blob URL with the URL of an asset on products,
+// families and media assets, including the URL model metadata was computed for
+// and the sizes of thumbnails
+func rewriteAssetURL(tx *gorm.DB, from string, asset models.Asset) (products, families, media int64, err error) {
+	to := asset.URL
+	columns := []struct {
+		name    string
+		updates map[string]interface{}
+	}{
+		{"model_url", map[string]interface{}{"model_url": to}},
+		{"thumbnail_url", map[string]interface{}{"thumbnail_url": to, "thumbnail_sizes": asset.Sizes}},
+	}
+	for _, column := range columns {
+		result := tx.Unscoped().Model(&models.ProductFamily{}).Where(column.name+" = ?", from).Updates(column.updates)
+		if result.Error != nil {
+			return 0, 0, 0, result.Error
+		}
+		families += result.RowsAffected
+
+		column.updates["version"] = gorm.Expr("version + 1")
+		result = tx.Unscoped().Model(&models.Product{}).Where(column.name+" = ?", from).Updates(column.updates)
+		if result.Error != nil {
+			return 0, 0, 0, result.Error
+		}
+		products += result.RowsAffected
+	}
+
+	// Media belongs to the product's version, as in CreateProductMedia
+	err = tx.Model(&models.Product{}).
+		Where("id IN (?)", tx.Model(&models.MediaAsset{}).Select("product_id").Where("url = ?", from)).
+		UpdateColumn("version", gorm.Expr("version + 1")).Error
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	result := tx.Model(&models.MediaAsset{}).Where("url = ?", from).Updates(map[string]interface{}{"url": to, "blob_name": asset.BlobName})
+	if result.Error != nil {
+		return 0, 0, 0, result.Error
+	}
+	media = result.RowsAffected
+
+	// The metadata describes the same content, so it stays valid
+	err = tx.Exec(`UPDATE products SET model_metadata = jsonb_set(model_metadata, '{model_url}', to_jsonb(?::text))
+		WHERE model_metadata->>'model_url' = ?`, to, from).Error
+	return products, families, media, err
+}
+
+// dedupeAsset hashes the blob behind a referenced URL and plans or performs its
+// move to a content-addressed asset. planned holds the assets already moved to in
+// this run by hash, for dry runs.
+func dedupeAsset(ctx context.Context, tx *gorm.DB, store storage.BlobStore, blobName string, dryRun bool, planned map[string]string, report *AssetDedupeReport) error {
+	ext := strings.ToLower(filepath.Ext(blobName))
+	kinds, ok := allowedExtensions[ext]
+	if !ok {
+		return fmt.Errorf("unsupported extension %q", ext)
+	}
+
+	body, _, err := store.Open(ctx, blobName)
+	if err != nil {
+		return err
+	}
+	contentType, sum, size, err := readAsset(body, maxUploadSizes[kinds[0]])
+	body.Close()
+	if err != nil {
+		return err
+	}
+
+	from := store.URL(blobName)
+	move := AssetMove{From: from, SHA256: sum, SizeBytes: size}
+	existing, err := findAsset(tx, sum)
+	if err != nil {
+		return err
+	}
+	switch {
+	case existing != nil:
+		move.To, move.Deduplicated = existing.URL, true
+	case planned[sum] != "":
+		move.To, move.Deduplicated = planned[sum], true
+	default:
+		move.To = store.URL(assetBlobName(sum, blobName))
+		planned[sum] = move.To
+	}
+
+	if !dryRun {
+		err := tx.Transaction(func(tx *gorm.DB) error {
+			asset, _, err := storeAsset(ctx, tx, store, blobName, models.Asset{
+				Kind:        kinds[0],
+				ContentType: contentType,
+				SizeBytes:   size,
+				SHA256:      sum,
+			})
+			if err != nil {
+				return err
+			}
+			move.To = asset.URL
+
+			if asset.Kind == models.MediaKindImage && len(asset.Sizes) == 0 {
+				if err := resizeImageAsset(ctx, tx, store, &asset); err != nil {
+					log.Printf("Failed to resize image %s: %v", asset.BlobName, err)
+				} else {
+					report.Resized++
+				}
+			}
+
+			products, families, media, err := rewriteAssetURL(tx, from, asset)
+			if err != nil {
+				return err
+			}
+			report.ProductUpdates += products
+			report.FamilyUpdates += families
+			report.MediaUpdates += media
+
+			// The upload record replaces an asset confirmed under the upload's name
+			return tx.Where("blob_name = ?", blobName).Delete(&models.Asset{}).Error
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// New image assets are resized
+	if dryRun && kinds[0] == models.MediaKindImage && !move.Deduplicated {
+		report.Resized++
+	}
+	if move.Deduplicated {
+		report.SavedBytes += size
+	}
+	report.Moves = append(report.Moves, move)
+	return nil
+}
+
+// runAssetDedupe moves the blobs referenced as product and family models and
+// thumbnails, and as media, to content-addressed assets and writes the report
+func runAssetDedupe(c *gin.Context, dryRun bool) {
+	ctx := c.Request.Context()
+	tx := db.GetDB().WithContext(ctx)
+	store := storage.Get()
+
+	urls, err := referencedAssetURLs(tx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to load asset references",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	report := AssetDedupeReport{DryRun: dryRun, Scanned: len(urls), External: []string{}, Moves: []AssetMove{}, Failed: map[string]string{}}
+	planned := map[string]string{}
+	for _, u := range urls {
+		blobName := blobgc.BlobName(u)
+		if blobName == "" || store.URL(blobName) != u {
+			report.External = append(report.External, u)
+			continue
+		}
+
+		var asset models.Asset
+		if err := tx.Where("url = ?", u).First(&asset).Error; err == nil && asset.BlobName == assetBlobName(asset.SHA256, blobName) {
+			report.Addressed++
+			// Images confirmed before uploads were resized
+			if asset.Kind == models.MediaKindImage && len(asset.Sizes) == 0 {
+				if dryRun {
+					report.Resized++
+				} else if err := resizeImageAsset(ctx, tx, store, &asset); err != nil {
+					report.Failed[u] = "failed to resize the image: " + err.Error()
+				} else {
+					report.Resized++
+				}
+			}
+			continue
+		}
+
+		if err := dedupeAsset(ctx, tx, store, blobName, dryRun, planned, &report); err != nil {
+			report.Failed[u] = err.Error()
+		}
+	}
+
+	message := "References moved to content-addressed assets; replaced blobs are left to the blob collector"
+	if dryRun {
+		message = "Dry run: no references were changed"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"data":    report,
+	})
+}
+
+// PlanAssetDedupe reports how the model and thumbnail URLs of products and families,
+// and media URLs, would be moved to content-addressed assets, and the storage identical files
+// would stop using (Admin only)
+// GET /api/admin/storage/dedupe
+func PlanAssetDedupe(c *gin.Context) {
+	runAssetDedupe(c, true)
+}
+
+// RunAssetDedupe hashes the blobs referenced as product and family models and
+// thumbnails, and as media, stores each content once under its hash and rewrites the references
+// to the shared asset (Admin only). The replaced blobs are kept while builds
+// reference them, and removed by the blob collector afterwards. Running it again
+// only moves references added since.
+// POST /api/admin/storage/dedupe
+func RunAssetDedupe(c *gin.Context) {
+	runAssetDedupe(c, false)
+}
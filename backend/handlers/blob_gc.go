@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"fit-pc/db"
+	"fit-pc/internal/blobcache"
+	"fit-pc/internal/blobgc"
+	"fit-pc/internal/config"
+	"fit-pc/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// runBlobGC runs a collection with the grace period from ?grace_hours=, or the
+// configured one, and writes the report
+func runBlobGC(c *gin.Context, dryRun bool) {
+	var grace time.Duration
+	if hours := c.Query("grace_hours"); hours != "" {
+		n, err := strconv.Atoi(hours)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "grace_hours must be a number of hours",
+			})
+			return
+		}
+		grace = time.Duration(n) * time.Hour
+	} else {
+		grace = config.GetConfig().BlobGCGrace
+	}
+	if grace < blobgc.MinGrace {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Grace period must be at least " + blobgc.MinGrace.String(),
+		})
+		return
+	}
+
+	report, err := blobgc.Run(c.Request.Context(), storage.Get(), blobcache.Get(), db.GetDB(), grace, dryRun)
+	if errors.Is(err, blobgc.ErrRunning) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Blob garbage collection is already running",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to collect orphaned blobs",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	message := "Orphaned blobs deleted"
+	if dryRun {
+		message = "Dry run: no blobs were deleted"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"data":    report,
+	})
+}
+
+// PlanBlobGC reports the blobs a garbage collection would delete: blobs no product,
+// family, media asset or build references, older than the grace period (Admin only)
+// GET /api/admin/storage/gc?grace_hours=
+func PlanBlobGC(c *gin.Context) {
+	runBlobGC(c, true)
+}
+
+// RunBlobGC deletes the orphaned blobs reported by GET /api/admin/storage/gc (Admin only).
+// The same collection runs periodically in the background.
+// POST /api/admin/storage/gc?grace_hours=
+func RunBlobGC(c *gin.Context) {
+	runBlobGC(c, false)
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"net/http"
+
+	"fit-pc/db"
+	"fit-pc/middleware"
+	"fit-pc/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ForkBuildRequest represents the optional request body for cloning or forking a build
+type ForkBuildRequest struct {
+	Name string `json:"name"` // Defaults to the source name with " (copy)" appended
+}
+
+// CloneBuild copies a build of the authenticated user into a new private build,
+// re-hydrated from the current catalog. The response lists the components whose
+// price or specs changed, and those left out because they are no longer available.
+// POST /api/user/builds/:id/clone
+func CloneBuild(c *gin.Context) {
+	source, ok := ownBuild(c)
+	if !ok {
+		return
+	}
+	forkBuild(c, source)
+}
+
+// ForkSharedBuild copies a shared build into a new private build of the
+// authenticated user, as CloneBuild does for the owner
+// POST /api/shared/builds/:slug/fork
+func ForkSharedBuild(c *gin.Context) {
+	if _, exists := middleware.GetUserIDFromContext(c); !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+	_, source, ok := findSharedBuild(c)
+	if !ok {
+		return
+	}
+	forkBuild(c, source)
+}
+
+func forkBuild(c *gin.Context, source models.Build) {
+	userID, _ := middleware.GetUserIDFromContext(c)
+
+	var req ForkBuildRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid request body",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+	if req.Name == "" {
+		req.Name = source.Name + " (copy)"
+	}
+
+	components, changes, err := liveComponents(db.GetDB(), source.Components)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to load catalog products",
+			"details": err.Error(),
+		})
+		return
+	}
+	components = availableComponents(components, changes)
+
+	build := models.Build{
+		UserID:     userID,
+		Name:       req.Name,
+		Components: components,
+		TotalPrice: componentsTotal(components),
+		ForkedFrom: &source.ID,
+	}
+	err = db.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&build).Error; err != nil {
+			return err
+		}
+		return recordBuildRevision(tx, build)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to save build",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Build copied",
+		"data":    build,
+		"changes": changes,
+	})
+}
@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"reflect"
+	"sort"
+
+	"fit-pc/db"
+	"fit-pc/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Reasons a build component is missing from the live catalog
+const (
+	MissingDeleted     = "deleted"     // The product was deleted
+	MissingUnpublished = "unpublished" // The product is a draft, archived or not yet published
+)
+
+// ComponentChange describes how a saved build component differs from its product
+// in the live catalog
+type ComponentChange struct {
+	Index        int      `json:"index"` // Position in the saved build
+	ProductID    uint     `json:"product_id"`
+	Name         string   `json:"name"`
+	Category     string   `json:"category"`
+	Missing      bool     `json:"missing,omitempty"` // Deleted or no longer in the published catalog
+	Reason       string   `json:"reason,omitempty"`  // Why it is missing, see Missing*
+	OldPrice     float64  `json:"old_price"`
+	NewPrice     float64  `json:"new_price,omitempty"`
+	PriceDelta   float64  `json:"price_delta,omitempty"`   // Per unit
+	SpecsChanged []string `json:"specs_changed,omitempty"` // Spec keys added, removed or changed
+}
+
+// liveComponents re-hydrates build components from the published catalog: name,
+// price, model, specs and anchor points are taken from the current product, while
+// the category and quantity chosen in the builder are kept. Components whose
+// product is gone keep their snapshot and are reported as missing, deleted or
+// unpublished; the others are reported when their price or specs changed.
+func liveComponents(tx *gorm.DB, components models.BuildComponents) (models.BuildComponents, []ComponentChange, error) {
+	ids := make([]uint, 0, len(components))
+	for _, component := range components {
+		ids = append(ids, component.ID)
+	}
+	var products []models.Product
+	if err := tx.Scopes(publishedProducts).Preload("Family").Where("id IN ?", ids).Find(&products).Error; err != nil {
+		return nil, nil, err
+	}
+	byID := make(map[uint]models.Product, len(products))
+	for _, product := range products {
+		byID[product.ID] = product.Resolved()
+	}
+
+	// Products that are not deleted but left the catalog are unpublished
+	var unpublishedIDs []uint
+	if len(byID) < len(ids) {
+		if err := tx.Model(&models.Product{}).Where("id IN ?", ids).Pluck("id", &unpublishedIDs).Error; err != nil {
+			return nil, nil, err
+		}
+	}
+	unpublished := make(map[uint]bool, len(unpublishedIDs))
+	for _, id := range unpublishedIDs {
+		unpublished[id] = true
+	}
+
+	live := make(models.BuildComponents, len(components))
+	changes := []ComponentChange{}
+	for i, component := range components {
+		change := ComponentChange{
+			Index:     i,
+			ProductID: component.ID,
+			Name:      component.Name,
+			Category:  component.Category,
+			OldPrice:  component.Price,
+		}
+
+		product, ok := byID[component.ID]
+		if !ok {
+			live[i] = component
+			change.Missing = true
+			change.Reason = MissingDeleted
+			if unpublished[component.ID] {
+				change.Reason = MissingUnpublished
+			}
+			changes = append(changes, change)
+			continue
+		}
+
+		delta := roundCents(product.Price - component.Price)
+		if delta != 0 {
+			change.NewPrice = product.Price
+			change.PriceDelta = delta
+		}
+		change.SpecsChanged = specChanges(component.TechnicalSpecs, product.TechnicalSpecs)
+		if delta != 0 || len(change.SpecsChanged) > 0 {
+			change.Name = product.Name
+			changes = append(changes, change)
+		}
+
+		live[i] = models.BuildComponent{
+			ID:             product.ID,
+			Name:           product.Name,
+			Category:       component.Category,
+			Price:          product.Price,
+			ModelURL:       product.ModelURL,
+			TechnicalSpecs: product.TechnicalSpecs,
+			AnchorPoints:   product.AnchorPoints,
+			Quantity:       component.Quantity,
+		}
+	}
+	return live, changes, nil
+}
+
+// catalogModelURLs returns the model each product of the components resolves to in
+// the catalog, by product ID, whatever its status. Builds name the models of their
+// components as sent by the client, so only these are trusted to download or render.
+func catalogModelURLs(tx *gorm.DB, components models.BuildComponents) (map[uint]string, error) {
+	ids := make([]uint, 0, len(components))
+	for _, component := range components {
+		ids = append(ids, component.ID)
+	}
+	result := make(map[uint]string, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+	var products []models.Product
+	if err := tx.Unscoped().Select("id", "model_url", "family_id").Preload("Family").Where("id IN ?", ids).Find(&products).Error; err != nil {
+		return nil, err
+	}
+	for _, product := range products {
+		result[product.ID] = product.Resolved().ModelURL
+	}
+	return result, nil
+}
+
+// availableComponents leaves out the components reported missing by liveComponents
+func availableComponents(components models.BuildComponents, changes []ComponentChange) models.BuildComponents {
+	missing := make(map[int]bool)
+	for _, change := range changes {
+		if change.Missing {
+			missing[change.Index] = true
+		}
+	}
+	available := models.BuildComponents{}
+	for i, component := range components {
+		if !missing[i] {
+			available = append(available, component)
+		}
+	}
+	return available
+}
+
+// specChanges returns the sorted spec keys whose values differ between a snapshot
+// and the current specs
+func specChanges(saved, current models.TechnicalSpecs) []string {
+	var keys []string
+	for key, value := range saved {
+		if other, ok := current[key]; !ok || !reflect.DeepEqual(value, other) {
+			keys = append(keys, key)
+		}
+	}
+	for key := range current {
+		if _, ok := saved[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// roundCents rounds an amount to whole cents, as prices are stored
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// lineTotal returns the price of a component times its quantity
+func lineTotal(component models.BuildComponent) float64 {
+	quantity := component.Quantity
+	if quantity == 0 {
+		quantity = 1
+	}
+	return component.Price * float64(quantity)
+}
+
+// componentsTotal returns the total price of build components
+func componentsTotal(components models.BuildComponents) float64 {
+	var total float64
+	for _, component := range components {
+		total += lineTotal(component)
+	}
+	return roundCents(total)
+}
+
+// RepriceBuildRequest represents the optional request body for repricing a build
+type RepriceBuildRequest struct {
+	Apply bool `json:"apply"` // Save the current prices into the build
+}
+
+// RepriceBuild compares the components of a build of the authenticated user
+// against the live catalog and reports their drift: price changed, specs changed
+// or product deleted. With apply, the current prices are saved into the build as
+// a new revision; specs and deleted products are left as saved.
+// Applying requires If-Match with the build's current ETag.
+// POST /api/user/builds/:id/reprice
+func RepriceBuild(c *gin.Context) {
+	build, ok := ownBuild(c)
+	if !ok {
+		return
+	}
+
+	var req RepriceBuildRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid request body",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+
+	var expected uint
+	if req.Apply {
+		if expected, ok = ifMatchVersion(c); !ok {
+			return
+		}
+		if build.Version != expected {
+			preconditionFailed(c, build.Version, "Build was modified in another session", build)
+			return
+		}
+	}
+
+	live, changes, err := liveComponents(db.GetDB(), build.Components)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to load catalog products",
+			"details": err.Error(),
+		})
+		return
+	}
+	// Deleted products count at their saved price
+	liveTotal := componentsTotal(live)
+
+	repriced := make(models.BuildComponents, len(build.Components))
+	copy(repriced, build.Components)
+	priceChanged := false
+	for _, change := range changes {
+		if change.PriceDelta != 0 {
+			repriced[change.Index].Price = change.NewPrice
+			priceChanged = true
+		}
+	}
+
+	applied := false
+	if req.Apply && priceChanged {
+		updated := false
+		err = db.GetDB().Transaction(func(tx *gorm.DB) error {
+			if err := baselineRevision(tx, build); err != nil {
+				return err
+			}
+			var err error
+			updated, err = updateVersioned(tx, &build, expected, map[string]interface{}{
+				"components":  repriced,
+				"total_price": componentsTotal(repriced),
+			})
+			if err != nil || !updated {
+				return err
+			}
+			if err := tx.First(&build, build.ID).Error; err != nil {
+				return err
+			}
+			return recordBuildRevision(tx, build)
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to reprice build",
+				"details": err.Error(),
+			})
+			return
+		}
+		if !updated {
+			db.GetDB().First(&build, build.ID)
+			preconditionFailed(c, build.Version, "Build was modified in another session", build)
+			return
+		}
+		applied = true
+	}
+
+	setETag(c, build.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"data":             changes,
+		"build":            build,
+		"applied":          applied,
+		"total_price":      build.TotalPrice,
+		"live_total_price": liveTotal,
+	})
+}
@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"fit-pc/db"
+	"fit-pc/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// recordBuildRevision stores the current state of a build as the revision of its
+// version; a revision already recorded for that version is kept
+func recordBuildRevision(tx *gorm.DB, build models.Build) error {
+	revision := models.BuildRevision{
+		BuildID:    build.ID,
+		Version:    build.Version,
+		Name:       build.Name,
+		Components: build.Components,
+		TotalPrice: build.TotalPrice,
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&revision).Error
+}
+
+// baselineRevision records the current state of a build saved before revisions
+// were kept, so that its first change can be undone
+func baselineRevision(tx *gorm.DB, build models.Build) error {
+	var count int64
+	if err := tx.Model(&models.BuildRevision{}).Where("build_id = ?", build.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	return recordBuildRevision(tx, build)
+}
+
+// GetBuildRevisions lists the saved states of a build of the authenticated user,
+// newest first
+// GET /api/user/builds/:id/revisions
+func GetBuildRevisions(c *gin.Context) {
+	build, ok := ownBuild(c)
+	if !ok {
+		return
+	}
+
+	var revisions []models.BuildRevision
+	if err := db.GetDB().Where("build_id = ?", build.ID).Order("version DESC").Find(&revisions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch revisions",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":    revisions,
+		"count":   len(revisions),
+		"current": build.Version,
+	})
+}
+
+// ComponentDiff is a component added, removed or changed between two revisions
+type ComponentDiff struct {
+	ProductID  uint                   `json:"product_id"`
+	Name       string                 `json:"name"`
+	Category   string                 `json:"category"`
+	From       *models.BuildComponent `json:"from,omitempty"` // Nil when added
+	To         *models.BuildComponent `json:"to,omitempty"`   // Nil when removed
+	PriceDelta float64                `json:"price_delta"`    // Change of price × quantity
+}
+
+// BuildDiff is the difference between two revisions of a build
+type BuildDiff struct {
+	From            uint            `json:"from"`
+	To              uint            `json:"to"`
+	FromName        string          `json:"from_name"`
+	ToName          string          `json:"to_name"`
+	Added           []ComponentDiff `json:"added"`
+	Removed         []ComponentDiff `json:"removed"`
+	Changed         []ComponentDiff `json:"changed"` // Price, quantity or name changed
+	TotalPriceDelta float64         `json:"total_price_delta"`
+}
+
+// diffRevisions compares two revisions. Components are matched by product, in
+// order when a product appears more than once.
+func diffRevisions(from, to models.BuildRevision) BuildDiff {
+	diff := BuildDiff{
+		From:            from.Version,
+		To:              to.Version,
+		FromName:        from.Name,
+		ToName:          to.Name,
+		Added:           []ComponentDiff{},
+		Removed:         []ComponentDiff{},
+		Changed:         []ComponentDiff{},
+		TotalPriceDelta: roundCents(to.TotalPrice - from.TotalPrice),
+	}
+
+	unmatched := make(map[uint][]int)
+	for i, component := range from.Components {
+		unmatched[component.ID] = append(unmatched[component.ID], i)
+	}
+
+	for i := range to.Components {
+		next := &to.Components[i]
+		candidates := unmatched[next.ID]
+		if len(candidates) == 0 {
+			diff.Added = append(diff.Added, ComponentDiff{
+				ProductID:  next.ID,
+				Name:       next.Name,
+				Category:   next.Category,
+				To:         next,
+				PriceDelta: roundCents(lineTotal(*next)),
+			})
+			continue
+		}
+		prev := &from.Components[candidates[0]]
+		unmatched[next.ID] = candidates[1:]
+
+		delta := roundCents(lineTotal(*next) - lineTotal(*prev))
+		if delta != 0 || prev.Quantity != next.Quantity || prev.Name != next.Name {
+			diff.Changed = append(diff.Changed, ComponentDiff{
+				ProductID:  next.ID,
+				Name:       next.Name,
+				Category:   next.Category,
+				From:       prev,
+				To:         next,
+				PriceDelta: delta,
+			})
+		}
+	}
+
+	removed := make(map[int]bool)
+	for _, indices := range unmatched {
+		for _, i := range indices {
+			removed[i] = true
+		}
+	}
+	for i := range from.Components {
+		if !removed[i] {
+			continue
+		}
+		prev := &from.Components[i]
+		diff.Removed = append(diff.Removed, ComponentDiff{
+			ProductID:  prev.ID,
+			Name:       prev.Name,
+			Category:   prev.Category,
+			From:       prev,
+			PriceDelta: roundCents(-lineTotal(*prev)),
+		})
+	}
+
+	return diff
+}
+
+// BuildDiffQuery selects the revisions to compare
+type BuildDiffQuery struct {
+	From uint `form:"from" binding:"required"`
+	To   uint `form:"to" binding:"required"`
+}
+
+// GetBuildRevisionDiff compares two revisions of a build of the authenticated
+// user: components added, removed and changed, with their price deltas
+// GET /api/user/builds/:id/revisions/diff?from=&to=
+func GetBuildRevisionDiff(c *gin.Context) {
+	build, ok := ownBuild(c)
+	if !ok {
+		return
+	}
+
+	var query BuildDiffQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid query parameters",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	var revisions []models.BuildRevision
+	if err := db.GetDB().Where("build_id = ? AND version IN ?", build.ID, []uint{query.From, query.To}).Find(&revisions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch revisions",
+		})
+		return
+	}
+	byVersion := make(map[uint]models.BuildRevision, len(revisions))
+	for _, revision := range revisions {
+		byVersion[revision.Version] = revision
+	}
+	from, foundFrom := byVersion[query.From]
+	to, foundTo := byVersion[query.To]
+	if !foundFrom || !foundTo {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Revision not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": diffRevisions(from, to),
+	})
+}
+
+// RollbackBuild restores the name and components of a build of the authenticated
+// user from one of its revisions. The restored state is saved as a new revision,
+// so the rollback itself can be undone.
+// Requires If-Match with the build's current ETag.
+// POST /api/user/builds/:id/revisions/:version/rollback
+func RollbackBuild(c *gin.Context) {
+	build, ok := ownBuild(c)
+	if !ok {
+		return
+	}
+
+	version, err := strconv.ParseUint(c.Param("version"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid revision version",
+		})
+		return
+	}
+
+	expected, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+	if build.Version != expected {
+		preconditionFailed(c, build.Version, "Build was modified in another session", build)
+		return
+	}
+
+	var revision models.BuildRevision
+	if err := db.GetDB().Where("build_id = ? AND version = ?", build.ID, version).First(&revision).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Revision not found",
+		})
+		return
+	}
+
+	updated := false
+	err = db.GetDB().Transaction(func(tx *gorm.DB) error {
+		var err error
+		updated, err = updateVersioned(tx, &build, expected, map[string]interface{}{
+			"name":        revision.Name,
+			"components":  revision.Components,
+			"total_price": revision.TotalPrice,
+		})
+		if err != nil || !updated {
+			return err
+		}
+		if err := tx.First(&build, build.ID).Error; err != nil {
+			return err
+		}
+		return recordBuildRevision(tx, build)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to roll back build",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if !updated {
+		db.GetDB().First(&build, build.ID)
+		preconditionFailed(c, build.Version, "Build was modified in another session", build)
+		return
+	}
+
+	setETag(c, build.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Build rolled back",
+		"data":    build,
+	})
+}
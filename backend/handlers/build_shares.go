@@ -0,0 +1,373 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"fit-pc/db"
+	"fit-pc/middleware"
+	"fit-pc/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// shareSlugBytes is the randomness of share slugs: 128 bits, 22 URL-safe characters
+const shareSlugBytes = 16
+
+// newShareSlug returns an unguessable share slug
+func newShareSlug() (string, error) {
+	b := make([]byte, shareSlugBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// SharedBuild is a build as shown through a share link: everything needed to list
+// its parts and render its 3D scene, without its owner
+type SharedBuild struct {
+	Slug       string                 `json:"slug"`
+	Name       string                 `json:"name"`
+	Components models.BuildComponents `json:"components"`
+	TotalPrice float64                `json:"total_price"`
+	Visibility string                 `json:"visibility"`
+	ViewCount  int64                  `json:"view_count"`
+	PreviewURL string                 `json:"preview_url"` // Share card image
+	CreatedAt  time.Time              `json:"created_at"`
+	UpdatedAt  time.Time              `json:"updated_at"`
+}
+
+func newSharedBuild(share models.BuildShare, build models.Build) SharedBuild {
+	components := build.Components
+	if components == nil {
+		components = models.BuildComponents{}
+	}
+	return SharedBuild{
+		Slug:       share.Slug,
+		Name:       build.Name,
+		Components: components,
+		TotalPrice: build.TotalPrice,
+		Visibility: build.Visibility,
+		ViewCount:  share.ViewCount,
+		PreviewURL: fmt.Sprintf("/api/shared/builds/%s/preview", share.Slug),
+		CreatedAt:  build.CreatedAt,
+		UpdatedAt:  build.UpdatedAt,
+	}
+}
+
+// activeSharedBuild returns the share link with the given slug and its build, or
+// gorm.ErrRecordNotFound when the link is revoked, expired or unknown, or the
+// build is deleted or private
+func activeSharedBuild(tx *gorm.DB, slug string) (models.BuildShare, models.Build, error) {
+	var share models.BuildShare
+	var build models.Build
+	if err := tx.Where("slug = ?", slug).First(&share).Error; err != nil {
+		return share, build, err
+	}
+	if !share.Active(time.Now()) {
+		return share, build, gorm.ErrRecordNotFound
+	}
+	err := tx.Where("id = ? AND visibility <> ?", share.BuildID, models.BuildVisibilityPrivate).First(&build).Error
+	return share, build, err
+}
+
+// findSharedBuild loads the build of a share link, responding 404 when the link
+// cannot be opened
+func findSharedBuild(c *gin.Context) (models.BuildShare, models.Build, bool) {
+	share, build, err := activeSharedBuild(db.GetDB(), c.Param("slug"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Shared build not found",
+		})
+		return share, build, false
+	}
+	return share, build, true
+}
+
+// ownBuild loads a build of the authenticated user by the id parameter, responding
+// with an error when it cannot
+func ownBuild(c *gin.Context) (models.Build, bool) {
+	var build models.Build
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return build, false
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid build ID",
+		})
+		return build, false
+	}
+
+	if err := db.GetDB().Where("id = ? AND user_id = ?", id, userID).First(&build).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Build not found",
+		})
+		return build, false
+	}
+	return build, true
+}
+
+// CreateBuildShareRequest represents the request body for creating a share link
+type CreateBuildShareRequest struct {
+	ExpiresAt *time.Time `json:"expires_at"` // Optional; the link never expires without it
+}
+
+// CreateBuildShare creates a read-only share link to a build of the authenticated
+// user, opened at GET /api/shared/builds/:slug without signing in. Sharing a
+// private build makes it unlisted; make it public with PUT /api/user/builds/:id
+// to also list it among the public builds.
+// POST /api/user/builds/:id/shares
+func CreateBuildShare(c *gin.Context) {
+	build, ok := ownBuild(c)
+	if !ok {
+		return
+	}
+
+	var req CreateBuildShareRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid request body",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "expires_at must be in the future",
+		})
+		return
+	}
+
+	slug, err := newShareSlug()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create share link",
+			"details": err.Error(),
+		})
+		return
+	}
+	share := models.BuildShare{BuildID: build.ID, Slug: slug, ExpiresAt: req.ExpiresAt}
+
+	err = db.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&share).Error; err != nil {
+			return err
+		}
+		if build.Visibility != models.BuildVisibilityPrivate {
+			return nil
+		}
+		return tx.Model(&build).Updates(map[string]interface{}{
+			"visibility": models.BuildVisibilityUnlisted,
+			"version":    gorm.Expr("version + 1"),
+		}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create share link",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	db.GetDB().First(&build, build.ID)
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Share link created",
+		"data":       share,
+		"visibility": build.Visibility,
+	})
+}
+
+// GetBuildShares lists the share links of a build of the authenticated user with
+// their view counts, including revoked and expired ones
+// GET /api/user/builds/:id/shares
+func GetBuildShares(c *gin.Context) {
+	build, ok := ownBuild(c)
+	if !ok {
+		return
+	}
+
+	var shares []models.BuildShare
+	if err := db.GetDB().Where("build_id = ?", build.ID).Order("created_at DESC").Find(&shares).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch share links",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       shares,
+		"count":      len(shares),
+		"visibility": build.Visibility,
+	})
+}
+
+// RevokeBuildShare revokes a share link of a build of the authenticated user.
+// The link stops working immediately; it stays listed with its view count.
+// DELETE /api/user/builds/:id/shares/:shareId
+func RevokeBuildShare(c *gin.Context) {
+	build, ok := ownBuild(c)
+	if !ok {
+		return
+	}
+
+	shareID, err := strconv.ParseUint(c.Param("shareId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid share ID",
+		})
+		return
+	}
+
+	var share models.BuildShare
+	if err := db.GetDB().Where("id = ? AND build_id = ?", shareID, build.ID).First(&share).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Share link not found",
+		})
+		return
+	}
+
+	if share.RevokedAt == nil {
+		if err := db.GetDB().Model(&share).Update("revoked_at", time.Now()).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to revoke share link",
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Share link revoked",
+		"data":    share,
+	})
+}
+
+// GetSharedBuild returns a build through its share link, without signing in, and
+// counts the view. The owner is never exposed. The models of its components can
+// be downloaded by passing share=<slug> to the download token endpoints.
+// GET /api/shared/builds/:slug
+func GetSharedBuild(c *gin.Context) {
+	share, build, ok := findSharedBuild(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	err := db.GetDB().Model(&share).UpdateColumns(map[string]interface{}{
+		"view_count":     gorm.Expr("view_count + 1"),
+		"last_viewed_at": now,
+	}).Error
+	if err == nil {
+		share.ViewCount++
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"data": newSharedBuild(share, build),
+	})
+}
+
+// GetSharedBuildPreview returns the share card image of a shared build, as
+// GET /api/user/builds/:id/preview does for its owner
+// GET /api/shared/builds/:slug/preview
+func GetSharedBuildPreview(c *gin.Context) {
+	_, build, ok := findSharedBuild(c)
+	if !ok {
+		return
+	}
+	serveBuildPreview(c, build)
+}
+
+// PublicBuildsQuery pages the list of public builds
+type PublicBuildsQuery struct {
+	Page  int `form:"page,default=1" binding:"min=1"`
+	Limit int `form:"limit,default=20" binding:"min=1,max=100"`
+}
+
+// GetPublicBuilds lists the public builds through their newest active share link,
+// most recently shared first
+// GET /api/shared/builds?page=&limit=
+func GetPublicBuilds(c *gin.Context) {
+	var query PublicBuildsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid query parameters",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	tx := db.GetDB()
+	latest := tx.Model(&models.BuildShare{}).
+		Select("DISTINCT ON (build_shares.build_id) build_shares.*").
+		Joins("JOIN builds ON builds.id = build_shares.build_id AND builds.deleted_at IS NULL").
+		Where("builds.visibility = ? AND build_shares.revoked_at IS NULL", models.BuildVisibilityPublic).
+		Where("build_shares.expires_at IS NULL OR build_shares.expires_at > ?", time.Now()).
+		Order("build_shares.build_id, build_shares.created_at DESC")
+
+	var total int64
+	if err := tx.Table("(?) AS latest", latest).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to count public builds",
+		})
+		return
+	}
+
+	var shares []models.BuildShare
+	err := tx.Table("(?) AS latest", latest).Order("created_at DESC").
+		Offset((query.Page - 1) * query.Limit).Limit(query.Limit).Find(&shares).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch public builds",
+		})
+		return
+	}
+
+	buildIDs := make([]uint, len(shares))
+	for i, share := range shares {
+		buildIDs[i] = share.BuildID
+	}
+	var builds []models.Build
+	if err := tx.Where("id IN ?", buildIDs).Find(&builds).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch public builds",
+		})
+		return
+	}
+	byID := make(map[uint]models.Build, len(builds))
+	for _, build := range builds {
+		byID[build.ID] = build
+	}
+
+	data := make([]SharedBuild, 0, len(shares))
+	for _, share := range shares {
+		if build, ok := byID[share.BuildID]; ok {
+			data = append(data, newSharedBuild(share, build))
+		}
+	}
+
+	lastPage := int(math.Ceil(float64(total) / float64(query.Limit)))
+	if lastPage == 0 {
+		lastPage = 1
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data": data,
+		"meta": PaginationMeta{
+			Total:    total,
+			Page:     query.Page,
+			LastPage: lastPage,
+		},
+	})
+}
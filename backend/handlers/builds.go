@@ -9,6 +9,7 @@ import (
 	"fit-pc/models"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // SaveBuildRequest represents the request body for saving a build
@@ -77,7 +78,13 @@ func SaveBuild(c *gin.Context) {
 		TotalPrice: totalPrice,
 	}
 
-	if err := db.GetDB().Create(&build).Error; err != nil {
+	err := db.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&build).Error; err != nil {
+			return err
+		}
+		return recordBuildRevision(tx, build)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to save build",
 			"details": err.Error(),
@@ -116,8 +123,10 @@ func GetUserBuilds(c *gin.Context) {
 	})
 }
 
-// GetBuildDetails returns a specific build with its components
-// GET /api/user/builds/:id
+// GetBuildDetails returns a specific build with its components. With live=true
+// the components and total price are re-hydrated from the current catalog, with
+// their drift listed in "changes"; the saved build is left unchanged.
+// GET /api/user/builds/:id?live=
 func GetBuildDetails(c *gin.Context) {
 	userID, exists := middleware.GetUserIDFromContext(c)
 	if !exists {
@@ -143,6 +152,32 @@ func GetBuildDetails(c *gin.Context) {
 		return
 	}
 
+	// The live view follows the catalog, so it is not revalidated by the build's ETag
+	if c.Query("live") == "true" {
+		components, changes, err := liveComponents(db.GetDB(), build.Components)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to load catalog products",
+				"details": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{
+				"build":       build,
+				"components":  components,
+				"total_price": componentsTotal(components),
+				"changes":     changes,
+				"live":        true,
+			},
+		})
+		return
+	}
+
+	if notModified(c, build.Version) {
+		return
+	}
+
 	// Components are already stored in the build
 	c.JSON(http.StatusOK, gin.H{
 		"data": gin.H{
@@ -157,9 +192,11 @@ func GetBuildDetails(c *gin.Context) {
 type UpdateBuildRequest struct {
 	Name       *string              `json:"name"`
 	Components []SaveBuildComponent `json:"components"`
+	Visibility *string              `json:"visibility" binding:"omitempty,oneof=private unlisted public"`
 }
 
 // UpdateBuild updates an existing build
+// Requires If-Match with the build's current ETag.
 // PUT /api/user/builds/:id
 func UpdateBuild(c *gin.Context) {
 	userID, exists := middleware.GetUserIDFromContext(c)
@@ -186,6 +223,15 @@ func UpdateBuild(c *gin.Context) {
 		return
 	}
 
+	expected, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+	if build.Version != expected {
+		preconditionFailed(c, build.Version, "Build was modified in another session", build)
+		return
+	}
+
 	var req UpdateBuildRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -200,6 +246,10 @@ func UpdateBuild(c *gin.Context) {
 	if req.Name != nil {
 		updates["name"] = *req.Name
 	}
+	if req.Visibility != nil {
+		// Private closes every share link; public also lists the build at GET /api/shared/builds
+		updates["visibility"] = *req.Visibility
+	}
 	if req.Components != nil {
 		// Convert and calculate total price
 		var totalPrice float64
@@ -226,7 +276,26 @@ func UpdateBuild(c *gin.Context) {
 		updates["total_price"] = totalPrice
 	}
 
-	if err := db.GetDB().Model(&build).Updates(updates).Error; err != nil {
+	// A change of name or components is saved as a new revision
+	revised := req.Name != nil || req.Components != nil
+	updated := false
+	err = db.GetDB().Transaction(func(tx *gorm.DB) error {
+		if revised {
+			if err := baselineRevision(tx, build); err != nil {
+				return err
+			}
+		}
+		var err error
+		updated, err = updateVersioned(tx, &build, expected, updates)
+		if err != nil || !updated || !revised {
+			return err
+		}
+		if err := tx.First(&build, id).Error; err != nil {
+			return err
+		}
+		return recordBuildRevision(tx, build)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to update build",
 			"details": err.Error(),
@@ -237,6 +306,12 @@ func UpdateBuild(c *gin.Context) {
 	// Reload build
 	db.GetDB().First(&build, id)
 
+	if !updated {
+		preconditionFailed(c, build.Version, "Build was modified in another session", build)
+		return
+	}
+
+	setETag(c, build.Version)
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Build updated successfully",
 		"data":    build,
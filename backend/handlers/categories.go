@@ -0,0 +1,409 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"fit-pc/db"
+	"fit-pc/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CategoryRequest represents the request body for creating a category
+type CategoryRequest struct {
+	Slug          string   `json:"slug" binding:"required,max=50"`
+	DisplayName   string   `json:"display_name" binding:"required,max=100"`
+	SortOrder     int      `json:"sort_order"`
+	ParentID      *uint    `json:"parent_id"`
+	Icon          string   `json:"icon"`
+	Required      bool     `json:"required"`
+	SpecSchema    string   `json:"spec_schema"`
+	RequiredSpecs []string `json:"required_specs"`
+	AnchorTypes   []string `json:"anchor_types"`
+}
+
+// UpdateCategoryRequest represents the request body for updating a category
+type UpdateCategoryRequest struct {
+	Slug          *string  `json:"slug" binding:"omitempty,max=50"`
+	DisplayName   *string  `json:"display_name" binding:"omitempty,max=100"`
+	SortOrder     *int     `json:"sort_order"`
+	ParentID      *uint    `json:"parent_id"` // 0 turns the category into a top-level category
+	Icon          *string  `json:"icon"`
+	Required      *bool    `json:"required"`
+	SpecSchema    *string  `json:"spec_schema"`
+	RequiredSpecs []string `json:"required_specs"`
+	AnchorTypes   []string `json:"anchor_types"`
+}
+
+// taxonomy is a snapshot of the categories table used to validate references
+type taxonomy struct {
+	categories      map[string]bool
+	compatibleTypes map[string]bool
+}
+
+func loadTaxonomy() (*taxonomy, error) {
+	var categories []models.Category
+	if err := db.GetDB().Find(&categories).Error; err != nil {
+		return nil, err
+	}
+
+	t := &taxonomy{
+		categories:      make(map[string]bool, len(categories)),
+		compatibleTypes: make(map[string]bool),
+	}
+	for _, category := range categories {
+		t.categories[category.Slug] = true
+		t.compatibleTypes[category.Slug] = true
+		for _, anchorType := range category.AnchorTypes {
+			t.compatibleTypes[anchorType] = true
+		}
+	}
+	return t, nil
+}
+
+// validate returns an error listing every unknown category or compatible type.
+// An empty category is not checked, so anchor-only updates can reuse it.
+func (t *taxonomy) validate(category string, anchors []models.AnchorPoint) error {
+	var problems []string
+
+	if category != "" && !t.categories[category] {
+		problems = append(problems, fmt.Sprintf("unknown category %q", category))
+	}
+
+	unknown := make(map[string]bool)
+	for _, anchor := range anchors {
+		for _, compatType := range anchor.CompatibleTypes {
+			if !t.compatibleTypes[compatType] && !unknown[compatType] {
+				unknown[compatType] = true
+				problems = append(problems, fmt.Sprintf("anchor %q references unknown compatible type %q", anchor.Name, compatType))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// checkCategoryRefs validates a category and the anchors' compatible types against
+// the taxonomy. It writes the error response and returns false when invalid.
+func checkCategoryRefs(c *gin.Context, category string, anchors []models.AnchorPoint) bool {
+	t, err := loadTaxonomy()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to load categories",
+		})
+		return false
+	}
+
+	if err := t.validate(category, anchors); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid category reference",
+			"details": err.Error(),
+		})
+		return false
+	}
+
+	return true
+}
+
+// GetCategories returns the category taxonomy in builder order
+// GET /api/categories
+func GetCategories(c *gin.Context) {
+	var categories []models.Category
+	if err := db.GetDB().Order("sort_order ASC, display_name ASC").Find(&categories).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch categories",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  categories,
+		"count": len(categories),
+	})
+}
+
+// validateCategoryParent checks that parentID exists and that attaching the
+// category to it would not create a cycle
+func validateCategoryParent(categoryID uint, parentID uint) error {
+	visited := map[uint]bool{categoryID: true}
+	current := parentID
+	for {
+		if visited[current] {
+			return fmt.Errorf("category %d cannot be its own ancestor", categoryID)
+		}
+		visited[current] = true
+
+		var parent models.Category
+		if err := db.GetDB().First(&parent, current).Error; err != nil {
+			return fmt.Errorf("parent category %d does not exist", current)
+		}
+		if parent.ParentID == nil {
+			return nil
+		}
+		current = *parent.ParentID
+	}
+}
+
+// categoryUsage counts products and families referencing a category slug
+func categoryUsage(slug string) (int64, error) {
+	var products, families int64
+	if err := db.GetDB().Unscoped().Model(&models.Product{}).Where("category = ?", slug).Count(&products).Error; err != nil {
+		return 0, err
+	}
+	if err := db.GetDB().Model(&models.ProductFamily{}).Where("category = ?", slug).Count(&families).Error; err != nil {
+		return 0, err
+	}
+	return products + families, nil
+}
+
+// normalizeList trims, deduplicates and sorts a list of identifiers
+func normalizeList(values []string) models.StringList {
+	seen := make(map[string]bool, len(values))
+	result := make(models.StringList, 0, len(values))
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		result = append(result, v)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// CreateCategory creates a new category (Admin only)
+// POST /api/admin/categories
+func CreateCategory(c *gin.Context) {
+	var req CategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	req.Slug = strings.TrimSpace(req.Slug)
+
+	if req.ParentID != nil && *req.ParentID != 0 {
+		if err := validateCategoryParent(0, *req.ParentID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid parent category",
+				"details": err.Error(),
+			})
+			return
+		}
+	} else {
+		req.ParentID = nil
+	}
+
+	var existing int64
+	db.GetDB().Model(&models.Category{}).Where("slug = ?", req.Slug).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Category slug already exists",
+		})
+		return
+	}
+
+	category := models.Category{
+		Slug:          req.Slug,
+		DisplayName:   req.DisplayName,
+		SortOrder:     req.SortOrder,
+		ParentID:      req.ParentID,
+		Icon:          req.Icon,
+		Required:      req.Required,
+		SpecSchema:    req.SpecSchema,
+		RequiredSpecs: normalizeList(req.RequiredSpecs),
+		AnchorTypes:   normalizeList(req.AnchorTypes),
+	}
+
+	if err := db.GetDB().Create(&category).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create category",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Category created successfully",
+		"data":    category,
+	})
+}
+
+// UpdateCategory updates a category (Admin only)
+// The slug can only be changed while no product or family references it.
+// PUT /api/admin/categories/:id
+func UpdateCategory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid category ID",
+		})
+		return
+	}
+
+	var category models.Category
+	if err := db.GetDB().First(&category, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Category not found",
+		})
+		return
+	}
+
+	var req UpdateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	updates := make(map[string]interface{})
+	if req.Slug != nil && strings.TrimSpace(*req.Slug) != category.Slug {
+		slug := strings.TrimSpace(*req.Slug)
+
+		usage, err := categoryUsage(category.Slug)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to check category usage",
+			})
+			return
+		}
+		if usage > 0 {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":       "Cannot rename a category that is in use",
+				"usage_count": usage,
+			})
+			return
+		}
+
+		var existing int64
+		db.GetDB().Model(&models.Category{}).Where("slug = ?", slug).Count(&existing)
+		if existing > 0 {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "Category slug already exists",
+			})
+			return
+		}
+		updates["slug"] = slug
+	}
+	if req.DisplayName != nil {
+		updates["display_name"] = *req.DisplayName
+	}
+	if req.SortOrder != nil {
+		updates["sort_order"] = *req.SortOrder
+	}
+	if req.ParentID != nil {
+		if *req.ParentID == 0 {
+			updates["parent_id"] = nil
+		} else {
+			if err := validateCategoryParent(category.ID, *req.ParentID); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":   "Invalid parent category",
+					"details": err.Error(),
+				})
+				return
+			}
+			updates["parent_id"] = *req.ParentID
+		}
+	}
+	if req.Icon != nil {
+		updates["icon"] = *req.Icon
+	}
+	if req.Required != nil {
+		updates["required"] = *req.Required
+	}
+	if req.SpecSchema != nil {
+		updates["spec_schema"] = *req.SpecSchema
+	}
+	if req.RequiredSpecs != nil {
+		updates["required_specs"] = normalizeList(req.RequiredSpecs)
+	}
+	if req.AnchorTypes != nil {
+		updates["anchor_types"] = normalizeList(req.AnchorTypes)
+	}
+
+	if len(updates) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "No fields to update",
+		})
+		return
+	}
+
+	if err := db.GetDB().Model(&category).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update category",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	db.GetDB().First(&category, id)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Category updated successfully",
+		"data":    category,
+	})
+}
+
+// DeleteCategory deletes a category that has no products, families or subcategories (Admin only)
+// DELETE /api/admin/categories/:id
+func DeleteCategory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid category ID",
+		})
+		return
+	}
+
+	var category models.Category
+	if err := db.GetDB().First(&category, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Category not found",
+		})
+		return
+	}
+
+	usage, err := categoryUsage(category.Slug)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to check category usage",
+		})
+		return
+	}
+
+	var children int64
+	db.GetDB().Model(&models.Category{}).Where("parent_id = ?", category.ID).Count(&children)
+
+	if usage > 0 || children > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":         "Category is still in use",
+			"usage_count":   usage,
+			"subcategories": children,
+		})
+		return
+	}
+
+	if err := db.GetDB().Delete(&category).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to delete category",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Category deleted successfully",
+	})
+}
@@ -0,0 +1,302 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"fit-pc/db"
+	"fit-pc/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CloneProductRequest represents the optional request body for cloning a product.
+// Without a SKU a unique placeholder is generated.
+type CloneProductRequest struct {
+	Name *string `json:"name" binding:"omitempty,max=255"`
+	SKU  *string `json:"sku" binding:"omitempty,max=100"`
+}
+
+// CopyAnchorsRequest represents the request body for copying anchor points between products
+type CopyAnchorsRequest struct {
+	SourceProductID uint `json:"source_product_id" binding:"required"`
+}
+
+// Sizes of the name and sku columns, in characters
+const (
+	maxNameLength = 255
+	maxSKULength  = 100
+)
+
+// copyNameSuffix marks the name of a copy
+const copyNameSuffix = " (Copy)"
+
+// copySuffix matches the suffix placeholderSKU appends
+var copySuffix = regexp.MustCompile(`-COPY-[0-9A-F]{8}$`)
+
+// placeholderSKU generates a unique SKU for a copy, to be replaced by the admin.
+// The suffix of a copy of a copy replaces the previous one, and the original SKU
+// is truncated so the result fits the sku column.
+func placeholderSKU(sku string) string {
+	suffix := "-COPY-" + strings.ToUpper(uuid.New().String()[:8])
+	base := copySuffix.ReplaceAllString(sku, "")
+	return truncateRunes(base, maxSKULength-len(suffix)) + suffix
+}
+
+// copyName names a copy, truncating the original name so the result fits the
+// name column
+func copyName(name string) string {
+	return truncateRunes(name, maxNameLength-len(copyNameSuffix)) + copyNameSuffix
+}
+
+// truncateRunes cuts s to at most n characters, never inside a character
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+// cloneAnchorPoints returns a deep copy of anchor points, so edits to the copy
+// never alias the original's compatible types
+func cloneAnchorPoints(anchors models.AnchorPoints) models.AnchorPoints {
+	if anchors == nil {
+		return nil
+	}
+	result := make(models.AnchorPoints, len(anchors))
+	for i, anchor := range anchors {
+		result[i] = anchor
+		result[i].CompatibleTypes = append([]string(nil), anchor.CompatibleTypes...)
+	}
+	return result
+}
+
+// cloneTechnicalSpecs returns a copy of technical specs
+func cloneTechnicalSpecs(specs models.TechnicalSpecs) models.TechnicalSpecs {
+	if specs == nil {
+		return nil
+	}
+	result := make(models.TechnicalSpecs, len(specs))
+	for k, v := range specs {
+		result[k] = v
+	}
+	return result
+}
+
+// CloneProduct creates a draft copy of a product with its specs, anchor points,
+// model and thumbnail references, family and media gallery (Admin only)
+// POST /api/admin/products/:id/clone
+func CloneProduct(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	var req CloneProductRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid request body",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+
+	var source models.Product
+	if err := db.GetDB().Preload("Media", orderedMedia).First(&source, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Product not found",
+		})
+		return
+	}
+
+	clone := models.Product{
+		Name:                  copyName(source.Name),
+		SKU:                   placeholderSKU(source.SKU),
+		Category:              source.Category,
+		Price:                 source.Price,
+		ModelURL:              source.ModelURL,
+		ThumbnailURL:          source.ThumbnailURL,
+		ThumbnailSizes:        source.ThumbnailSizes,
+		GeneratedThumbnailURL: source.GeneratedThumbnailURL,
+		TechnicalSpecs:        cloneTechnicalSpecs(source.TechnicalSpecs),
+		AnchorPoints:          cloneAnchorPoints(source.AnchorPoints),
+		FamilyID:              source.FamilyID,
+		Status:                models.ProductStatusDraft,
+		AnchorTemplateID:      source.AnchorTemplateID,
+		AnchorTemplateVersion: source.AnchorTemplateVersion,
+		ModelMetadata:         source.ModelMetadata,
+		ModelFlagged:          source.ModelFlagged,
+	}
+	if req.Name != nil && strings.TrimSpace(*req.Name) != "" {
+		clone.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.SKU != nil && strings.TrimSpace(*req.SKU) != "" {
+		clone.SKU = strings.TrimSpace(*req.SKU)
+
+		var existing int64
+		db.GetDB().Unscoped().Model(&models.Product{}).Where("sku = ?", clone.SKU).Count(&existing)
+		if existing > 0 {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "SKU already exists",
+			})
+			return
+		}
+	}
+
+	err = db.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&clone).Error; err != nil {
+			return err
+		}
+
+		for _, media := range source.Media {
+			copied := models.MediaAsset{
+				ProductID: clone.ID,
+				Kind:      media.Kind,
+				BlobName:  media.BlobName,
+				URL:       media.URL,
+				AltText:   media.AltText,
+				SortOrder: media.SortOrder,
+				LODLevel:  media.LODLevel,
+			}
+			if err := tx.Create(&copied).Error; err != nil {
+				return err
+			}
+			clone.Media = append(clone.Media, copied)
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to clone product",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":   "Product cloned successfully",
+		"data":      clone,
+		"source_id": source.ID,
+	})
+}
+
+// CopyProductAnchors replaces the anchor points of a product with the (resolved)
+// anchor points of another product of the same category (Admin only)
+// Anchors that would block publishing a published product are rejected with 422.
+// Requires If-Match with the target product's current ETag.
+// POST /api/admin/products/:id/anchors/copy
+func CopyProductAnchors(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	var req CopyAnchorsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if uint64(req.SourceProductID) == id {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Source and target product must differ",
+		})
+		return
+	}
+
+	var product models.Product
+	if err := db.GetDB().First(&product, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Product not found",
+		})
+		return
+	}
+
+	var source models.Product
+	if err := db.GetDB().Preload("Family").First(&source, req.SourceProductID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Source product not found",
+		})
+		return
+	}
+
+	expected, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+	if product.Version != expected {
+		preconditionFailed(c, product.Version, "Product was modified by another user", product)
+		return
+	}
+
+	if source.Category != product.Category {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Cannot copy anchor points from a %s onto a %s", source.Category, product.Category),
+		})
+		return
+	}
+
+	anchors := cloneAnchorPoints(source.Resolved().AnchorPoints)
+	if len(anchors) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Source product has no anchor points",
+		})
+		return
+	}
+
+	updated := false
+	err = db.GetDB().Transaction(func(tx *gorm.DB) error {
+		check, err := guardPublished(tx, "id = ?", product.ID)
+		if err != nil {
+			return err
+		}
+		// The copied anchors are in the source's model space, not the template's
+		updated, err = updateVersioned(tx, &product, expected, withoutAnchorTemplate(map[string]interface{}{
+			"anchor_points": anchors,
+		}))
+		if err != nil || !updated {
+			return err
+		}
+		return check()
+	})
+	if respondPublishBlocked(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to copy anchor points",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	db.GetDB().First(&product, id)
+
+	if !updated {
+		preconditionFailed(c, product.Version, "Product was modified by another user", product)
+		return
+	}
+
+	setETag(c, product.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("Copied %d anchor points", len(anchors)),
+		"data":    product,
+	})
+}
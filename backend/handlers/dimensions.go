@@ -0,0 +1,396 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"fit-pc/db"
+	"fit-pc/internal/gltf"
+	"fit-pc/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Dimension spec keys, in millimetres. Height runs along a part's insertion
+// direction (or up, for parts without an input anchor); length and width are the
+// longer and shorter of the other two extents.
+const (
+	specLength = "length_mm"
+	specWidth  = "width_mm"
+	specHeight = "height_mm"
+)
+
+// defaultDimensionTolerance is the difference in millimetres between a spec and
+// the model below which they are considered to agree
+const defaultDimensionTolerance = 2.0
+
+// caseLimitSpecs maps clearance specs of cases to the outer dimension they cannot
+// exceed: a tower's graphics cards run along its length, coolers across its width
+var caseLimitSpecs = map[string]string{
+	"max_gpu_length_mm":        specLength,
+	"max_cpu_cooler_height_mm": specWidth,
+}
+
+// Dimension issue kinds
+const (
+	DimensionIssueMissing  = "missing"  // The spec is not set; autofill sets it from the model
+	DimensionIssueMismatch = "mismatch" // The spec and the model disagree by more than the tolerance
+	DimensionIssueExceeds  = "exceeds"  // A case clearance is larger than the case itself
+)
+
+// ProductDimensions are the outer dimensions of a product in millimetres,
+// measured on the bounding box of its analysed 3D model
+type ProductDimensions struct {
+	LengthMM float64 `json:"length_mm"`
+	WidthMM  float64 `json:"width_mm"`
+	HeightMM float64 `json:"height_mm"`
+	Frame    string  `json:"frame"` // Label of the input anchor measured from, or "model"
+}
+
+// spec returns the dimension for a dimension spec key
+func (d ProductDimensions) spec(key string) float64 {
+	switch key {
+	case specLength:
+		return d.LengthMM
+	case specWidth:
+		return d.WidthMM
+	}
+	return d.HeightMM
+}
+
+// DimensionIssue is a dimension spec that is missing or disagrees with the model
+type DimensionIssue struct {
+	Spec       string   `json:"spec"`
+	Kind       string   `json:"kind"` // See DimensionIssue*
+	SpecValue  *float64 `json:"spec_value,omitempty"`
+	ModelValue float64  `json:"model_value"`
+	Message    string   `json:"message"`
+}
+
+// DimensionCheck compares the dimension specs of a product with its model
+type DimensionCheck struct {
+	ProductID  uint               `json:"product_id"`
+	Name       string             `json:"name"`
+	SKU        string             `json:"sku"`
+	Category   string             `json:"category"`
+	Version    uint               `json:"version"`
+	Dimensions *ProductDimensions `json:"dimensions"`           // Nil when the model was not measured
+	Unmeasured string             `json:"unmeasured,omitempty"` // Why the model was not measured
+	Issues     []DimensionIssue   `json:"issues"`
+}
+
+// specNumber reads a numeric spec value, accepting numbers and numeric strings
+// with an optional "mm" unit
+func specNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(v), "mm")), 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// roundMM rounds a length to a tenth of a millimetre
+func roundMM(v float64) float64 {
+	return math.Round(v*10) / 10
+}
+
+// measureProduct returns the dimensions of a resolved product's model, measured
+// in the frame of its input anchor, or why it cannot be measured. The product
+// must carry the metadata of an analysis of its current model.
+func measureProduct(product models.Product, meta *models.ModelMetadata) (*ProductDimensions, string) {
+	switch {
+	case product.ModelURL == "":
+		return nil, "product has no 3D model"
+	case meta == nil || meta.ModelURL != product.ModelURL:
+		return nil, "model was not analysed; run the model analysis"
+	case !meta.Valid:
+		return nil, "model is invalid"
+	}
+
+	rotation := [3][3]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+	frame := "model"
+	for _, anchor := range product.AnchorPoints {
+		if anchor.Direction == "input" {
+			rotation, frame = gltf.AnchorRotation(anchor), anchor.Label
+			break
+		}
+	}
+
+	// Models are authored in centimetres
+	d := gltf.Measure(
+		[3]float64{meta.BoundsMin.X, meta.BoundsMin.Y, meta.BoundsMin.Z},
+		[3]float64{meta.BoundsMax.X, meta.BoundsMax.Y, meta.BoundsMax.Z},
+		rotation,
+	)
+	return &ProductDimensions{
+		LengthMM: roundMM(d.Length * 10),
+		WidthMM:  roundMM(d.Width * 10),
+		HeightMM: roundMM(d.Height * 10),
+		Frame:    frame,
+	}, ""
+}
+
+// checkDimensions compares the dimension specs of a product with its model. The
+// product's family must be preloaded so inherited specs and models are used.
+func checkDimensions(product models.Product, tolerance float64) DimensionCheck {
+	resolved := product.Resolved()
+	check := DimensionCheck{
+		ProductID: product.ID,
+		Name:      product.Name,
+		SKU:       product.SKU,
+		Category:  product.Category,
+		Version:   product.Version,
+		Issues:    []DimensionIssue{},
+	}
+	check.Dimensions, check.Unmeasured = measureProduct(resolved, product.ModelMetadata)
+	if check.Dimensions == nil {
+		return check
+	}
+
+	for _, key := range []string{specLength, specWidth, specHeight} {
+		modelValue := check.Dimensions.spec(key)
+		value, ok := resolved.TechnicalSpecs[key]
+		if !ok || value == nil || value == "" {
+			check.Issues = append(check.Issues, DimensionIssue{
+				Spec:       key,
+				Kind:       DimensionIssueMissing,
+				ModelValue: modelValue,
+				Message:    fmt.Sprintf("%s is not set; the model measures %.1f mm", key, modelValue),
+			})
+			continue
+		}
+		specValue, ok := specNumber(value)
+		if !ok {
+			check.Issues = append(check.Issues, DimensionIssue{
+				Spec:       key,
+				Kind:       DimensionIssueMismatch,
+				ModelValue: modelValue,
+				Message:    fmt.Sprintf("%s is not a number: %v", key, value),
+			})
+			continue
+		}
+		if math.Abs(specValue-modelValue) > tolerance {
+			check.Issues = append(check.Issues, DimensionIssue{
+				Spec:       key,
+				Kind:       DimensionIssueMismatch,
+				SpecValue:  &specValue,
+				ModelValue: modelValue,
+				Message:    fmt.Sprintf("%s is %g mm but the model measures %.1f mm", key, specValue, modelValue),
+			})
+		}
+	}
+
+	if strings.EqualFold(product.Category, "case") {
+		limits := make([]string, 0, len(caseLimitSpecs))
+		for key := range caseLimitSpecs {
+			limits = append(limits, key)
+		}
+		sort.Strings(limits)
+		for _, key := range limits {
+			specValue, ok := specNumber(resolved.TechnicalSpecs[key])
+			outer := check.Dimensions.spec(caseLimitSpecs[key])
+			if ok && specValue > outer+tolerance {
+				check.Issues = append(check.Issues, DimensionIssue{
+					Spec:       key,
+					Kind:       DimensionIssueExceeds,
+					SpecValue:  &specValue,
+					ModelValue: outer,
+					Message:    fmt.Sprintf("%s is %g mm but the case is only %.1f mm (%s)", key, specValue, outer, caseLimitSpecs[key]),
+				})
+			}
+		}
+	}
+	return check
+}
+
+// dimensionTolerance reads the tolerance_mm query parameter
+func dimensionTolerance(c *gin.Context) (float64, bool) {
+	raw := c.Query("tolerance_mm")
+	if raw == "" {
+		return defaultDimensionTolerance, true
+	}
+	tolerance, err := strconv.ParseFloat(raw, 64)
+	if err != nil || tolerance < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "tolerance_mm must be a non-negative number",
+		})
+		return 0, false
+	}
+	return tolerance, true
+}
+
+// DimensionSummary counts the products of a dimension report
+type DimensionSummary struct {
+	Checked    int `json:"checked"` // Products with a 3D model
+	Measured   int `json:"measured"`
+	Unmeasured int `json:"unmeasured"`
+	Missing    int `json:"missing"` // Products with missing dimension specs
+	Mismatched int `json:"mismatched"`
+	Exceeding  int `json:"exceeding"`
+}
+
+// GetDimensionReport compares the dimension specs of products (length_mm,
+// width_mm, height_mm and the clearances of cases) with the bounding box of their
+// analysed 3D model, measured in millimetres in the frame of the product's input
+// anchor (Admin only). Lists products with missing specs, specs differing by more
+// than tolerance_mm (default 2) and products whose model was not analysed; all=true
+// lists every product with a model.
+// GET /api/admin/data-quality/dimensions?category=&tolerance_mm=&all=
+func GetDimensionReport(c *gin.Context) {
+	tolerance, ok := dimensionTolerance(c)
+	if !ok {
+		return
+	}
+
+	query := db.GetDB().Preload("Family").Order("id")
+	if category := c.Query("category"); category != "" {
+		query = query.Where("category = ?", category)
+	}
+	var products []models.Product
+	if err := query.Find(&products).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch products",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	all := c.Query("all") == "true"
+	checks := []DimensionCheck{}
+	var summary DimensionSummary
+	for _, product := range products {
+		if product.Resolved().ModelURL == "" {
+			continue
+		}
+		check := checkDimensions(product, tolerance)
+		summary.Checked++
+		if check.Dimensions == nil {
+			summary.Unmeasured++
+		} else {
+			summary.Measured++
+		}
+		kinds := map[string]bool{}
+		for _, issue := range check.Issues {
+			kinds[issue.Kind] = true
+		}
+		if kinds[DimensionIssueMissing] {
+			summary.Missing++
+		}
+		if kinds[DimensionIssueMismatch] {
+			summary.Mismatched++
+		}
+		if kinds[DimensionIssueExceeds] {
+			summary.Exceeding++
+		}
+
+		if all || check.Dimensions == nil || len(check.Issues) > 0 {
+			checks = append(checks, check)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":         checks,
+		"summary":      summary,
+		"tolerance_mm": tolerance,
+	})
+}
+
+// AutofillDimensionsRequest limits an autofill to some products
+type AutofillDimensionsRequest struct {
+	ProductIDs []uint `json:"product_ids"`
+}
+
+// AutofilledProduct lists the specs set on a product by an autofill
+type AutofilledProduct struct {
+	ProductID uint               `json:"product_id"`
+	Version   uint               `json:"version"`
+	Filled    map[string]float64 `json:"filled"`
+}
+
+// AutofillDimensions sets the missing length_mm, width_mm and height_mm specs of
+// products from their analysed 3D model (Admin only). Specs that are already set
+// are never changed; disagreements are only reported by GetDimensionReport. The
+// body may list product_ids; without it every product with a measured model is
+// filled. Products modified during the autofill are reported as conflicts.
+// POST /api/admin/data-quality/dimensions/autofill
+func AutofillDimensions(c *gin.Context) {
+	var req AutofillDimensionsRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid request body",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+
+	query := db.GetDB().Preload("Family").Where("model_metadata IS NOT NULL").Order("id")
+	if req.ProductIDs != nil {
+		query = query.Where("id IN ?", req.ProductIDs)
+	}
+	var products []models.Product
+	if err := query.Find(&products).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch products",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	updated := []AutofilledProduct{}
+	conflicts := []uint{}
+	for _, product := range products {
+		filled := map[string]float64{}
+		for _, issue := range checkDimensions(product, defaultDimensionTolerance).Issues {
+			if issue.Kind == DimensionIssueMissing {
+				filled[issue.Spec] = issue.ModelValue
+			}
+		}
+		if len(filled) == 0 {
+			continue
+		}
+
+		// Inherited specs stay on the family; the product gets the missing ones
+		specs := make(models.TechnicalSpecs, len(product.TechnicalSpecs)+len(filled))
+		for k, v := range product.TechnicalSpecs {
+			specs[k] = v
+		}
+		for k, v := range filled {
+			specs[k] = v
+		}
+
+		ok, err := updateVersioned(db.GetDB(), &product, product.Version, map[string]interface{}{
+			"technical_specs": specs,
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to save specs",
+				"details": err.Error(),
+			})
+			return
+		}
+		if !ok {
+			conflicts = append(conflicts, product.ID)
+			continue
+		}
+		updated = append(updated, AutofilledProduct{ProductID: product.ID, Version: product.Version + 1, Filled: filled})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("Filled dimension specs of %d products", len(updated)),
+		"data": gin.H{
+			"updated":   updated,
+			"conflicts": conflicts,
+		},
+	})
+}
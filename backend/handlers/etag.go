@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"fit-pc/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// formatETag formats a resource version as a strong entity tag
+func formatETag(version uint) string {
+	return `"` + strconv.FormatUint(uint64(version), 10) + `"`
+}
+
+// parseETag extracts the version from an entity tag, ignoring a weak prefix
+func parseETag(tag string) (uint, bool) {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+	version, err := strconv.ParseUint(tag[1:len(tag)-1], 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return uint(version), true
+}
+
+// setETag sets the ETag response header for a resource version
+func setETag(c *gin.Context, version uint) {
+	c.Header("ETag", formatETag(version))
+}
+
+// notModified handles a conditional GET. It sets the ETag header and, when the
+// If-None-Match header matches the current version, responds with 304 and
+// returns true.
+func notModified(c *gin.Context, version uint) bool {
+	setETag(c, version)
+
+	header := c.GetHeader("If-None-Match")
+	if header == "" {
+		return false
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			c.Status(http.StatusNotModified)
+			return true
+		}
+		if v, ok := parseETag(tag); ok && v == version {
+			c.Status(http.StatusNotModified)
+			return true
+		}
+	}
+
+	return false
+}
+
+// ifMatchVersion reads the version the client expects from the If-Match header.
+// It writes a 428 response when the header is missing or unusable and returns false.
+func ifMatchVersion(c *gin.Context) (uint, bool) {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		c.JSON(http.StatusPreconditionRequired, gin.H{
+			"error": "If-Match header with the resource ETag is required",
+		})
+		return 0, false
+	}
+
+	version, ok := parseETag(header)
+	if !ok {
+		c.JSON(http.StatusPreconditionRequired, gin.H{
+			"error": "If-Match header must contain a single ETag",
+		})
+		return 0, false
+	}
+
+	return version, true
+}
+
+// preconditionFailed responds with 412 and the current state of the resource
+func preconditionFailed(c *gin.Context, version uint, message string, current interface{}) {
+	setETag(c, version)
+	c.JSON(http.StatusPreconditionFailed, gin.H{
+		"error": message,
+		"data":  current,
+	})
+}
+
+// updateVersioned applies updates to a versioned model only if its version still
+// equals expected, incrementing the version in the same statement. It returns
+// false when no row matched, i.e. the resource was modified concurrently.
+func updateVersioned(tx *gorm.DB, model interface{}, expected uint, updates map[string]interface{}) (bool, error) {
+	updates["version"] = gorm.Expr("version + 1")
+	result := tx.Model(model).Where("version = ?", expected).Updates(updates)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// bumpProductVersion increments the version of a product whose representation
+// changed without a direct update (media, ratings)
+func bumpProductVersion(tx *gorm.DB, productID uint) error {
+	return tx.Model(&models.Product{}).Where("id = ?", productID).UpdateColumn("version", gorm.Expr("version + 1")).Error
+}
+
+// bumpFamilyVariants increments the version of every variant of a family, since
+// their resolved representation changes with the family
+func bumpFamilyVariants(tx *gorm.DB, familyID uint) error {
+	return tx.Model(&models.Product{}).Where("family_id = ?", familyID).UpdateColumn("version", gorm.Expr("version + 1")).Error
+}
@@ -0,0 +1,480 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"fit-pc/db"
+	"fit-pc/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreateFamilyRequest represents the request body for creating a product family
+type CreateFamilyRequest struct {
+	Name           string                 `json:"name" binding:"required"`
+	Category       string                 `json:"category" binding:"required"`
+	ModelURL       string                 `json:"model_url"`
+	ThumbnailURL   string                 `json:"thumbnail_url"`
+	TechnicalSpecs map[string]interface{} `json:"technical_specs"`
+	AnchorPoints   []models.AnchorPoint   `json:"anchor_points"`
+}
+
+// UpdateFamilyRequest represents the request body for updating a product family
+type UpdateFamilyRequest struct {
+	Name           *string                `json:"name"`
+	Category       *string                `json:"category"`
+	ModelURL       *string                `json:"model_url"`
+	ThumbnailURL   *string                `json:"thumbnail_url"`
+	TechnicalSpecs map[string]interface{} `json:"technical_specs"`
+	AnchorPoints   []models.AnchorPoint   `json:"anchor_points"`
+}
+
+// validateFamilyAssignment checks that a product of the given category may join the family.
+// A nil or zero family ID means the product is standalone and is always valid.
+func validateFamilyAssignment(familyID *uint, category string) error {
+	if familyID == nil || *familyID == 0 {
+		return nil
+	}
+
+	var family models.ProductFamily
+	if err := db.GetDB().First(&family, *familyID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("product family %d does not exist", *familyID)
+		}
+		return err
+	}
+
+	if family.Category != category {
+		return fmt.Errorf("product category %q does not match family category %q", category, family.Category)
+	}
+
+	return nil
+}
+
+// familyUpdate validates a family or category change on an existing product and
+// returns the value to store in family_id (nil detaches the product). It writes
+// the error response and returns false when the change is not allowed.
+func familyUpdate(c *gin.Context, product models.Product, familyID *uint, category *string) (*uint, bool) {
+	newFamilyID := product.FamilyID
+	if familyID != nil {
+		newFamilyID = familyID
+		if *familyID == 0 {
+			newFamilyID = nil
+		}
+	}
+
+	newCategory := product.Category
+	if category != nil {
+		newCategory = *category
+	}
+
+	if err := validateFamilyAssignment(newFamilyID, newCategory); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid product family",
+			"details": err.Error(),
+		})
+		return nil, false
+	}
+
+	return newFamilyID, true
+}
+
+// GetFamily returns a product family together with its resolved, published variants
+// GET /api/families/:id
+func GetFamily(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid family ID",
+		})
+		return
+	}
+
+	var family models.ProductFamily
+	if err := db.GetDB().First(&family, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Product family not found",
+		})
+		return
+	}
+
+	var variants []models.Product
+	if err := db.GetDB().Scopes(publishedProducts).Preload("Family").Where("family_id = ?", family.ID).Order("price ASC").Find(&variants).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch variants",
+		})
+		return
+	}
+	resolveProducts(variants)
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"family":   family,
+			"variants": variants,
+		},
+	})
+}
+
+// GetAdminFamilies returns all product families with their variant counts
+// GET /api/admin/families?category=...
+func GetAdminFamilies(c *gin.Context) {
+	query := db.GetDB().Model(&models.ProductFamily{})
+	if category := c.Query("category"); category != "" {
+		query = query.Where("category = ?", category)
+	}
+
+	var families []models.ProductFamily
+	if err := query.Order("name ASC").Find(&families).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch product families",
+		})
+		return
+	}
+
+	type variantCount struct {
+		FamilyID uint
+		Count    int64
+	}
+	var counts []variantCount
+	if err := db.GetDB().Model(&models.Product{}).
+		Select("family_id, COUNT(*) AS count").
+		Where("family_id IS NOT NULL").
+		Group("family_id").
+		Scan(&counts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to count variants",
+		})
+		return
+	}
+
+	variantCounts := make(map[uint]int64, len(counts))
+	for _, vc := range counts {
+		variantCounts[vc.FamilyID] = vc.Count
+	}
+
+	data := make([]gin.H, len(families))
+	for i, family := range families {
+		data[i] = gin.H{
+			"family":        family,
+			"variant_count": variantCounts[family.ID],
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  data,
+		"count": len(data),
+	})
+}
+
+// GetAdminFamily returns a product family with its variants as stored (overrides only)
+// GET /api/admin/families/:id
+func GetAdminFamily(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid family ID",
+		})
+		return
+	}
+
+	var family models.ProductFamily
+	if err := db.GetDB().First(&family, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Product family not found",
+		})
+		return
+	}
+
+	var variants []models.Product
+	if err := db.GetDB().Where("family_id = ?", family.ID).Order("id ASC").Find(&variants).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch variants",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"family":   family,
+			"variants": variants,
+		},
+	})
+}
+
+// CreateFamily creates a new product family (Admin only)
+// POST /api/admin/families
+func CreateFamily(c *gin.Context) {
+	var req CreateFamilyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if !checkCategoryRefs(c, req.Category, req.AnchorPoints) {
+		return
+	}
+	if !checkAssetURLs(c, &req.ModelURL, &req.ThumbnailURL, "", "") {
+		return
+	}
+
+	family := models.ProductFamily{
+		Name:           req.Name,
+		Category:       req.Category,
+		ModelURL:       req.ModelURL,
+		ThumbnailURL:   req.ThumbnailURL,
+		ThumbnailSizes: thumbnailSizes(db.GetDB(), req.ThumbnailURL),
+		TechnicalSpecs: req.TechnicalSpecs,
+		AnchorPoints:   req.AnchorPoints,
+	}
+
+	if err := db.GetDB().Create(&family).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create product family",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Product family created successfully",
+		"data":    family,
+	})
+}
+
+// UpdateFamily updates a product family; changes apply to every variant that does not override them (Admin only)
+// A new model is analysed for the variants that inherit it once the update is committed.
+// An edit that would block publishing a published variant is rejected with 422.
+// PUT /api/admin/families/:id
+func UpdateFamily(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid family ID",
+		})
+		return
+	}
+
+	var family models.ProductFamily
+	if err := db.GetDB().First(&family, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Product family not found",
+		})
+		return
+	}
+
+	var req UpdateFamilyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if req.Category != nil || req.AnchorPoints != nil {
+		category := ""
+		if req.Category != nil {
+			category = *req.Category
+		}
+		if !checkCategoryRefs(c, category, req.AnchorPoints) {
+			return
+		}
+	}
+	if !checkAssetURLs(c, req.ModelURL, req.ThumbnailURL, family.ModelURL, family.ThumbnailURL) {
+		return
+	}
+
+	updates := make(map[string]interface{})
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.Category != nil && *req.Category != family.Category {
+		var variantCount int64
+		db.GetDB().Model(&models.Product{}).Where("family_id = ?", family.ID).Count(&variantCount)
+		if variantCount > 0 {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "Cannot change the category of a family that has variants",
+			})
+			return
+		}
+		updates["category"] = *req.Category
+	}
+	if req.ModelURL != nil {
+		updates["model_url"] = *req.ModelURL
+	}
+	if req.ThumbnailURL != nil {
+		updates["thumbnail_url"] = *req.ThumbnailURL
+		updates["thumbnail_sizes"] = thumbnailSizes(db.GetDB(), *req.ThumbnailURL)
+	}
+	if req.TechnicalSpecs != nil {
+		updates["technical_specs"] = models.TechnicalSpecs(req.TechnicalSpecs)
+	}
+	if req.AnchorPoints != nil {
+		updates["anchor_points"] = models.AnchorPoints(req.AnchorPoints)
+	}
+
+	if len(updates) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "No fields to update",
+		})
+		return
+	}
+
+	err = db.GetDB().Transaction(func(tx *gorm.DB) error {
+		check, err := guardPublished(tx, "family_id = ?", family.ID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&family).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := bumpFamilyVariants(tx, family.ID); err != nil {
+			return err
+		}
+		return check()
+	})
+	if respondPublishBlocked(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update product family",
+			"details": err.Error(),
+		})
+		return
+	}
+	if _, ok := updates["model_url"]; ok {
+		reanalyzeModels("family_id = ? AND model_url = ''", family.ID)
+	}
+
+	db.GetDB().First(&family, id)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Product family updated successfully",
+		"data":    family,
+	})
+}
+
+// UpdateFamilyAnchors updates the anchor points shared by all variants of a family (Admin only)
+// PATCH /api/admin/families/:id/anchors
+func UpdateFamilyAnchors(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid family ID",
+		})
+		return
+	}
+
+	var req UpdateAnchorPointsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	var family models.ProductFamily
+	if err := db.GetDB().First(&family, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Product family not found",
+		})
+		return
+	}
+
+	if !checkCategoryRefs(c, "", req.AnchorPoints) {
+		return
+	}
+
+	err = db.GetDB().Transaction(func(tx *gorm.DB) error {
+		check, err := guardPublished(tx, "family_id = ?", family.ID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&family).Update("anchor_points", models.AnchorPoints(req.AnchorPoints)).Error; err != nil {
+			return err
+		}
+		if err := bumpFamilyVariants(tx, family.ID); err != nil {
+			return err
+		}
+		return check()
+	})
+	if respondPublishBlocked(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update anchor points",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	db.GetDB().First(&family, id)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Family anchor points updated successfully",
+		"data":    family,
+	})
+}
+
+// DeleteFamily deletes a product family that no longer has variants (Admin only)
+// DELETE /api/admin/families/:id
+func DeleteFamily(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid family ID",
+		})
+		return
+	}
+
+	var family models.ProductFamily
+	if err := db.GetDB().First(&family, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Product family not found",
+		})
+		return
+	}
+
+	var variantCount int64
+	if err := db.GetDB().Model(&models.Product{}).Where("family_id = ?", family.ID).Count(&variantCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to count variants",
+		})
+		return
+	}
+	if variantCount > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":         "Product family still has variants",
+			"variant_count": variantCount,
+		})
+		return
+	}
+
+	err = db.GetDB().Transaction(func(tx *gorm.DB) error {
+		// Soft-deleted variants still reference the family; detach them first
+		if err := tx.Unscoped().Model(&models.Product{}).Where("family_id = ?", family.ID).Update("family_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&family).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to delete product family",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Product family deleted successfully",
+	})
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"fit-pc/internal/imaging"
+	"fit-pc/internal/storage"
+	"fit-pc/models"
+
+	"gorm.io/gorm"
+)
+
+// imageSizeBlobName returns the name of a resized copy of an image asset, stored
+// next to the original: <sha256>-<size><ext>
+func imageSizeBlobName(asset models.Asset, variant imaging.Variant) string {
+	return fmt.Sprintf("%s-%s%s", asset.SHA256, variant.Size.Name, variant.Ext)
+}
+
+// resizeImageAsset stores the list, card and detail copies of an image asset and
+// records their URLs on the asset and on the products and families already using
+// it as their thumbnail
+func resizeImageAsset(ctx context.Context, tx *gorm.DB, store storage.BlobStore, asset *models.Asset) error {
+	body, _, err := store.Open(ctx, asset.BlobName)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(io.LimitReader(body, maxUploadSizes[models.MediaKindImage]))
+	body.Close()
+	if err != nil {
+		return err
+	}
+
+	variants, err := imaging.Resize(data, imaging.Sizes)
+	if err != nil {
+		return err
+	}
+	sizes := make(models.ImageSizes, len(variants))
+	for _, variant := range variants {
+		name := imageSizeBlobName(*asset, variant)
+		if err := store.Put(ctx, name, bytes.NewReader(variant.Data), variant.ContentType); err != nil {
+			return fmt.Errorf("failed to store the %s size: %w", variant.Size.Name, err)
+		}
+		sizes[variant.Size.Name] = store.URL(name)
+	}
+
+	return tx.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(asset).Update("sizes", sizes).Error; err != nil {
+			return err
+		}
+		asset.Sizes = sizes
+		err := tx.Model(&models.Product{}).Where("thumbnail_url = ?", asset.URL).
+			Updates(map[string]interface{}{"thumbnail_sizes": sizes, "version": gorm.Expr("version + 1")}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.ProductFamily{}).Where("thumbnail_url = ?", asset.URL).Update("thumbnail_sizes", sizes).Error
+	})
+}
+
+// thumbnailSizes returns the resized copies of the image at url, or nil when it
+// is not an image asset or was not resized
+func thumbnailSizes(tx *gorm.DB, url string) models.ImageSizes {
+	if url == "" {
+		return nil
+	}
+	var asset models.Asset
+	if err := tx.Select("sizes").Where("url = ?", url).First(&asset).Error; err != nil {
+		return nil
+	}
+	return asset.Sizes
+}
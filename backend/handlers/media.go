@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"fit-pc/db"
+	"fit-pc/internal/storage"
+	"fit-pc/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreateMediaRequest represents the request body for attaching an uploaded blob to a product.
+// BlobName is the blob_name returned by GET /api/admin/upload-token.
+type CreateMediaRequest struct {
+	BlobName  string `json:"blob_name" binding:"required"`
+	Kind      string `json:"kind" binding:"required,oneof=image model model_lod datasheet"`
+	AltText   string `json:"alt_text" binding:"max=255"`
+	SortOrder int    `json:"sort_order"`
+	LODLevel  int    `json:"lod_level" binding:"gte=0"`
+}
+
+// UpdateMediaRequest represents the request body for updating media metadata
+type UpdateMediaRequest struct {
+	AltText   *string `json:"alt_text" binding:"omitempty,max=255"`
+	SortOrder *int    `json:"sort_order"`
+	LODLevel  *int    `json:"lod_level" binding:"omitempty,gte=0"`
+}
+
+// orderedMedia orders a product's media gallery for display
+func orderedMedia(tx *gorm.DB) *gorm.DB {
+	return tx.Order("sort_order ASC, id ASC")
+}
+
+// mediaKindAllowed reports whether a blob with the given name can be used as the media kind
+func mediaKindAllowed(blobName, kind string) bool {
+	for _, allowed := range allowedExtensions[strings.ToLower(filepath.Ext(blobName))] {
+		if allowed == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// GetProductMedia returns the media gallery of a product (Admin only)
+// GET /api/admin/products/:id/media
+func GetProductMedia(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	var product models.Product
+	if err := db.GetDB().Preload("Media", orderedMedia).First(&product, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Product not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  product.Media,
+		"count": len(product.Media),
+	})
+}
+
+// CreateProductMedia attaches an uploaded blob to a product's media gallery (Admin only)
+// The blob must be a confirmed asset, as returned by CompleteUpload.
+// POST /api/admin/products/:id/media
+func CreateProductMedia(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	var req CreateMediaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if !isValidBlobName(req.BlobName) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid blob name",
+		})
+		return
+	}
+	if !mediaKindAllowed(req.BlobName, req.Kind) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "File type does not match media kind " + req.Kind,
+		})
+		return
+	}
+
+	var product models.Product
+	if err := db.GetDB().First(&product, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Product not found",
+		})
+		return
+	}
+
+	// Uploads are moved to their content-addressed asset when confirmed, so only
+	// the asset's blob remains
+	url := storage.Get().URL(req.BlobName)
+	if err := checkAssetURL(db.GetDB(), url, allowedExtensions[strings.ToLower(filepath.Ext(req.BlobName))][0]); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid asset URL",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	media := models.MediaAsset{
+		ProductID: product.ID,
+		Kind:      req.Kind,
+		BlobName:  req.BlobName,
+		URL:       url,
+		AltText:   req.AltText,
+		SortOrder: req.SortOrder,
+		LODLevel:  req.LODLevel,
+	}
+
+	err = db.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&media).Error; err != nil {
+			return err
+		}
+		return bumpProductVersion(tx, product.ID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to add media",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Media added successfully",
+		"data":    media,
+	})
+}
+
+// UpdateProductMedia updates alt text, ordering or LOD level of a media asset (Admin only)
+// PUT /api/admin/products/:id/media/:mediaId
+func UpdateProductMedia(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	mediaID, err := strconv.ParseUint(c.Param("mediaId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid media ID",
+		})
+		return
+	}
+
+	var media models.MediaAsset
+	if err := db.GetDB().Where("id = ? AND product_id = ?", mediaID, id).First(&media).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Media not found",
+		})
+		return
+	}
+
+	var req UpdateMediaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	updates := make(map[string]interface{})
+	if req.AltText != nil {
+		updates["alt_text"] = *req.AltText
+	}
+	if req.SortOrder != nil {
+		updates["sort_order"] = *req.SortOrder
+	}
+	if req.LODLevel != nil {
+		updates["lod_level"] = *req.LODLevel
+	}
+
+	if len(updates) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "No fields to update",
+		})
+		return
+	}
+
+	err = db.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&media).Updates(updates).Error; err != nil {
+			return err
+		}
+		return bumpProductVersion(tx, media.ProductID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update media",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	db.GetDB().First(&media, mediaID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Media updated successfully",
+		"data":    media,
+	})
+}
+
+// DeleteProductMedia removes a media asset from a product's gallery (Admin only)
+// DELETE /api/admin/products/:id/media/:mediaId
+func DeleteProductMedia(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	mediaID, err := strconv.ParseUint(c.Param("mediaId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid media ID",
+		})
+		return
+	}
+
+	var media models.MediaAsset
+	if err := db.GetDB().Where("id = ? AND product_id = ?", mediaID, id).First(&media).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Media not found",
+		})
+		return
+	}
+
+	err = db.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&media).Error; err != nil {
+			return err
+		}
+		return bumpProductVersion(tx, media.ProductID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to delete media",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Media deleted successfully",
+	})
+}
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"fit-pc/db"
+	"fit-pc/internal/config"
+	"fit-pc/internal/gltf"
+	"fit-pc/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Plausible model sizes in centimetres. Models outside this range were most
+// likely exported in metres or millimetres.
+const (
+	minPlausibleModelSize = 1.0
+	maxPlausibleModelSize = 200.0
+)
+
+// analyzeModel validates a model and measures it. Invalid models are reported in
+// the metadata rather than as an error, so they can be flagged.
+func analyzeModel(data []byte, modelURL string, budget models.ModelBudget) models.ModelMetadata {
+	meta := models.ModelMetadata{
+		ModelURL:   modelURL,
+		SizeBytes:  int64(len(data)),
+		AnalyzedAt: time.Now(),
+	}
+
+	doc, err := gltf.Decode(data)
+	if err != nil {
+		meta.Errors = models.StringList{err.Error()}
+		meta.OverBudget = budget.Check(meta)
+		return meta
+	}
+
+	stats := doc.Stats()
+	meta.Triangles = stats.Triangles
+	meta.Vertices = stats.Vertices
+	meta.Meshes = stats.Meshes
+	meta.Materials = stats.Materials
+	meta.Textures = stats.Textures
+	meta.BoundsMin = models.Vector3{X: stats.Min[0], Y: stats.Min[1], Z: stats.Min[2]}
+	meta.BoundsMax = models.Vector3{X: stats.Max[0], Y: stats.Max[1], Z: stats.Max[2]}
+	size := stats.Size()
+	meta.Dimensions = models.Vector3{X: size[0], Y: size[1], Z: size[2]}
+
+	if stats.Empty {
+		meta.Errors = models.StringList{"the default scene contains no geometry"}
+	} else {
+		largest := max(size[0], size[1], size[2])
+		switch {
+		case largest < minPlausibleModelSize:
+			meta.Warnings = append(meta.Warnings, fmt.Sprintf("model is only %.2f cm across; it may be exported in metres instead of centimetres", largest))
+		case largest > maxPlausibleModelSize:
+			meta.Warnings = append(meta.Warnings, fmt.Sprintf("model is %.0f cm across; it may be exported in millimetres instead of centimetres", largest))
+		}
+	}
+
+	meta.Valid = len(meta.Errors) == 0
+	meta.OverBudget = budget.Check(meta)
+	return meta
+}
+
+// analyzeStoredModel downloads and analyses a stored model. A model too large to
+// download is reported in the metadata, like an invalid one.
+func analyzeStoredModel(modelURL string, budget models.ModelBudget) (models.ModelMetadata, error) {
+	data, err := fetchStoredModel(modelURL)
+	if errors.Is(err, errModelTooLarge) {
+		return models.ModelMetadata{
+			ModelURL:   modelURL,
+			Errors:     models.StringList{err.Error()},
+			AnalyzedAt: time.Now(),
+		}, nil
+	}
+	if err != nil {
+		return models.ModelMetadata{}, err
+	}
+	return analyzeModel(data, modelURL, budget), nil
+}
+
+// modelChanged reports whether product updates may change the model the product
+// resolves to
+func modelChanged(updates map[string]interface{}) bool {
+	_, model := updates["model_url"]
+	_, family := updates["family_id"]
+	return model || family
+}
+
+// reanalyzeModels analyses the model of the matching products whose metadata does
+// not describe the model they resolve to, after their or their family's model_url
+// changed. Products left without a model lose their metadata. Metadata of a model
+// that cannot be downloaded is cleared, to be filled by analysing it again.
+// It runs after the change is committed so no row lock is held while models are
+// downloaded; metadata is only stored if the product still resolves to the model
+// analysed, and the version is not bumped. Failures are logged.
+func reanalyzeModels(query string, args ...interface{}) {
+	var products []models.Product
+	if err := db.GetDB().Preload("Family").Where(query, args...).Find(&products).Error; err != nil {
+		log.Printf("Failed to load products to analyse: %v", err)
+		return
+	}
+
+	budget := config.GetConfig().ModelBudget
+	analysed := make(map[string]*models.ModelMetadata)
+	for _, product := range products {
+		modelURL := product.Resolved().ModelURL
+		if current := product.ModelMetadata; (current == nil && modelURL == "") || (current != nil && current.ModelURL == modelURL) {
+			continue
+		}
+
+		meta, done := analysed[modelURL]
+		if !done && modelURL != "" {
+			result, err := analyzeStoredModel(modelURL, budget)
+			if err != nil {
+				log.Printf("Failed to analyse model %s: %v", modelURL, err)
+			} else {
+				meta = &result
+			}
+			analysed[modelURL] = meta
+		}
+
+		columns := map[string]interface{}{"model_metadata": nil, "model_flagged": false}
+		if meta != nil {
+			columns["model_metadata"] = *meta
+			columns["model_flagged"] = !meta.Valid || len(meta.OverBudget) > 0
+		}
+		err := db.GetDB().Model(&models.Product{}).
+			Where("id = ? AND COALESCE(NULLIF(model_url, ''), (SELECT model_url FROM product_families WHERE product_families.id = products.family_id AND product_families.deleted_at IS NULL), '') = ?", product.ID, modelURL).
+			UpdateColumns(columns).Error
+		if err != nil {
+			log.Printf("Failed to store the metadata of model %s: %v", modelURL, err)
+		}
+	}
+}
+
+// AnalyzeProductModel downloads the product's model, validates its glTF structure
+// and stores its metadata (triangles, materials, textures and bounding box in cm)
+// on the product (Admin only). Invalid models and models over the configured
+// budgets are flagged. Models are analysed when a product's or family's model_url
+// changes; run this to analyse a model again, e.g. after the budgets changed.
+// POST /api/admin/products/:id/model/analyze
+func AnalyzeProductModel(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	var product models.Product
+	if err := db.GetDB().Preload("Family").First(&product, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Product not found",
+		})
+		return
+	}
+	resolved := product.Resolved()
+
+	if resolved.ModelURL == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Product has no 3D model",
+		})
+		return
+	}
+
+	budget := config.GetConfig().ModelBudget
+
+	meta, err := analyzeStoredModel(resolved.ModelURL, budget)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":   "Failed to load the product's model",
+			"details": err.Error(),
+		})
+		return
+	}
+	flagged := !meta.Valid || len(meta.OverBudget) > 0
+
+	updated, err := updateVersioned(db.GetDB(), &product, product.Version, map[string]interface{}{
+		"model_metadata": meta,
+		"model_flagged":  flagged,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to save model metadata",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	db.GetDB().First(&product, id)
+
+	if !updated {
+		preconditionFailed(c, product.Version, "Product was modified by another user", product)
+		return
+	}
+
+	message := "Model analysed successfully"
+	switch {
+	case !meta.Valid:
+		message = "Model is invalid"
+	case flagged:
+		message = "Model exceeds the configured budgets"
+	}
+
+	setETag(c, product.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"data":    meta,
+		"flagged": flagged,
+	})
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"fit-pc/internal/blobcache"
+	"fit-pc/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ServeModel streams a blob through the on-disk blob cache, for clients that cannot
+// read storage directly (CORS, expiring signed URLs). The same blobs as for
+// GET /api/download-token are served. Responses are cached privately for as long as
+// a download token is valid; Range, If-None-Match and If-Modified-Since requests are
+// supported.
+// GET /api/models/:blob
+func ServeModel(c *gin.Context) {
+	blobName := c.Param("blob")
+
+	allowed, err := downloadableBlobs(c, []string{blobName})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to resolve blob",
+			"details": err.Error(),
+		})
+		return
+	}
+	if !allowed[blobName] {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Blob not found",
+		})
+		return
+	}
+
+	f, info, err := blobcache.Get().Open(c.Request.Context(), blobName)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Blob not found",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":   "Failed to load the blob",
+			"details": err.Error(),
+		})
+		return
+	}
+	defer f.Close()
+
+	// Access can be revoked, e.g. by unpublishing, so responses are kept no longer
+	// than a download token and never by shared caches
+	c.Header("Cache-Control", fmt.Sprintf("private, max-age=%d", int(downloadTokenExpiry.Seconds())))
+	c.Header("Content-Type", info.ContentType)
+	if info.ETag != "" {
+		c.Header("ETag", info.ETag)
+	}
+	http.ServeContent(c.Writer, c.Request, blobName, info.LastModified, f)
+}
@@ -8,25 +8,39 @@ import (
 	"fit-pc/models"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-// GetParts returns all products, optionally filtered by category
-// GET /api/parts?category=...
+// GetParts returns all published products, optionally filtered by category
+// and sorted by rating (sort=rating)
+// GET /api/parts?category=...&sort=...
 func GetParts(c *gin.Context) {
 	var products []models.Product
-	query := db.GetDB()
+	query := db.GetDB().Scopes(publishedProducts).Preload("Family")
 
 	// Filter by category if provided
 	if category := c.Query("category"); category != "" {
 		query = query.Where("category = ?", category)
 	}
 
+	switch c.Query("sort") {
+	case "":
+	case "rating":
+		query = query.Order("rating_average DESC, rating_count DESC, id ASC")
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid sort parameter",
+		})
+		return
+	}
+
 	if err := query.Find(&products).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to fetch products",
 		})
 		return
 	}
+	resolveProducts(products)
 
 	c.JSON(http.StatusOK, gin.H{
 		"data":  products,
@@ -34,7 +48,7 @@ func GetParts(c *gin.Context) {
 	})
 }
 
-// GetPartDetails returns a single product by ID
+// GetPartDetails returns a single published product by ID
 // GET /api/parts/:id
 func GetPartDetails(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -46,18 +60,29 @@ func GetPartDetails(c *gin.Context) {
 	}
 
 	var product models.Product
-	if err := db.GetDB().First(&product, id).Error; err != nil {
+	if err := db.GetDB().Scopes(publishedProducts).Preload("Family").Preload("Media", orderedMedia).First(&product, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": "Product not found",
 		})
 		return
 	}
 
+	if notModified(c, product.Version) {
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"data": product,
+		"data": product.Resolved(),
 	})
 }
 
+// resolveProducts replaces each product in place with its family-resolved view
+func resolveProducts(products []models.Product) {
+	for i := range products {
+		products[i] = products[i].Resolved()
+	}
+}
+
 // GetCompatibleParts returns parts compatible with the given parent part's anchor points
 // GET /api/parts/:id/compatible
 func GetCompatibleParts(c *gin.Context) {
@@ -71,12 +96,13 @@ func GetCompatibleParts(c *gin.Context) {
 
 	// Get the parent part
 	var parentPart models.Product
-	if err := db.GetDB().First(&parentPart, id).Error; err != nil {
+	if err := db.GetDB().Scopes(publishedProducts).Preload("Family").First(&parentPart, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": "Product not found",
 		})
 		return
 	}
+	parentPart = parentPart.Resolved()
 
 	// Check if parent has anchor points
 	if len(parentPart.AnchorPoints) == 0 {
@@ -108,12 +134,13 @@ func GetCompatibleParts(c *gin.Context) {
 	// Find compatible parts by category
 	var compatibleParts []models.Product
 	if len(compatibleCategories) > 0 {
-		if err := db.GetDB().Where("category IN ?", compatibleCategories).Find(&compatibleParts).Error; err != nil {
+		if err := db.GetDB().Scopes(publishedProducts).Preload("Family").Where("category IN ?", compatibleCategories).Find(&compatibleParts).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error": "Failed to fetch compatible parts",
 			})
 			return
 		}
+		resolveProducts(compatibleParts)
 	}
 
 	// Advanced compatibility check: match socket types
@@ -168,9 +195,10 @@ type CreatePartRequest struct {
 	ThumbnailURL   string                 `json:"thumbnail_url"`
 	TechnicalSpecs map[string]interface{} `json:"technical_specs"`
 	AnchorPoints   []models.AnchorPoint   `json:"anchor_points"`
+	FamilyID       *uint                  `json:"family_id"`
 }
 
-// CreatePart creates a new product (Admin only)
+// CreatePart creates a new product (Admin only); its model is analysed
 // POST /api/admin/parts
 func CreatePart(c *gin.Context) {
 	var req CreatePartRequest
@@ -182,6 +210,22 @@ func CreatePart(c *gin.Context) {
 		return
 	}
 
+	if !checkCategoryRefs(c, req.Category, req.AnchorPoints) {
+		return
+	}
+	if !checkAssetURLs(c, &req.ModelURL, &req.ThumbnailURL, "", "") {
+		return
+	}
+
+	if err := validateFamilyAssignment(req.FamilyID, req.Category); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid product family",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// New products start as drafts and only reach the public catalog once published
 	product := models.Product{
 		Name:           req.Name,
 		SKU:            req.SKU,
@@ -189,8 +233,13 @@ func CreatePart(c *gin.Context) {
 		Price:          req.Price,
 		ModelURL:       req.ModelURL,
 		ThumbnailURL:   req.ThumbnailURL,
+		ThumbnailSizes: thumbnailSizes(db.GetDB(), req.ThumbnailURL),
 		TechnicalSpecs: req.TechnicalSpecs,
 		AnchorPoints:   req.AnchorPoints,
+		Status:         models.ProductStatusDraft,
+	}
+	if req.FamilyID != nil && *req.FamilyID != 0 {
+		product.FamilyID = req.FamilyID
 	}
 
 	if err := db.GetDB().Create(&product).Error; err != nil {
@@ -200,6 +249,8 @@ func CreatePart(c *gin.Context) {
 		})
 		return
 	}
+	reanalyzeModels("id = ?", product.ID)
+	db.GetDB().First(&product, product.ID)
 
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Product created successfully",
@@ -213,7 +264,11 @@ type UpdateAnchorPointsRequest struct {
 }
 
 // UpdatePartAnchors updates only the anchor points of a product (Admin only)
-// Used by the 3D Visual Editor
+// Used by the 3D Visual Editor. When the product inherits its anchors from a
+// family, the family anchors are updated so the change applies to every variant;
+// pass ?scope=variant to store an override on this product instead. Anchors
+// that would block publishing a published product are rejected with 422.
+// Requires If-Match with the product's current ETag.
 // PATCH /api/admin/parts/:id/anchors
 func UpdatePartAnchors(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -242,8 +297,63 @@ func UpdatePartAnchors(c *gin.Context) {
 		return
 	}
 
-	// Update only the anchor points
-	if err := db.GetDB().Model(&product).Update("anchor_points", models.AnchorPoints(req.AnchorPoints)).Error; err != nil {
+	expected, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+	if product.Version != expected {
+		preconditionFailed(c, product.Version, "Product was modified by another user", product)
+		return
+	}
+
+	if !checkCategoryRefs(c, "", req.AnchorPoints) {
+		return
+	}
+
+	// Update only the anchor points, on the family if the product inherits them
+	message := "Anchor points updated successfully"
+	updated := false
+	err = db.GetDB().Transaction(func(tx *gorm.DB) error {
+		if product.InheritsAnchors() && c.Query("scope") != "variant" {
+			message = "Family anchor points updated successfully"
+
+			// Every published variant of the family resolves to the new anchors
+			check, err := guardPublished(tx, "family_id = ?", *product.FamilyID)
+			if err != nil {
+				return err
+			}
+			if updated, err = updateVersioned(tx, &product, expected, map[string]interface{}{}); err != nil || !updated {
+				return err
+			}
+			family := models.ProductFamily{ID: *product.FamilyID}
+			if err := tx.Model(&family).Update("anchor_points", models.AnchorPoints(req.AnchorPoints)).Error; err != nil {
+				return err
+			}
+			// Sibling variants resolve to the new anchors too
+			if err := tx.Model(&models.Product{}).
+				Where("family_id = ? AND id <> ?", family.ID, product.ID).
+				UpdateColumn("version", gorm.Expr("version + 1")).Error; err != nil {
+				return err
+			}
+			return check()
+		}
+
+		check, err := guardPublished(tx, "id = ?", product.ID)
+		if err != nil {
+			return err
+		}
+		updated, err = updateVersioned(tx, &product, expected, withoutAnchorTemplate(map[string]interface{}{
+			"anchor_points": models.AnchorPoints(req.AnchorPoints),
+		}))
+		if err != nil || !updated {
+			return err
+		}
+		return check()
+	})
+	if respondPublishBlocked(c, err) {
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to update anchor points",
 			"details": err.Error(),
@@ -252,10 +362,16 @@ func UpdatePartAnchors(c *gin.Context) {
 	}
 
 	// Reload the product to get updated data
-	db.GetDB().First(&product, id)
+	db.GetDB().Preload("Family").First(&product, id)
 
+	if !updated {
+		preconditionFailed(c, product.Version, "Product was modified by another user", product)
+		return
+	}
+
+	setETag(c, product.Version)
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Anchor points updated successfully",
+		"message": message,
 		"data":    product,
 	})
 }
@@ -270,9 +386,13 @@ type UpdatePartRequest struct {
 	ThumbnailURL   *string                `json:"thumbnail_url"`
 	TechnicalSpecs map[string]interface{} `json:"technical_specs"`
 	AnchorPoints   []models.AnchorPoint   `json:"anchor_points"`
+	FamilyID       *uint                  `json:"family_id"` // 0 detaches the product from its family
 }
 
 // UpdatePart updates a product (Admin only)
+// A new model is analysed once the update is committed. An edit that would block
+// publishing a published product is rejected with 422.
+// Requires If-Match with the product's current ETag.
 // PUT /api/admin/parts/:id
 func UpdatePart(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -291,6 +411,15 @@ func UpdatePart(c *gin.Context) {
 		return
 	}
 
+	expected, ok := ifMatchVersion(c)
+	if !ok {
+		return
+	}
+	if product.Version != expected {
+		preconditionFailed(c, product.Version, "Product was modified by another user", product)
+		return
+	}
+
 	var req UpdatePartRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -300,6 +429,19 @@ func UpdatePart(c *gin.Context) {
 		return
 	}
 
+	if req.Category != nil || req.AnchorPoints != nil {
+		category := ""
+		if req.Category != nil {
+			category = *req.Category
+		}
+		if !checkCategoryRefs(c, category, req.AnchorPoints) {
+			return
+		}
+	}
+	if !checkAssetURLs(c, req.ModelURL, req.ThumbnailURL, product.ModelURL, product.ThumbnailURL) {
+		return
+	}
+
 	// Update fields if provided
 	updates := make(map[string]interface{})
 	if req.Name != nil {
@@ -319,25 +461,60 @@ func UpdatePart(c *gin.Context) {
 	}
 	if req.ThumbnailURL != nil {
 		updates["thumbnail_url"] = *req.ThumbnailURL
+		updates["thumbnail_sizes"] = thumbnailSizes(db.GetDB(), *req.ThumbnailURL)
 	}
 	if req.TechnicalSpecs != nil {
 		updates["technical_specs"] = models.TechnicalSpecs(req.TechnicalSpecs)
 	}
 	if req.AnchorPoints != nil {
 		updates["anchor_points"] = models.AnchorPoints(req.AnchorPoints)
+		withoutAnchorTemplate(updates)
+	}
+	if req.FamilyID != nil || req.Category != nil {
+		familyID, ok := familyUpdate(c, product, req.FamilyID, req.Category)
+		if !ok {
+			return
+		}
+		if req.FamilyID != nil {
+			updates["family_id"] = familyID
+		}
 	}
 
-	if err := db.GetDB().Model(&product).Updates(updates).Error; err != nil {
+	updated := false
+	err = db.GetDB().Transaction(func(tx *gorm.DB) error {
+		check, err := guardPublished(tx, "id = ?", product.ID)
+		if err != nil {
+			return err
+		}
+		updated, err = updateVersioned(tx, &product, expected, updates)
+		if err != nil || !updated {
+			return err
+		}
+		return check()
+	})
+	if respondPublishBlocked(c, err) {
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to update product",
 			"details": err.Error(),
 		})
 		return
 	}
+	if updated && modelChanged(updates) {
+		reanalyzeModels("id = ?", product.ID)
+	}
 
 	// Reload product
 	db.GetDB().First(&product, id)
 
+	if !updated {
+		preconditionFailed(c, product.Version, "Product was modified by another user", product)
+		return
+	}
+
+	setETag(c, product.Version)
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Product updated successfully",
 		"data":    product,
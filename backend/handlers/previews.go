@@ -0,0 +1,475 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"fit-pc/db"
+	"fit-pc/internal/blobcache"
+	"fit-pc/internal/gltf"
+	"fit-pc/internal/render"
+	"fit-pc/internal/storage"
+	"fit-pc/middleware"
+	"fit-pc/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Rendered image sizes: square product thumbnails, and build previews at the
+// size of link share cards
+const (
+	thumbnailRenderSize = 512
+	buildPreviewWidth   = 1200
+	buildPreviewHeight  = 630
+	unlinkedPartGap     = 5.0 // cm between parts laid out beside the assembly
+)
+
+// buildPreviewBackground keeps share cards opaque
+var buildPreviewBackground = color.NRGBA{R: 245, G: 245, B: 247, A: 255}
+
+// errNothingToRender is returned when no model of a build could be rendered
+var errNothingToRender = errors.New("no component has a 3D model that can be rendered")
+
+// loadTriangles downloads a stored model and returns its triangles in world space
+func loadTriangles(modelURL string) ([]gltf.Triangle, error) {
+	data, err := fetchStoredModel(modelURL)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := gltf.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	return doc.Triangles()
+}
+
+// encodePNG encodes a rendering
+func encodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// storeRenderedImage stores a PNG rendering under its SHA-256 hash and registers
+// it as an image asset, or returns the asset of an identical image
+func storeRenderedImage(ctx context.Context, tx *gorm.DB, store storage.BlobStore, data []byte, uploadedBy string) (models.Asset, error) {
+	digest := sha256.Sum256(data)
+	sum := hex.EncodeToString(digest[:])
+	existing, err := findAsset(tx, sum)
+	if err != nil {
+		return models.Asset{}, err
+	}
+
+	// Storing the image again also keeps an existing copy from being collected
+	name := sum + ".png"
+	if existing != nil {
+		name = existing.BlobName
+	}
+	if err := store.Put(ctx, name, bytes.NewReader(data), "image/png"); err != nil {
+		return models.Asset{}, fmt.Errorf("failed to store %s: %w", name, err)
+	}
+	if existing != nil {
+		return *existing, nil
+	}
+
+	asset := models.Asset{
+		BlobName:    name,
+		URL:         store.URL(name),
+		Kind:        models.MediaKindImage,
+		ContentType: "image/png",
+		SizeBytes:   int64(len(data)),
+		SHA256:      sum,
+		UploadedBy:  uploadedBy,
+	}
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&asset)
+	if result.Error != nil {
+		return models.Asset{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		err = tx.Where("blob_name = ?", name).First(&asset).Error
+	}
+	return asset, err
+}
+
+// renderErrorStatus maps a failure to load or render a model to a response status
+func renderErrorStatus(err error) int {
+	if errors.Is(err, gltf.ErrInvalidModel) || errors.Is(err, gltf.ErrExternalBuffer) || errors.Is(err, render.ErrEmptyScene) ||
+		errors.Is(err, errModelTooLarge) || errors.Is(err, errNothingToRender) {
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusBadGateway
+}
+
+// RenderProductThumbnail renders a preview of the product's 3D model on the server
+// and stores it as the product's generated thumbnail, a 512x512 PNG with a
+// transparent background seen from a fixed three-quarter angle (Admin only). The
+// projection query parameter selects perspective (default) or orthographic.
+// Render again after changing the model.
+// POST /api/admin/products/:id/thumbnail/render
+func RenderProductThumbnail(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	projection, err := render.ParseProjection(c.Query("projection"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid projection",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	var product models.Product
+	if err := db.GetDB().Preload("Family").First(&product, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Product not found",
+		})
+		return
+	}
+	resolved := product.Resolved()
+
+	if resolved.ModelURL == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Product has no 3D model",
+		})
+		return
+	}
+
+	triangles, err := loadTriangles(resolved.ModelURL)
+	var img *image.RGBA
+	if err == nil {
+		var scene render.Scene
+		scene.Add(triangles, gltf.Identity())
+		img, err = render.Render(&scene, render.Options{Width: thumbnailRenderSize, Height: thumbnailRenderSize, Projection: projection})
+	}
+	if err != nil {
+		c.JSON(renderErrorStatus(err), gin.H{
+			"error":   "Failed to render the product's model",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	data, err := encodePNG(img)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to encode the thumbnail",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(c)
+	asset, err := storeRenderedImage(c.Request.Context(), db.GetDB(), storage.Get(), data, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to store the thumbnail",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	updated, err := updateVersioned(db.GetDB(), &product, product.Version, map[string]interface{}{
+		"generated_thumbnail_url": asset.URL,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to save the thumbnail",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	db.GetDB().First(&product, id)
+
+	if !updated {
+		preconditionFailed(c, product.Version, "Product was modified by another user", product)
+		return
+	}
+
+	setETag(c, product.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Thumbnail rendered successfully",
+		"data":    product,
+	})
+}
+
+// anchorsConnect reports whether two anchors can be joined: either lists the
+// other's type as compatible
+func anchorsConnect(a, b models.AnchorPoint) bool {
+	return slices.Contains(a.CompatibleTypes, b.Name) || slices.Contains(b.CompatibleTypes, a.Name)
+}
+
+// anchorTransform returns the transform from a part's space to the space of the
+// anchor, positioned and rotated (XYZ Euler angles in radians) on the part
+func anchorTransform(anchor models.AnchorPoint) gltf.Mat4 {
+	position := [3]float64{anchor.Position.X, anchor.Position.Y, anchor.Position.Z}
+	return gltf.Transform(position, gltf.EulerMatrix([3]float64{anchor.Rotation.X, anchor.Rotation.Y, anchor.Rotation.Z}))
+}
+
+// translation returns the matrix moving points by offset
+func translation(offset [3]float64) gltf.Mat4 {
+	return gltf.Transform(offset, [3][3]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}})
+}
+
+// triangleBounds returns the bounding box of triangles
+func triangleBounds(triangles []gltf.Triangle) (lo, hi [3]float64) {
+	lo = [3]float64{math.Inf(1), math.Inf(1), math.Inf(1)}
+	hi = [3]float64{math.Inf(-1), math.Inf(-1), math.Inf(-1)}
+	for _, t := range triangles {
+		for _, v := range t.Vertices {
+			for axis := range v {
+				lo[axis], hi[axis] = math.Min(lo[axis], v[axis]), math.Max(hi[axis], v[axis])
+			}
+		}
+	}
+	return lo, hi
+}
+
+// assembleBuild places the components of a build the way the builder does: the
+// case (or else the motherboard, or else the first part) sits at the origin and
+// every other part is joined to an already placed part through a pair of
+// compatible anchors: its anchor is moved onto the other's position and the part
+// turned by the other's rotation. Parts that cannot be joined are laid out in a row beside the
+// assembly. load returns the triangles of a component, or nil to leave it out.
+func assembleBuild(components models.BuildComponents, load func(models.BuildComponent) []gltf.Triangle) *render.Scene {
+	type part struct {
+		component models.BuildComponent
+		triangles []gltf.Triangle
+		transform gltf.Mat4
+		placed    bool
+	}
+	var parts []*part
+	for _, component := range components {
+		if triangles := load(component); len(triangles) > 0 {
+			parts = append(parts, &part{component: component, triangles: triangles})
+		}
+	}
+	scene := &render.Scene{}
+	if len(parts) == 0 {
+		return scene
+	}
+
+	root := parts[0]
+	for _, category := range []string{"case", "motherboard"} {
+		if i := slices.IndexFunc(parts, func(p *part) bool { return strings.EqualFold(p.component.Category, category) }); i >= 0 {
+			root = parts[i]
+			break
+		}
+	}
+	root.transform, root.placed = gltf.Identity(), true
+	placed := []*part{root}
+
+	// Join parts until none can be joined to the assembly any more
+	for joined := true; joined; {
+		joined = false
+		for _, child := range parts {
+			if child.placed {
+				continue
+			}
+		search:
+			for _, parent := range placed {
+				for _, a := range parent.component.AnchorPoints {
+					for _, b := range child.component.AnchorPoints {
+						if !anchorsConnect(a, b) {
+							continue
+						}
+						offset := translation([3]float64{-b.Position.X, -b.Position.Y, -b.Position.Z})
+						child.transform = parent.transform.Mul(anchorTransform(a)).Mul(offset)
+						child.placed, joined = true, true
+						placed = append(placed, child)
+						break search
+					}
+				}
+			}
+		}
+	}
+	for _, p := range placed {
+		scene.Add(p.triangles, p.transform)
+	}
+
+	// Unjoined parts stand in a row to the right, on the same floor
+	var assembly []gltf.Triangle
+	for _, p := range placed {
+		for _, t := range p.triangles {
+			for i := range t.Vertices {
+				t.Vertices[i] = p.transform.Apply(t.Vertices[i])
+			}
+			assembly = append(assembly, t)
+		}
+	}
+	lo, hi := triangleBounds(assembly)
+	cursor := hi[0] + unlinkedPartGap
+	for _, p := range parts {
+		if p.placed {
+			continue
+		}
+		partLo, partHi := triangleBounds(p.triangles)
+		offset := [3]float64{cursor - partLo[0], lo[1] - partLo[1], (lo[2]+hi[2])/2 - (partLo[2]+partHi[2])/2}
+		scene.Add(p.triangles, translation(offset))
+		cursor += partHi[0] - partLo[0] + unlinkedPartGap
+	}
+	return scene
+}
+
+// renderBuildPreview renders an assembled build as a PNG. Components are drawn
+// with the model their catalog product resolves to, never the URL stored in the
+// build; components without a stored model, or whose model cannot be read, are
+// left out.
+func renderBuildPreview(build models.Build, projection render.Projection) ([]byte, error) {
+	catalog, err := catalogModelURLs(db.GetDB(), build.Components)
+	if err != nil {
+		return nil, err
+	}
+	cache := map[string][]gltf.Triangle{}
+	scene := assembleBuild(build.Components, func(component models.BuildComponent) []gltf.Triangle {
+		modelURL := catalog[component.ID]
+		if modelURL == "" {
+			return nil
+		}
+		triangles, ok := cache[modelURL]
+		if !ok {
+			var err error
+			triangles, err = loadTriangles(modelURL)
+			if err != nil {
+				log.Printf("Build %d preview: skipping %s: %v", build.ID, component.Name, err)
+			}
+			cache[modelURL] = triangles
+		}
+		return triangles
+	})
+	if scene.Len() == 0 {
+		return nil, errNothingToRender
+	}
+
+	img, err := render.Render(scene, render.Options{
+		Width:      buildPreviewWidth,
+		Height:     buildPreviewHeight,
+		Projection: projection,
+		Background: buildPreviewBackground,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return encodePNG(img)
+}
+
+// buildPreviewBlobName names the stored preview of a build version. Previews of
+// older versions are no longer referenced and are removed by the blob collector.
+func buildPreviewBlobName(build models.Build, projection render.Projection) string {
+	return fmt.Sprintf("build-%d-%d-%s.png", build.ID, build.Version, projection)
+}
+
+// GetBuildPreview returns a 1200x630 PNG of the assembled build for share cards,
+// rendered on the server with parts joined through their anchor points. Parts
+// without a usable 3D model are left out. The projection query parameter selects
+// perspective (default) or orthographic. Previews are rendered once per build
+// version and served from storage afterwards.
+// GET /api/user/builds/:id/preview
+func GetBuildPreview(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid build ID",
+		})
+		return
+	}
+
+	var build models.Build
+	if err := db.GetDB().Where("id = ? AND user_id = ?", id, userID).First(&build).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Build not found",
+		})
+		return
+	}
+
+	serveBuildPreview(c, build)
+}
+
+// serveBuildPreview responds with the preview of a build in the projection of the
+// request, rendering and storing it on first use
+func serveBuildPreview(c *gin.Context, build models.Build) {
+	projection, err := render.ParseProjection(c.Query("projection"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid projection",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	store := storage.Get()
+	name := buildPreviewBlobName(build, projection)
+	if _, err := store.Stat(ctx, name); errors.Is(err, storage.ErrNotFound) {
+		data, err := renderBuildPreview(build, projection)
+		if err != nil {
+			c.JSON(renderErrorStatus(err), gin.H{
+				"error":   "Failed to render the build",
+				"details": err.Error(),
+			})
+			return
+		}
+		if err := store.Put(ctx, name, bytes.NewReader(data), "image/png"); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to store the preview",
+				"details": err.Error(),
+			})
+			return
+		}
+	} else if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":   "Failed to load the preview",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	f, info, err := blobcache.Get().Open(ctx, name)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":   "Failed to load the preview",
+			"details": err.Error(),
+		})
+		return
+	}
+	defer f.Close()
+
+	// The URL serves the build's latest version, so clients revalidate
+	c.Header("Cache-Control", "private, no-cache")
+	c.Header("Content-Type", "image/png")
+	if info.ETag != "" {
+		c.Header("ETag", info.ETag)
+	}
+	http.ServeContent(c.Writer, c.Request, name, info.LastModified, f)
+}
@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"fit-pc/db"
+	"fit-pc/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ChangeProductStatusRequest represents the request body for moving a product through the lifecycle.
+// PublishAt schedules publication and is only used with the published status.
+type ChangeProductStatusRequest struct {
+	Status    string     `json:"status" binding:"required,oneof=draft in_review published archived"`
+	PublishAt *time.Time `json:"publish_at"`
+}
+
+// productStatusTransitions lists the statuses a product may move to from each status.
+// A published product may also be published again to change its schedule.
+var productStatusTransitions = map[string][]string{
+	models.ProductStatusDraft:     {models.ProductStatusInReview, models.ProductStatusPublished, models.ProductStatusArchived},
+	models.ProductStatusInReview:  {models.ProductStatusDraft, models.ProductStatusPublished, models.ProductStatusArchived},
+	models.ProductStatusPublished: {models.ProductStatusPublished, models.ProductStatusDraft, models.ProductStatusArchived},
+	models.ProductStatusArchived:  {models.ProductStatusDraft},
+}
+
+func statusTransitionAllowed(from, to string) bool {
+	for _, allowed := range productStatusTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// publishedProducts limits a product query to the public catalog
+func publishedProducts(tx *gorm.DB) *gorm.DB {
+	return tx.Where("products.status = ? AND (products.publish_at IS NULL OR products.publish_at <= ?)", models.ProductStatusPublished, time.Now())
+}
+
+// publishProblems lists what prevents a product from being published: a missing
+// model, missing anchor points or specs required by its category. The product's
+// family must be preloaded so inherited values are taken into account.
+func publishProblems(product models.Product) ([]string, error) {
+	resolved := product.Resolved()
+	problems := []string{}
+
+	if resolved.ModelURL == "" {
+		problems = append(problems, "3D model is missing")
+	} else if meta := product.ModelMetadata; meta != nil && meta.ModelURL == resolved.ModelURL && !meta.Valid {
+		problems = append(problems, "3D model is invalid")
+	}
+	if len(resolved.AnchorPoints) == 0 {
+		problems = append(problems, "anchor points are missing")
+	}
+
+	var category models.Category
+	if err := db.GetDB().Where("slug = ?", resolved.Category).First(&category).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		problems = append(problems, fmt.Sprintf("unknown category %q", resolved.Category))
+		return problems, nil
+	}
+
+	for _, spec := range category.RequiredSpecs {
+		if value, ok := resolved.TechnicalSpecs[spec]; !ok || value == nil || value == "" {
+			problems = append(problems, fmt.Sprintf("required spec %q is missing", spec))
+		}
+	}
+
+	return problems, nil
+}
+
+// publishBlockedError reports the problems that an edit of a published product
+// or of its family would introduce
+type publishBlockedError struct {
+	problems []string
+}
+
+func (e publishBlockedError) Error() string {
+	return "published product would become unpublishable"
+}
+
+// publishedProblems runs publishProblems within tx on the matching products that
+// are published
+func publishedProblems(tx *gorm.DB, query string, args ...interface{}) ([]models.Product, map[uint][]string, error) {
+	var products []models.Product
+	if err := tx.Preload("Family").Where("status = ?", models.ProductStatusPublished).Where(query, args...).Find(&products).Error; err != nil {
+		return nil, nil, err
+	}
+	problems := make(map[uint][]string, len(products))
+	for _, product := range products {
+		p, err := publishProblems(product)
+		if err != nil {
+			return nil, nil, err
+		}
+		problems[product.ID] = p
+	}
+	return products, problems, nil
+}
+
+// guardPublished records the publish problems of the matching published products
+// before an edit within tx, and returns a check to run after it. The check fails
+// when the edit gives a product a problem it did not have, so that a published
+// product cannot lose what publishing requires; products published before a rule
+// existed can still be edited. Problems of several products are prefixed with the
+// product they concern.
+func guardPublished(tx *gorm.DB, query string, args ...interface{}) (func() error, error) {
+	_, before, err := publishedProblems(tx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return func() error {
+		products, after, err := publishedProblems(tx, query, args...)
+		if err != nil {
+			return err
+		}
+		var blocked []string
+		for _, product := range products {
+			known := make(map[string]bool)
+			for _, problem := range before[product.ID] {
+				known[problem] = true
+			}
+			for _, problem := range after[product.ID] {
+				if known[problem] {
+					continue
+				}
+				if len(products) > 1 {
+					problem = fmt.Sprintf("%s (#%d): %s", product.Name, product.ID, problem)
+				}
+				blocked = append(blocked, problem)
+			}
+		}
+		if len(blocked) > 0 {
+			return publishBlockedError{blocked}
+		}
+		return nil
+	}, nil
+}
+
+// respondPublishBlocked responds 422 when err is a publishBlockedError, as
+// ChangeProductStatus does, and reports whether it did
+func respondPublishBlocked(c *gin.Context, err error) bool {
+	var blocked publishBlockedError
+	if !errors.As(err, &blocked) {
+		return false
+	}
+	c.JSON(http.StatusUnprocessableEntity, gin.H{
+		"error":    "Published products cannot lose their model, anchor points or required specs",
+		"problems": blocked.problems,
+	})
+	return true
+}
+
+// ChangeProductStatus moves a product through the draft, in review, published and
+// archived lifecycle (Admin only). Publishing is blocked while the model, anchor
+// points or required specs are missing; publish_at schedules the product.
+// POST /api/admin/products/:id/status
+func ChangeProductStatus(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	var req ChangeProductStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if req.PublishAt != nil && req.Status != models.ProductStatusPublished {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "publish_at can only be set when publishing",
+		})
+		return
+	}
+
+	var product models.Product
+	if err := db.GetDB().Preload("Family").First(&product, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Product not found",
+		})
+		return
+	}
+
+	if !statusTransitionAllowed(product.Status, req.Status) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   fmt.Sprintf("Cannot change status from %s to %s", product.Status, req.Status),
+			"allowed": productStatusTransitions[product.Status],
+		})
+		return
+	}
+
+	if req.Status == models.ProductStatusPublished {
+		problems, err := publishProblems(product)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to validate product",
+			})
+			return
+		}
+		if len(problems) > 0 {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":    "Product cannot be published",
+				"problems": problems,
+			})
+			return
+		}
+	}
+
+	updated, err := updateVersioned(db.GetDB(), &product, product.Version, map[string]interface{}{
+		"status":     req.Status,
+		"publish_at": req.PublishAt,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to change product status",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	db.GetDB().First(&product, id)
+
+	if !updated {
+		preconditionFailed(c, product.Version, "Product was modified by another user", product)
+		return
+	}
+
+	setETag(c, product.Version)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Product status changed to " + req.Status,
+		"data":    product,
+	})
+}
+
+// PreviewProduct returns a product as the public catalog would show it, regardless
+// of its status, together with what still blocks publishing (Admin only)
+// GET /api/admin/products/:id/preview
+func PreviewProduct(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	var product models.Product
+	if err := db.GetDB().Preload("Family").Preload("Media", orderedMedia).First(&product, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Product not found",
+		})
+		return
+	}
+
+	problems, err := publishProblems(product)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to validate product",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":             product.Resolved(),
+		"visible":          product.IsVisible(time.Now()),
+		"publish_problems": problems,
+	})
+}
@@ -0,0 +1,495 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"fit-pc/db"
+	"fit-pc/middleware"
+	"fit-pc/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreateReviewRequest represents the request body for reviewing a product
+type CreateReviewRequest struct {
+	ProductID uint   `json:"product_id" binding:"required"`
+	Rating    int    `json:"rating" binding:"required,min=1,max=5"`
+	Body      string `json:"body" binding:"max=5000"`
+	BuildID   *uint  `json:"build_id"`
+}
+
+// UpdateReviewRequest represents the request body for editing an own review
+type UpdateReviewRequest struct {
+	Rating  *int    `json:"rating" binding:"omitempty,min=1,max=5"`
+	Body    *string `json:"body" binding:"omitempty,max=5000"`
+	BuildID *uint   `json:"build_id"` // 0 removes the build reference
+}
+
+// ModerateReviewRequest represents the request body for moderating a review
+type ModerateReviewRequest struct {
+	Status string `json:"status" binding:"required,oneof=pending approved rejected"`
+	Note   string `json:"note" binding:"max=500"`
+}
+
+type ReviewListQuery struct {
+	Page  int `form:"page,default=1" binding:"min=1"`
+	Limit int `form:"limit,default=10" binding:"min=1,max=100"`
+}
+
+// PublicReview is the representation of an approved review shown to everyone.
+// It intentionally omits the reviewer's user ID.
+type PublicReview struct {
+	ID            uint      `json:"id"`
+	Rating        int       `json:"rating"`
+	Body          string    `json:"body"`
+	VerifiedBuild bool      `json:"verified_build"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// refreshProductRating recomputes the aggregated rating of a product from its approved reviews
+func refreshProductRating(tx *gorm.DB, productID uint) error {
+	return tx.Model(&models.Product{}).Where("id = ?", productID).UpdateColumns(map[string]interface{}{
+		"rating_average": gorm.Expr("COALESCE((SELECT AVG(rating) FROM reviews WHERE product_id = ? AND status = ?), 0)", productID, models.ReviewStatusApproved),
+		"rating_count":   gorm.Expr("(SELECT COUNT(*) FROM reviews WHERE product_id = ? AND status = ?)", productID, models.ReviewStatusApproved),
+		"version":        gorm.Expr("version + 1"),
+	}).Error
+}
+
+// buildContainsProduct reports whether the user's build includes the product
+func buildContainsProduct(userID string, buildID, productID uint) bool {
+	var build models.Build
+	if err := db.GetDB().Where("id = ? AND user_id = ?", buildID, userID).First(&build).Error; err != nil {
+		return false
+	}
+	for _, component := range build.Components {
+		if component.ID == productID {
+			return true
+		}
+	}
+	return false
+}
+
+// GetProductReviews returns the approved reviews of a product
+// GET /api/parts/:id/reviews?page=&limit=
+func GetProductReviews(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	var query ReviewListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid query parameters",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	var product models.Product
+	if err := db.GetDB().Scopes(publishedProducts).First(&product, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Product not found",
+		})
+		return
+	}
+
+	dbQuery := db.GetDB().Model(&models.Review{}).Where("product_id = ? AND status = ?", product.ID, models.ReviewStatusApproved)
+
+	var total int64
+	if err := dbQuery.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to count reviews",
+		})
+		return
+	}
+
+	lastPage := int(math.Ceil(float64(total) / float64(query.Limit)))
+	if lastPage == 0 {
+		lastPage = 1
+	}
+
+	var reviews []models.Review
+	if err := dbQuery.Offset((query.Page - 1) * query.Limit).Limit(query.Limit).Order("created_at DESC").Find(&reviews).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch reviews",
+		})
+		return
+	}
+
+	data := make([]PublicReview, len(reviews))
+	for i, review := range reviews {
+		data[i] = PublicReview{
+			ID:            review.ID,
+			Rating:        review.Rating,
+			Body:          review.Body,
+			VerifiedBuild: review.BuildID != nil,
+			CreatedAt:     review.CreatedAt,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":           data,
+		"rating_average": product.RatingAverage,
+		"rating_count":   product.RatingCount,
+		"meta": PaginationMeta{
+			Total:    total,
+			Page:     query.Page,
+			LastPage: lastPage,
+		},
+	})
+}
+
+// GetUserReviews returns all reviews written by the authenticated user
+// GET /api/user/reviews
+func GetUserReviews(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	var reviews []models.Review
+	if err := db.GetDB().Where("user_id = ?", userID).Order("created_at DESC").Find(&reviews).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch reviews",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  reviews,
+		"count": len(reviews),
+	})
+}
+
+// CreateReview submits a review for moderation; one review per user per product
+// POST /api/user/reviews
+func CreateReview(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	var req CreateReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	var product models.Product
+	if err := db.GetDB().Scopes(publishedProducts).First(&product, req.ProductID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Product not found",
+		})
+		return
+	}
+
+	if req.BuildID != nil && !buildContainsProduct(userID, *req.BuildID, product.ID) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Referenced build does not belong to you or does not contain this product",
+		})
+		return
+	}
+
+	var existing models.Review
+	if err := db.GetDB().Where("product_id = ? AND user_id = ?", product.ID, userID).First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":     "You have already reviewed this product",
+			"review_id": existing.ID,
+		})
+		return
+	}
+
+	review := models.Review{
+		ProductID: product.ID,
+		UserID:    userID,
+		BuildID:   req.BuildID,
+		Rating:    req.Rating,
+		Body:      req.Body,
+		Status:    models.ReviewStatusPending,
+	}
+
+	if err := db.GetDB().Create(&review).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to save review",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Review submitted for moderation",
+		"data":    review,
+	})
+}
+
+// UpdateReview edits an own review; the edited review goes back to moderation
+// PUT /api/user/reviews/:id
+func UpdateReview(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid review ID",
+		})
+		return
+	}
+
+	var review models.Review
+	if err := db.GetDB().Where("id = ? AND user_id = ?", id, userID).First(&review).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Review not found",
+		})
+		return
+	}
+
+	var req UpdateReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	updates := make(map[string]interface{})
+	if req.Rating != nil {
+		updates["rating"] = *req.Rating
+	}
+	if req.Body != nil {
+		updates["body"] = *req.Body
+	}
+	if req.BuildID != nil {
+		if *req.BuildID == 0 {
+			updates["build_id"] = nil
+		} else {
+			if !buildContainsProduct(userID, *req.BuildID, review.ProductID) {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": "Referenced build does not belong to you or does not contain this product",
+				})
+				return
+			}
+			updates["build_id"] = *req.BuildID
+		}
+	}
+
+	if len(updates) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "No fields to update",
+		})
+		return
+	}
+
+	updates["status"] = models.ReviewStatusPending
+	updates["moderation_note"] = ""
+	updates["moderated_by"] = ""
+	updates["moderated_at"] = nil
+
+	err = db.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&review).Updates(updates).Error; err != nil {
+			return err
+		}
+		return refreshProductRating(tx, review.ProductID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update review",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	db.GetDB().First(&review, id)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Review updated and submitted for moderation",
+		"data":    review,
+	})
+}
+
+// DeleteReview deletes an own review
+// DELETE /api/user/reviews/:id
+func DeleteReview(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid review ID",
+		})
+		return
+	}
+
+	var review models.Review
+	if err := db.GetDB().Where("id = ? AND user_id = ?", id, userID).First(&review).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Review not found",
+		})
+		return
+	}
+
+	if err := deleteReview(review); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to delete review",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Review deleted successfully",
+	})
+}
+
+func deleteReview(review models.Review) error {
+	return db.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&review).Error; err != nil {
+			return err
+		}
+		return refreshProductRating(tx, review.ProductID)
+	})
+}
+
+// GetAdminReviews returns reviews for moderation (Admin only)
+// GET /api/admin/reviews?status=pending&product_id=
+func GetAdminReviews(c *gin.Context) {
+	query := db.GetDB().Model(&models.Review{})
+
+	status := c.DefaultQuery("status", models.ReviewStatusPending)
+	if status != "all" {
+		query = query.Where("status = ?", status)
+	}
+	if productID := c.Query("product_id"); productID != "" {
+		query = query.Where("product_id = ?", productID)
+	}
+
+	var reviews []models.Review
+	if err := query.Order("created_at ASC").Find(&reviews).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to fetch reviews",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  reviews,
+		"count": len(reviews),
+	})
+}
+
+// ModerateReview approves or rejects a review and refreshes the product rating (Admin only)
+// PATCH /api/admin/reviews/:id
+func ModerateReview(c *gin.Context) {
+	adminID, _ := middleware.GetUserIDFromContext(c)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid review ID",
+		})
+		return
+	}
+
+	var req ModerateReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	var review models.Review
+	if err := db.GetDB().First(&review, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Review not found",
+		})
+		return
+	}
+
+	now := time.Now()
+	err = db.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&review).Updates(map[string]interface{}{
+			"status":          req.Status,
+			"moderation_note": req.Note,
+			"moderated_by":    adminID,
+			"moderated_at":    now,
+		}).Error; err != nil {
+			return err
+		}
+		return refreshProductRating(tx, review.ProductID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to moderate review",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	db.GetDB().First(&review, id)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Review moderated successfully",
+		"data":    review,
+	})
+}
+
+// DeleteAdminReview removes any review (Admin only)
+// DELETE /api/admin/reviews/:id
+func DeleteAdminReview(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid review ID",
+		})
+		return
+	}
+
+	var review models.Review
+	if err := db.GetDB().First(&review, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Review not found",
+		})
+		return
+	}
+
+	if err := deleteReview(review); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to delete review",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Review deleted successfully",
+	})
+}
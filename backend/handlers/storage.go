@@ -1,25 +1,71 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
-	"fit-pc/internal/config"
+	"fit-pc/db"
+	"fit-pc/internal/storage"
+	"fit-pc/middleware"
+	"fit-pc/models"
 
-	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
-	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/sas"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 const (
-	defaultContainerName = "models"
-	sasTokenExpiry       = 15 * time.Minute
+	sasTokenExpiry = 15 * time.Minute // Lifetime of signed upload URLs
+
+	// Signed download URLs are valid for an hour and handed out again until they
+	// have less than a quarter of an hour left
+	downloadTokenExpiry  = time.Hour
+	downloadTokenMinLeft = 15 * time.Minute
+)
+
+var (
+	downloadURLsOnce sync.Once
+	downloadURLs     *storage.DownloadURLCache
 )
 
+// downloadURLCache returns the cache of signed download URLs of the blob store
+func downloadURLCache() *storage.DownloadURLCache {
+	downloadURLsOnce.Do(func() {
+		downloadURLs = storage.NewDownloadURLCache(storage.Get(), downloadTokenExpiry, downloadTokenMinLeft)
+	})
+	return downloadURLs
+}
+
+// allowedExtensions maps uploadable file extensions to the media kinds they may be used as
+var allowedExtensions = map[string][]string{
+	".glb":  {models.MediaKindModel, models.MediaKindModelLOD},
+	".gltf": {models.MediaKindModel, models.MediaKindModelLOD},
+	".png":  {models.MediaKindImage},
+	".jpg":  {models.MediaKindImage},
+	".jpeg": {models.MediaKindImage},
+	".pdf":  {models.MediaKindDatasheet},
+}
+
+// isValidBlobName reports whether name is a plain blob name (no path segments)
+// with an uploadable extension
+func isValidBlobName(name string) bool {
+	if name == "" || strings.ContainsAny(name, "/\\") || strings.Contains(name, "..") {
+		return false
+	}
+	_, ok := allowedExtensions[strings.ToLower(filepath.Ext(name))]
+	return ok
+}
+
+type DownloadToken struct {
+	DownloadURL string `json:"download_url"`
+	ExpiresAt   string `json:"expires_at"`
+}
+
 type UploadTokenResponse struct {
 	UploadURL string `json:"upload_url"`
 	BlobURL   string `json:"blob_url"`
@@ -37,76 +83,50 @@ func GenerateUploadToken(c *gin.Context) {
 	}
 
 	ext := strings.ToLower(filepath.Ext(filename))
-	allowedExtensions := map[string]bool{
-		".glb":  true,
-		".gltf": true,
-		".png":  true,
-		".jpg":  true,
-		".jpeg": true,
-	}
-
-	if !allowedExtensions[ext] {
+	if _, ok := allowedExtensions[ext]; !ok {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "invalid file extension",
-			"allowed": []string{".glb", ".gltf", ".png", ".jpg", ".jpeg"},
+			"allowed": []string{".glb", ".gltf", ".png", ".jpg", ".jpeg", ".pdf"},
 		})
 		return
 	}
 
 	blobName := fmt.Sprintf("%s%s", uuid.New().String(), ext)
 
-	cfg := config.GetConfig()
-
-	credential, err := azblob.NewSharedKeyCredential(cfg.StorageAccountName, cfg.StorageAccountKey)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "failed to create storage credential",
-		})
-		return
-	}
-
-	expiryTime := time.Now().UTC().Add(sasTokenExpiry)
-
-	permissions := sas.BlobPermissions{
-		Write:  true,
-		Create: true,
-	}
-
-	sasValues := sas.BlobSignatureValues{
-		Protocol:      sas.ProtocolHTTPS,
-		StartTime:     time.Now().UTC().Add(-5 * time.Minute),
-		ExpiryTime:    expiryTime,
-		Permissions:   permissions.String(),
-		ContainerName: defaultContainerName,
-		BlobName:      blobName,
-	}
-
-	queryParams, err := sasValues.SignWithSharedKey(credential)
+	store := storage.Get()
+	uploadURL, expiryTime, err := store.PresignUpload(c.Request.Context(), blobName, sasTokenExpiry)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "failed to sign SAS token",
+			"error": err.Error(),
 		})
 		return
 	}
 
-	blobURL := fmt.Sprintf(
-		"https://%s.blob.core.windows.net/%s/%s",
-		cfg.StorageAccountName,
-		defaultContainerName,
-		blobName,
-	)
-
-	uploadURL := fmt.Sprintf("%s?%s", blobURL, queryParams.Encode())
-
 	c.JSON(http.StatusOK, UploadTokenResponse{
 		UploadURL: uploadURL,
-		BlobURL:   blobURL,
+		BlobURL:   store.URL(blobName),
 		BlobName:  blobName,
 		ExpiresAt: expiryTime.Format(time.RFC3339),
 	})
 }
 
+// GenerateDownloadToken returns a signed download URL for a blob referenced by a
+// published product, its family or media gallery (resized thumbnails included),
+// or one of the caller's builds. Other blobs are reported as not found, whether
+// they exist or not. Viewers of a shared build pass its slug as share to load its
+// models.
+// GET /api/download-token?blob=...&share=...
 func GenerateDownloadToken(c *gin.Context) {
+	generateDownloadToken(c, true)
+}
+
+// GenerateAdminDownloadToken returns a signed download URL for any blob (Admin only)
+// GET /api/admin/download-token?blob=...
+func GenerateAdminDownloadToken(c *gin.Context) {
+	generateDownloadToken(c, false)
+}
+
+func generateDownloadToken(c *gin.Context, restricted bool) {
 	blobName := c.Query("blob")
 	if blobName == "" {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -115,50 +135,208 @@ func GenerateDownloadToken(c *gin.Context) {
 		return
 	}
 
-	cfg := config.GetConfig()
+	if restricted {
+		allowed, err := downloadableBlobs(c, []string{blobName})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to resolve blob",
+				"details": err.Error(),
+			})
+			return
+		}
+		if !allowed[blobName] {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Blob not found",
+			})
+			return
+		}
+	}
 
-	credential, err := azblob.NewSharedKeyCredential(cfg.StorageAccountName, cfg.StorageAccountKey)
+	downloadURL, expiryTime, err := downloadURLCache().URL(c.Request.Context(), blobName)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "failed to create storage credential",
+			"error": err.Error(),
 		})
 		return
 	}
 
-	expiryTime := time.Now().UTC().Add(1 * time.Hour)
+	c.JSON(http.StatusOK, DownloadToken{
+		DownloadURL: downloadURL,
+		ExpiresAt:   expiryTime.Format(time.RFC3339),
+	})
+}
 
-	permissions := sas.BlobPermissions{
-		Read: true,
-	}
+type DownloadTokensRequest struct {
+	Blobs []string `json:"blobs" binding:"required,min=1,max=100"`
+}
 
-	sasValues := sas.BlobSignatureValues{
-		Protocol:      sas.ProtocolHTTPS,
-		StartTime:     time.Now().UTC().Add(-5 * time.Minute),
-		ExpiryTime:    expiryTime,
-		Permissions:   permissions.String(),
-		ContainerName: defaultContainerName,
-		BlobName:      blobName,
+// CreateDownloadTokens returns signed download URLs for many blobs at once, e.g. every
+// model of a build scene. The same blobs as for GET /api/download-token are allowed;
+// the others are listed in "denied".
+// POST /api/download-tokens?share=...
+func CreateDownloadTokens(c *gin.Context) {
+	var req DownloadTokensRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
 	}
 
-	queryParams, err := sasValues.SignWithSharedKey(credential)
+	allowed, err := downloadableBlobs(c, req.Blobs)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "failed to sign SAS token",
+			"error":   "Failed to resolve blobs",
+			"details": err.Error(),
 		})
 		return
 	}
 
-	blobURL := fmt.Sprintf(
-		"https://%s.blob.core.windows.net/%s/%s",
-		cfg.StorageAccountName,
-		defaultContainerName,
-		blobName,
-	)
-
-	downloadURL := fmt.Sprintf("%s?%s", blobURL, queryParams.Encode())
+	tokens := make(map[string]DownloadToken, len(req.Blobs))
+	denied := []string{}
+	for _, blobName := range req.Blobs {
+		if _, done := tokens[blobName]; done {
+			continue
+		}
+		if !allowed[blobName] {
+			denied = append(denied, blobName)
+			continue
+		}
+		downloadURL, expiryTime, err := downloadURLCache().URL(c.Request.Context(), blobName)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		tokens[blobName] = DownloadToken{
+			DownloadURL: downloadURL,
+			ExpiresAt:   expiryTime.Format(time.RFC3339),
+		}
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"download_url": downloadURL,
-		"expires_at":   expiryTime.Format(time.RFC3339),
+		"data":   tokens,
+		"denied": denied,
 	})
 }
+
+// downloadableBlobs reports which of the blobs the caller may download: admins may
+// download any blob, everyone else only blobs referenced by the published catalog, or
+// models of catalog products in their own builds or in the build shared through the
+// share query parameter
+func downloadableBlobs(c *gin.Context, blobNames []string) (map[string]bool, error) {
+	allowed := make(map[string]bool, len(blobNames))
+	store := storage.Get()
+
+	role, _ := middleware.GetUserRoleFromContext(c)
+
+	urls := make([]string, 0, len(blobNames))
+	byURL := make(map[string]string, len(blobNames))
+	for _, name := range blobNames {
+		if !isValidBlobName(name) {
+			continue
+		}
+		if role == middleware.RoleOrgAdmin {
+			allowed[name] = true
+			continue
+		}
+		url := store.URL(name)
+		urls = append(urls, url)
+		byURL[url] = name
+	}
+	if len(urls) == 0 {
+		return allowed, nil
+	}
+	allow := func(refs ...string) {
+		for _, ref := range refs {
+			if name, ok := byURL[ref]; ok {
+				allowed[name] = true
+			}
+		}
+	}
+
+	tx := db.GetDB()
+	published := tx.Model(&models.Product{}).Select("id").Where("status = ?", models.ProductStatusPublished)
+	publishedFamilies := tx.Model(&models.Product{}).Select("family_id").Where("status = ? AND family_id IS NOT NULL", models.ProductStatusPublished)
+
+	allowSizes := func(sizes models.ImageSizes) {
+		for _, ref := range sizes {
+			allow(ref)
+		}
+	}
+	// Matches rows with a resized thumbnail among the URLs
+	const inSizes = "EXISTS (SELECT 1 FROM jsonb_each_text(thumbnail_sizes) AS size WHERE size.value IN ?)"
+
+	var products []models.Product
+	if err := tx.Select("model_url", "thumbnail_url", "thumbnail_sizes", "generated_thumbnail_url").
+		Where("status = ? AND (model_url IN ? OR thumbnail_url IN ? OR generated_thumbnail_url IN ? OR "+inSizes+")", models.ProductStatusPublished, urls, urls, urls, urls).
+		Find(&products).Error; err != nil {
+		return nil, err
+	}
+	for _, p := range products {
+		allow(p.ModelURL, p.ThumbnailURL, p.GeneratedThumbnailURL)
+		allowSizes(p.ThumbnailSizes)
+	}
+
+	var families []models.ProductFamily
+	if err := tx.Select("model_url", "thumbnail_url", "thumbnail_sizes").
+		Where("(model_url IN ? OR thumbnail_url IN ? OR "+inSizes+") AND id IN (?)", urls, urls, urls, publishedFamilies).
+		Find(&families).Error; err != nil {
+		return nil, err
+	}
+	for _, f := range families {
+		allow(f.ModelURL, f.ThumbnailURL)
+		allowSizes(f.ThumbnailSizes)
+	}
+
+	var media []models.MediaAsset
+	if err := tx.Select("url").Where("url IN ? AND product_id IN (?)", urls, published).Find(&media).Error; err != nil {
+		return nil, err
+	}
+	for _, m := range media {
+		allow(m.URL)
+	}
+
+	// Builds keep the models of their products, even once unpublished. Components
+	// are saved as sent, so only models their products resolve to are allowed.
+	allowComponents := func(components models.BuildComponents) error {
+		catalog, err := catalogModelURLs(tx, components)
+		if err != nil {
+			return err
+		}
+		for _, component := range components {
+			if modelURL := catalog[component.ID]; modelURL != "" && modelURL == component.ModelURL {
+				allow(modelURL)
+			}
+		}
+		return nil
+	}
+	if userID, ok := middleware.GetUserIDFromContext(c); ok {
+		var builds []models.Build
+		if err := tx.Select("components").Where("user_id = ?", userID).Find(&builds).Error; err != nil {
+			return nil, err
+		}
+		var components models.BuildComponents
+		for _, b := range builds {
+			components = append(components, b.Components...)
+		}
+		if err := allowComponents(components); err != nil {
+			return nil, err
+		}
+	}
+
+	// So do shared builds, for anyone holding the link
+	if slug := c.Query("share"); slug != "" {
+		_, build, err := activeSharedBuild(tx, slug)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		if err := allowComponents(build.Components); err != nil {
+			return nil, err
+		}
+	}
+
+	return allowed, nil
+}
@@ -0,0 +1,320 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"fit-pc/db"
+	"fit-pc/internal/gltf"
+	"fit-pc/internal/storage"
+	"fit-pc/middleware"
+	"fit-pc/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxUploadSizes bounds the size of a confirmed upload by asset kind
+var maxUploadSizes = map[string]int64{
+	models.MediaKindModel:     maxModelImportSize,
+	models.MediaKindImage:     10 << 20,
+	models.MediaKindDatasheet: 25 << 20,
+}
+
+// extensionContentTypes maps uploadable extensions to the content type their files must sniff as
+var extensionContentTypes = map[string]string{
+	".glb":  gltf.MediaTypeBinary,
+	".gltf": gltf.MediaTypeJSON,
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".pdf":  "application/pdf",
+}
+
+// sniffContentType detects the content type of a file from its first bytes,
+// recognising glTF models on top of the types known to net/http
+func sniffContentType(head []byte) string {
+	if bytes.HasPrefix(head, []byte("glTF")) {
+		return gltf.MediaTypeBinary
+	}
+	if trimmed := bytes.TrimLeft(head, " \t\r\n\ufeff"); bytes.HasPrefix(trimmed, []byte("{")) && bytes.Contains(head, []byte(`"asset"`)) {
+		return gltf.MediaTypeJSON
+	}
+	contentType, _, _ := strings.Cut(http.DetectContentType(head), ";")
+	return contentType
+}
+
+// readAsset sniffs the content type of a blob from its first bytes and hashes it,
+// reading at most limit bytes
+func readAsset(body io.Reader, limit int64) (contentType, sum string, size int64, err error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(body, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", "", 0, err
+	}
+	head = head[:n]
+
+	hash := sha256.New()
+	hash.Write(head)
+	rest, err := io.Copy(hash, io.LimitReader(body, limit-int64(n)))
+	if err != nil {
+		return "", "", 0, err
+	}
+	return sniffContentType(head), hex.EncodeToString(hash.Sum(nil)), int64(n) + rest, nil
+}
+
+// assetBlobName returns the content-addressed name of a blob: the SHA-256 of its
+// content followed by its extension
+func assetBlobName(sum, sourceName string) string {
+	return sum + strings.ToLower(filepath.Ext(sourceName))
+}
+
+// findAsset returns the content-addressed asset with a hash, if any. Assets
+// confirmed before uploads were content-addressed keep their upload name and
+// are not returned.
+func findAsset(tx *gorm.DB, sum string) (*models.Asset, error) {
+	var asset models.Asset
+	err := tx.Where("sha256 = ? AND blob_name LIKE ?", sum, sum+".%").Order("id").First(&asset).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &asset, nil
+}
+
+// storeAsset stores the content of the blob source, described by asset, under its
+// content-addressed name and records source as an upload of it. If an asset with
+// the same content exists it is returned instead, with created false. The content
+// is copied in both cases, so a blob about to be collected as an orphan is
+// refreshed. The source blob is left in place.
+func storeAsset(ctx context.Context, tx *gorm.DB, store storage.BlobStore, source string, asset models.Asset) (models.Asset, bool, error) {
+	existing, err := findAsset(tx, asset.SHA256)
+	if err != nil {
+		return models.Asset{}, false, err
+	}
+	if existing != nil {
+		asset = *existing
+	} else {
+		asset.BlobName = assetBlobName(asset.SHA256, source)
+		asset.URL = store.URL(asset.BlobName)
+	}
+
+	if asset.BlobName != source {
+		if err := store.Copy(ctx, source, asset.BlobName); err != nil {
+			return models.Asset{}, false, fmt.Errorf("failed to store %s: %w", asset.BlobName, err)
+		}
+	}
+
+	created := false
+	if existing == nil {
+		// Identical files confirmed concurrently share the first asset registered
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&asset)
+		if result.Error != nil {
+			return models.Asset{}, false, result.Error
+		}
+		created = result.RowsAffected == 1
+		if !created {
+			if err := tx.Where("blob_name = ?", asset.BlobName).First(&asset).Error; err != nil {
+				return models.Asset{}, false, err
+			}
+		}
+	}
+
+	upload := models.AssetUpload{BlobName: source, AssetID: asset.ID, UploadedBy: asset.UploadedBy}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&upload).Error; err != nil {
+		return models.Asset{}, false, err
+	}
+	return asset, created, nil
+}
+
+// CompleteUpload confirms an upload made through GenerateUploadToken (Admin only).
+// The blob must exist, fit the size limit of its kind and have content matching its
+// extension; blobs failing the checks are deleted. The content is then stored under
+// its SHA-256 hash and registered as an asset, so it can be referenced as a product
+// or family model or thumbnail through the asset's URL; images also get resized
+// copies, listed in the asset's sizes. Uploading a file identical to an existing
+// asset returns that asset (200, "deduplicated"). Confirming an upload twice
+// returns the asset it resolved to.
+// POST /api/admin/uploads/:blob/complete
+func CompleteUpload(c *gin.Context) {
+	blobName := c.Param("blob")
+	if !isValidBlobName(blobName) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid blob name",
+		})
+		return
+	}
+
+	// Uploads confirmed before assets were content-addressed are assets themselves
+	var existing models.Asset
+	err := db.GetDB().
+		Where("id = (?)", db.GetDB().Model(&models.AssetUpload{}).Select("asset_id").Where("blob_name = ?", blobName)).
+		Or("blob_name = ?", blobName).
+		First(&existing).Error
+	if err == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Upload already confirmed",
+			"data":    existing,
+		})
+		return
+	}
+
+	ext := strings.ToLower(filepath.Ext(blobName))
+	kind := allowedExtensions[ext][0]
+	store := storage.Get()
+	ctx := c.Request.Context()
+
+	body, info, err := store.Open(ctx, blobName)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Blob not found: the upload did not complete",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":   "Failed to read the uploaded blob",
+			"details": err.Error(),
+		})
+		return
+	}
+	defer body.Close()
+
+	// reject deletes the blob, which is of no use once it failed verification
+	reject := func(details string) {
+		if err := store.Delete(ctx, blobName); err != nil {
+			details += fmt.Sprintf(" (the blob could not be deleted: %v)", err)
+		}
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":   "Upload rejected",
+			"details": details,
+		})
+	}
+
+	maxSize := maxUploadSizes[kind]
+	if info.Size == 0 {
+		reject("the file is empty")
+		return
+	}
+	if info.Size > maxSize {
+		reject(fmt.Sprintf("%s files are limited to %d MB", kind, maxSize>>20))
+		return
+	}
+
+	contentType, sum, size, err := readAsset(body, maxSize)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":   "Failed to read the uploaded blob",
+			"details": err.Error(),
+		})
+		return
+	}
+	if contentType != extensionContentTypes[ext] {
+		reject(fmt.Sprintf("a %s file must contain %s, found %s", ext, extensionContentTypes[ext], contentType))
+		return
+	}
+
+	uploader, _ := middleware.GetUserIDFromContext(c)
+	asset, created, err := storeAsset(ctx, db.GetDB(), store, blobName, models.Asset{
+		Kind:        kind,
+		ContentType: contentType,
+		SizeBytes:   size,
+		SHA256:      sum,
+		UploadedBy:  uploader,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to register the upload",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// The upload's content now lives under the asset's name
+	if asset.BlobName != blobName {
+		if err := store.Delete(ctx, blobName); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("Failed to delete confirmed upload %s: %v", blobName, err)
+		}
+	}
+
+	response := gin.H{
+		"message": "Upload confirmed",
+		"data":    &asset,
+	}
+	status := http.StatusCreated
+	if !created {
+		status = http.StatusOK
+		response["message"] = "Identical file already uploaded"
+		response["deduplicated"] = true
+	}
+
+	// Images are resized once, for the lists, cards and detail pages showing them.
+	// The original stays usable if resizing fails.
+	if kind == models.MediaKindImage && len(asset.Sizes) == 0 {
+		if err := resizeImageAsset(ctx, db.GetDB(), store, &asset); err != nil {
+			log.Printf("Failed to resize image %s: %v", asset.BlobName, err)
+			response["warning"] = "The image could not be resized: " + err.Error()
+		}
+	}
+
+	c.JSON(status, response)
+}
+
+// checkAssetURL returns an error unless url is empty or the URL of a confirmed
+// upload of the given kind
+func checkAssetURL(tx *gorm.DB, url, kind string) error {
+	if url == "" {
+		return nil
+	}
+	var asset models.Asset
+	if err := tx.Where("url = ?", url).First(&asset).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%s is not a confirmed upload", url)
+		}
+		return err
+	}
+	if asset.Kind != kind {
+		return fmt.Errorf("%s is a %s, not a %s", url, asset.Kind, kind)
+	}
+	return nil
+}
+
+// checkAssetURLs validates the model and thumbnail URLs a request sets, writing a
+// 400 response if one does not reference a confirmed upload. URLs left unchanged
+// from current are not checked, so items created before uploads were confirmed
+// can still be edited.
+func checkAssetURLs(c *gin.Context, modelURL, thumbnailURL *string, currentModel, currentThumbnail string) bool {
+	checks := []struct {
+		url     *string
+		current string
+		kind    string
+	}{
+		{modelURL, currentModel, models.MediaKindModel},
+		{thumbnailURL, currentThumbnail, models.MediaKindImage},
+	}
+	for _, check := range checks {
+		if check.url == nil || *check.url == check.current {
+			continue
+		}
+		if err := checkAssetURL(db.GetDB(), *check.url, check.kind); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid asset URL",
+				"details": err.Error(),
+			})
+			return false
+		}
+	}
+	return true
+}
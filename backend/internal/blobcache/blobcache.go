@@ -0,0 +1,186 @@
+// Package blobcache keeps recently served blobs on local disk in front of the blob
+// store. Blob names are random per upload or derived from the blob's content, and
+// a blob is never rewritten with different content, so cached copies never go
+// stale; the least recently used ones are evicted once the cache outgrows its size
+// limit. Concurrent misses for the same blob are coalesced into a single download
+// from storage.
+package blobcache
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"fit-pc/internal/storage"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// entry is a cached blob
+type entry struct {
+	info storage.BlobInfo
+	path string
+}
+
+// Cache is an on-disk LRU cache of blobs
+type Cache struct {
+	store    storage.BlobStore
+	dir      string
+	maxBytes int64
+
+	mu      sync.Mutex
+	lru     *list.List               // Front is the most recently used *entry
+	entries map[string]*list.Element // By blob name
+	size    int64
+
+	fetches singleflight.Group
+}
+
+// subdir is the directory of the cache inside the configured one. Only it is
+// emptied, so pointing the cache at a shared directory such as /tmp is safe.
+const subdir = "fit-pc-blob-cache"
+
+// New returns a cache of at most maxBytes in a subdirectory of dir. The
+// subdirectory is emptied, as its content cannot be trusted after a restart.
+func New(store storage.BlobStore, dir string, maxBytes int64) (*Cache, error) {
+	dir = filepath.Join(dir, subdir)
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, fmt.Errorf("failed to clear the blob cache: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create the blob cache: %w", err)
+	}
+	return &Cache{store: store, dir: dir, maxBytes: maxBytes, lru: list.New(), entries: map[string]*list.Element{}}, nil
+}
+
+var cache *Cache
+
+// Init sets the cache used by the handlers
+func Init(c *Cache) {
+	cache = c
+}
+
+// Get returns the cache set by Init
+func Get() *Cache {
+	if cache == nil {
+		panic("blob cache not initialized: call blobcache.Init() first")
+	}
+	return cache
+}
+
+// Open returns the cached copy of a blob, downloading it from storage on a miss.
+// The caller closes the file. Returns storage.ErrNotFound for missing blobs.
+func (c *Cache) Open(ctx context.Context, name string) (*os.File, storage.BlobInfo, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		if e, ok := c.lookup(name); ok {
+			f, err := os.Open(e.path)
+			if err == nil {
+				return f, e.info, nil
+			}
+			// Evicted between the lookup and the open
+			if !os.IsNotExist(err) {
+				return nil, storage.BlobInfo{}, err
+			}
+		}
+
+		// The download is shared by every request for the blob, so one client
+		// cancelling must not abort it for the others
+		_, err, _ := c.fetches.Do(name, func() (interface{}, error) {
+			return nil, c.fetch(context.WithoutCancel(ctx), name)
+		})
+		if err != nil {
+			return nil, storage.BlobInfo{}, err
+		}
+	}
+	return nil, storage.BlobInfo{}, fmt.Errorf("blob %s was evicted while being served", name)
+}
+
+// lookup returns a cached blob and marks it as recently used
+func (c *Cache) lookup(name string) (entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[name]
+	if !ok {
+		return entry{}, false
+	}
+	c.lru.MoveToFront(element)
+	return *element.Value.(*entry), true
+}
+
+// fetch downloads a blob into the cache
+func (c *Cache) fetch(ctx context.Context, name string) error {
+	if _, ok := c.lookup(name); ok {
+		return nil
+	}
+
+	body, info, err := c.store.Open(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	tmp, err := os.CreateTemp(c.dir, ".fetch-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	size, err := io.Copy(tmp, body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to download blob %s: %w", name, err)
+	}
+	info.Name, info.Size = name, size
+
+	path := filepath.Join(c.dir, name)
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[name] = c.lru.PushFront(&entry{info: info, path: path})
+	c.size += size
+	c.evict(name)
+	return nil
+}
+
+// evict removes the least recently used blobs until the cache fits its limit.
+// The blob just added is kept even if it alone is over the limit, so it can be
+// served; open files stay readable after their removal.
+func (c *Cache) evict(keep string) {
+	for c.size > c.maxBytes {
+		element := c.lru.Back()
+		e := element.Value.(*entry)
+		if e.info.Name == keep {
+			if c.lru.Len() == 1 {
+				return
+			}
+			c.lru.MoveToFront(element)
+			continue
+		}
+		c.lru.Remove(element)
+		delete(c.entries, e.info.Name)
+		c.size -= e.info.Size
+		os.Remove(e.path)
+	}
+}
+
+// Remove drops a blob from the cache, e.g. after it was deleted from storage
+func (c *Cache) Remove(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[name]; ok {
+		e := element.Value.(*entry)
+		c.lru.Remove(element)
+		delete(c.entries, name)
+		c.size -= e.info.Size
+		os.Remove(e.path)
+	}
+}
@@ -0,0 +1,123 @@
+package blobcache_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"fit-pc/internal/blobcache"
+	"fit-pc/internal/storage"
+)
+
+// slowStore counts the blobs opened from a local store, each taking a moment
+type slowStore struct {
+	storage.BlobStore
+	opened atomic.Int32
+}
+
+func (s *slowStore) Open(ctx context.Context, name string) (io.ReadCloser, storage.BlobInfo, error) {
+	s.opened.Add(1)
+	time.Sleep(20 * time.Millisecond)
+	return s.BlobStore.Open(ctx, name)
+}
+
+func newCache(t *testing.T, maxBytes int64, blobs map[string]string) (*blobcache.Cache, *slowStore) {
+	t.Helper()
+	dir := t.TempDir()
+	local, err := storage.NewLocalStore(dir, "http://localhost/storage", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range blobs {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store := &slowStore{BlobStore: local}
+	cache, err := blobcache.New(store, filepath.Join(t.TempDir(), "cache"), maxBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cache, store
+}
+
+func read(t *testing.T, cache *blobcache.Cache, name string) string {
+	t.Helper()
+	f, info, err := cache.Open(context.Background(), name)
+	if err != nil {
+		t.Fatalf("Open(%s): %v", name, err)
+	}
+	defer f.Close()
+	data, _ := io.ReadAll(f)
+	if info.Size != int64(len(data)) || info.ContentType != "model/gltf-binary" {
+		t.Errorf("unexpected info for %s: %+v", name, info)
+	}
+	return string(data)
+}
+
+func TestCache_CoalescesMisses(t *testing.T) {
+	cache, store := newCache(t, 1<<20, map[string]string{"gpu.glb": "glTF gpu"})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got := read(t, cache, "gpu.glb"); got != "glTF gpu" {
+				t.Errorf("read %q", got)
+			}
+		}()
+	}
+	wg.Wait()
+	read(t, cache, "gpu.glb")
+
+	if n := store.opened.Load(); n != 1 {
+		t.Errorf("expected the blob to be fetched once, got %d fetches", n)
+	}
+}
+
+func TestCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache, store := newCache(t, 10, map[string]string{"a.glb": "aaaaaa", "b.glb": "bbbbbb"})
+
+	read(t, cache, "a.glb")
+	read(t, cache, "b.glb") // Evicts a.glb, 12 bytes exceed the limit
+	read(t, cache, "b.glb")
+	if n := store.opened.Load(); n != 2 {
+		t.Fatalf("expected 2 fetches, got %d", n)
+	}
+	read(t, cache, "a.glb")
+	if n := store.opened.Load(); n != 3 {
+		t.Errorf("expected a.glb to be fetched again, got %d fetches", n)
+	}
+}
+
+func TestCache_NotFound(t *testing.T) {
+	cache, _ := newCache(t, 1<<20, nil)
+	if _, _, err := cache.Open(context.Background(), "missing.glb"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Open = %v, want ErrNotFound", err)
+	}
+}
+
+func TestNew_KeepsSharedDirectory(t *testing.T) {
+	dir := t.TempDir()
+	other := filepath.Join(dir, "other.txt")
+	if err := os.WriteFile(other, []byte("not ours"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	local, err := storage.NewLocalStore(t.TempDir(), "http://localhost/storage", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := blobcache.New(local, dir, 1<<20); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("expected files outside the cache to be kept: %v", err)
+	}
+}
@@ -0,0 +1,242 @@
+// Package blobgc removes uploaded blobs that nothing references any more: models
+// and images replaced on a product, left behind by deleted products, or uploaded
+// and never saved, along with the resized copies of unused images and previews
+// rendered for outdated build versions. A blob is kept while a product, family,
+// media asset, build snapshot or build revision references it, and for a grace
+// period after it was last modified so uploads in progress are never collected.
+package blobgc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"fit-pc/internal/blobcache"
+	"fit-pc/internal/storage"
+	"fit-pc/models"
+
+	"gorm.io/gorm"
+)
+
+// MinGrace is the shortest grace period allowed. It outlasts upload tokens, so a
+// blob is never collected between its upload and the save referencing it.
+const MinGrace = time.Hour
+
+// ErrRunning is returned when a collection is already in progress
+var ErrRunning = errors.New("blob garbage collection is already running")
+
+// running serialises collections started by the job and by admins
+var running sync.Mutex
+
+// Report describes a collection, or what one would remove when dry-running
+type Report struct {
+	DryRun      bool               `json:"dry_run"`
+	Cutoff      time.Time          `json:"cutoff"`     // Unreferenced blobs modified before this are orphans
+	Scanned     int                `json:"scanned"`    // Blobs in the store
+	Referenced  int                `json:"referenced"` // Blobs in use
+	Recent      int                `json:"recent"`     // Unreferenced blobs still within the grace period
+	Orphans     []storage.BlobInfo `json:"orphans"`
+	OrphanBytes int64              `json:"orphan_bytes"`
+	Deleted     []string           `json:"deleted"`
+	Failed      []string           `json:"failed"`
+}
+
+// BlobName returns the blob name a stored URL refers to, or "" if it has none
+func BlobName(rawURL string) string {
+	if rawURL == "" {
+		return ""
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	name := path.Base(parsed.Path)
+	if name == "." || name == "/" {
+		return ""
+	}
+	return name
+}
+
+// References returns the names of the blobs referenced by the catalog and by
+// saved builds. Soft-deleted products and builds do not keep their blobs.
+func References(tx *gorm.DB) (map[string]bool, error) {
+	refs := map[string]bool{}
+	add := func(urls ...string) {
+		for _, u := range urls {
+			if name := BlobName(u); name != "" {
+				refs[name] = true
+			}
+		}
+	}
+
+	addSizes := func(sizes models.ImageSizes) {
+		for _, u := range sizes {
+			add(u)
+		}
+	}
+
+	var products []models.Product
+	if err := tx.Select("model_url", "thumbnail_url", "thumbnail_sizes", "generated_thumbnail_url").Find(&products).Error; err != nil {
+		return nil, fmt.Errorf("failed to load product references: %w", err)
+	}
+	for _, p := range products {
+		add(p.ModelURL, p.ThumbnailURL, p.GeneratedThumbnailURL)
+		addSizes(p.ThumbnailSizes)
+	}
+
+	var families []models.ProductFamily
+	if err := tx.Select("model_url", "thumbnail_url", "thumbnail_sizes").Find(&families).Error; err != nil {
+		return nil, fmt.Errorf("failed to load family references: %w", err)
+	}
+	for _, f := range families {
+		add(f.ModelURL, f.ThumbnailURL)
+		addSizes(f.ThumbnailSizes)
+	}
+
+	var media []models.MediaAsset
+	if err := tx.Select("blob_name", "url").Find(&media).Error; err != nil {
+		return nil, fmt.Errorf("failed to load media references: %w", err)
+	}
+	for _, m := range media {
+		refs[m.BlobName] = true
+		add(m.URL)
+	}
+
+	// Build snapshots keep the model of every component they were saved with
+	var builds []models.Build
+	err := tx.Select("id", "components").FindInBatches(&builds, 500, func(batch *gorm.DB, _ int) error {
+		for _, b := range builds {
+			for _, component := range b.Components {
+				add(component.ModelURL)
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load build references: %w", err)
+	}
+
+	// So do the revisions of existing builds, which can be rolled back to
+	var revisions []models.BuildRevision
+	err = tx.Select("build_revisions.id", "build_revisions.components").
+		Joins("JOIN builds ON builds.id = build_revisions.build_id AND builds.deleted_at IS NULL").
+		FindInBatches(&revisions, 500, func(batch *gorm.DB, _ int) error {
+			for _, r := range revisions {
+				for _, component := range r.Components {
+					add(component.ModelURL)
+				}
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load build revision references: %w", err)
+	}
+
+	return refs, nil
+}
+
+// Plan lists the store and reports the unreferenced blobs last modified before cutoff
+func Plan(ctx context.Context, store storage.BlobStore, refs map[string]bool, cutoff time.Time) (*Report, error) {
+	blobs, err := store.List(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list blobs: %w", err)
+	}
+
+	report := &Report{DryRun: true, Cutoff: cutoff, Scanned: len(blobs), Orphans: []storage.BlobInfo{}, Deleted: []string{}, Failed: []string{}}
+	for _, blob := range blobs {
+		switch {
+		case refs[blob.Name]:
+			report.Referenced++
+		case !blob.LastModified.Before(cutoff):
+			report.Recent++
+		default:
+			report.Orphans = append(report.Orphans, blob)
+			report.OrphanBytes += blob.Size
+		}
+	}
+	sort.Slice(report.Orphans, func(i, j int) bool {
+		return report.Orphans[i].LastModified.Before(report.Orphans[j].LastModified)
+	})
+	return report, nil
+}
+
+// Execute deletes the orphans of a plan and drops them from cache, if set. Blobs
+// already gone count as deleted.
+func (r *Report) Execute(ctx context.Context, store storage.BlobStore, cache *blobcache.Cache) {
+	r.DryRun = false
+	for _, blob := range r.Orphans {
+		if err := store.Delete(ctx, blob.Name); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("blobgc: failed to delete %s: %v", blob.Name, err)
+			r.Failed = append(r.Failed, blob.Name)
+			continue
+		}
+		if cache != nil {
+			cache.Remove(blob.Name)
+		}
+		r.Deleted = append(r.Deleted, blob.Name)
+	}
+}
+
+// Run plans a collection with the given grace period and, unless dryRun is set,
+// deletes the orphans from the store and the cache. It returns ErrRunning if
+// another collection is in progress.
+func Run(ctx context.Context, store storage.BlobStore, cache *blobcache.Cache, tx *gorm.DB, grace time.Duration, dryRun bool) (*Report, error) {
+	if grace < MinGrace {
+		return nil, fmt.Errorf("grace period must be at least %s", MinGrace)
+	}
+	if !running.TryLock() {
+		return nil, ErrRunning
+	}
+	defer running.Unlock()
+
+	refs, err := References(tx.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	report, err := Plan(ctx, store, refs, time.Now().Add(-grace))
+	if err != nil {
+		return nil, err
+	}
+	if !dryRun {
+		report.Execute(ctx, store, cache)
+		if len(report.Deleted) > 0 {
+			// Confirmed uploads that were never referenced go with their blob
+			db := tx.WithContext(ctx)
+			deleted := db.Model(&models.Asset{}).Select("id").Where("blob_name IN ?", report.Deleted)
+			if err := db.Where("asset_id IN (?)", deleted).Delete(&models.AssetUpload{}).Error; err != nil {
+				log.Printf("blobgc: failed to remove uploads of deleted assets: %v", err)
+			}
+			if err := db.Where("blob_name IN ?", report.Deleted).Delete(&models.Asset{}).Error; err != nil {
+				log.Printf("blobgc: failed to remove deleted assets: %v", err)
+			}
+		}
+	}
+	return report, nil
+}
+
+// Schedule collects orphaned blobs every interval until ctx is cancelled
+func Schedule(ctx context.Context, store storage.BlobStore, cache *blobcache.Cache, tx *gorm.DB, interval, grace time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := Run(ctx, store, cache, tx, grace, false)
+			if err != nil {
+				log.Printf("blobgc: %v", err)
+				continue
+			}
+			log.Printf("blobgc: scanned %d blobs, %d orphans (%d bytes), deleted %d, %d failed",
+				report.Scanned, len(report.Orphans), report.OrphanBytes, len(report.Deleted), len(report.Failed))
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package blobgc_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"fit-pc/internal/blobcache"
+	"fit-pc/internal/blobgc"
+	"fit-pc/internal/storage"
+)
+
+func TestBlobName(t *testing.T) {
+	tests := map[string]string{
+		"https://acct.blob.core.windows.net/models/a.glb":      "a.glb",
+		"http://127.0.0.1:10000/devstoreaccount1/models/b.png": "b.png",
+		"http://localhost:8080/storage/c.glb?sp=r":             "c.glb",
+		"":                     "",
+		"https://example.com/": "",
+	}
+	for in, want := range tests {
+		if got := blobgc.BlobName(in); got != want {
+			t.Errorf("BlobName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestPlanAndExecute(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := storage.NewLocalStore(dir, "http://localhost/storage", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	old := now.Add(-48 * time.Hour)
+	for name, modified := range map[string]time.Time{
+		"used.glb":   old,
+		"orphan.glb": old,
+		"fresh.glb":  now,
+	} {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(p, modified, modified)
+	}
+
+	refs := map[string]bool{"used.glb": true}
+	report, err := blobgc.Plan(ctx, store, refs, now.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if report.Scanned != 3 || report.Referenced != 1 || report.Recent != 1 {
+		t.Errorf("unexpected counts: %+v", report)
+	}
+	if len(report.Orphans) != 1 || report.Orphans[0].Name != "orphan.glb" || report.OrphanBytes != int64(len("orphan.glb")) {
+		t.Fatalf("expected orphan.glb to be the only orphan, got %+v", report.Orphans)
+	}
+	if !report.DryRun || len(report.Deleted) != 0 {
+		t.Error("expected a plan to delete nothing")
+	}
+	if _, err := store.Stat(ctx, "orphan.glb"); err != nil {
+		t.Errorf("plan removed the orphan: %v", err)
+	}
+
+	cache, err := blobcache.New(store, t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, _, err := cache.Open(ctx, "orphan.glb")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	f.Close()
+
+	report.Execute(ctx, store, cache)
+	if report.DryRun || len(report.Deleted) != 1 || len(report.Failed) != 0 {
+		t.Errorf("unexpected execution: %+v", report)
+	}
+	if _, err := store.Stat(ctx, "orphan.glb"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("expected orphan.glb to be deleted, got %v", err)
+	}
+	if _, _, err := cache.Open(ctx, "orphan.glb"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("expected orphan.glb to be dropped from the cache, got %v", err)
+	}
+	for _, name := range []string{"used.glb", "fresh.glb"} {
+		if _, err := store.Stat(ctx, name); err != nil {
+			t.Errorf("expected %s to be kept: %v", name, err)
+		}
+	}
+}
@@ -4,9 +4,12 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strconv"
 	"sync"
 	"time"
 
+	"fit-pc/models"
+
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/keyvault/azsecrets"
 )
@@ -17,6 +20,20 @@ type Config struct {
 	StorageAccountKey  string
 	ClerkSecretKey     string
 	Port               string
+	ModelBudget        models.ModelBudget // Limits above which uploaded models are flagged
+
+	StorageBackend     string // "azure" (default) or "local"
+	StorageEndpoint    string // Blob service URL override, e.g. Azurite's http://127.0.0.1:10000/devstoreaccount1
+	StorageContainer   string
+	LocalStorageDir    string // Directory of the local backend
+	LocalStorageURL    string // URL the API server serves the local backend under
+	LocalStorageSecret string // Signs local upload and download URLs; random when empty
+
+	BlobGCInterval time.Duration // How often orphaned blobs are collected; 0 disables the job
+	BlobGCGrace    time.Duration // How long unreferenced blobs are kept after upload
+
+	BlobCacheDir      string // Directory holding the on-disk cache of served models, in its own subdirectory
+	BlobCacheMaxBytes int64
 }
 
 type secretMapping struct {
@@ -37,6 +54,11 @@ func LoadConfig() *Config {
 	return instance
 }
 
+// Init sets the configuration without loading it from Key Vault, as in tests
+func Init(cfg *Config) {
+	instance = cfg
+}
+
 func GetConfig() *Config {
 	if instance == nil {
 		panic("config not initialized: call LoadConfig() first")
@@ -47,7 +69,25 @@ func GetConfig() *Config {
 func loadFromKeyVault() *Config {
 	cfg := &Config{
 		Port: getEnvOrDefault("PORT", "8080"),
+		ModelBudget: models.ModelBudget{
+			MaxSizeBytes: int64(getEnvIntOrDefault("MODEL_MAX_SIZE_MB", 50)) << 20,
+			MaxTriangles: getEnvIntOrDefault("MODEL_MAX_TRIANGLES", 500000),
+			MaxMaterials: getEnvIntOrDefault("MODEL_MAX_MATERIALS", 32),
+			MaxTextures:  getEnvIntOrDefault("MODEL_MAX_TEXTURES", 16),
+		},
+		StorageBackend:     getEnvOrDefault("STORAGE_BACKEND", "azure"),
+		StorageEndpoint:    os.Getenv("STORAGE_ENDPOINT"),
+		StorageContainer:   getEnvOrDefault("STORAGE_CONTAINER", "models"),
+		StorageAccountName: os.Getenv("STORAGE_ACCOUNT_NAME"),
+		StorageAccountKey:  os.Getenv("STORAGE_ACCOUNT_KEY"),
+		LocalStorageDir:    getEnvOrDefault("LOCAL_STORAGE_DIR", "./data/blobs"),
+		LocalStorageSecret: os.Getenv("LOCAL_STORAGE_SECRET"),
+		BlobGCInterval:     time.Duration(getEnvIntOrDefault("BLOB_GC_INTERVAL_HOURS", 24)) * time.Hour,
+		BlobGCGrace:        time.Duration(getEnvIntOrDefault("BLOB_GC_GRACE_HOURS", 72)) * time.Hour,
+		BlobCacheDir:       getEnvOrDefault("BLOB_CACHE_DIR", os.TempDir()),
+		BlobCacheMaxBytes:  int64(getEnvIntOrDefault("BLOB_CACHE_MAX_MB", 2048)) << 20,
 	}
+	cfg.LocalStorageURL = getEnvOrDefault("LOCAL_STORAGE_URL", "http://localhost:"+cfg.Port+"/storage")
 
 	vaultURL := os.Getenv("AZURE_KEYVAULT_URL")
 	if vaultURL == "" {
@@ -66,11 +106,18 @@ func loadFromKeyVault() *Config {
 
 	mappings := []secretMapping{
 		{keyVaultName: "db-connection-string", target: &cfg.DBConnectionString, required: true},
-		{keyVaultName: "storage-account-name", target: &cfg.StorageAccountName, required: true},
-		{keyVaultName: "storage-account-key", target: &cfg.StorageAccountKey, required: true},
 		{keyVaultName: "clerk-secret-key", target: &cfg.ClerkSecretKey, required: true},
 	}
 
+	// Storage credentials come from the environment for Azurite and are not
+	// needed by the local backend
+	if cfg.StorageBackend == "azure" && cfg.StorageAccountName == "" {
+		mappings = append(mappings,
+			secretMapping{keyVaultName: "storage-account-name", target: &cfg.StorageAccountName, required: true},
+			secretMapping{keyVaultName: "storage-account-key", target: &cfg.StorageAccountKey, required: true},
+		)
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
@@ -119,3 +166,11 @@ func getEnvOrDefault(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvIntOrDefault(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
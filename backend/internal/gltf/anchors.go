@@ -0,0 +1,196 @@
+package gltf
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"fit-pc/models"
+)
+
+// anchorNodeName matches the naming convention for anchor empties, e.g.
+// "anchor_dimm_a1" or "Anchor-cpu_socket". Blender's ".001" duplicate suffix is ignored.
+var anchorNodeName = regexp.MustCompile(`(?i)^anchor[_-]([a-z0-9_\-]+?)(?:\.\d+)?$`)
+
+// indexSuffix matches the trailing slot index of a label, e.g. "_a1" or "_2".
+// An "x" prefix is a lane width ("pcie_x16"), not an index.
+var indexSuffix = regexp.MustCompile(`_[a-wyz]?\d+$`)
+
+// Anchors returns the anchor points defined by the nodes of the default scene,
+// in node order, together with warnings about nodes that were skipped.
+//
+// A node is an anchor when its extras carry an "anchor" object (type, label,
+// direction, connection_axis, compatible_types), flat Blender custom properties
+// (anchor_type, anchor_label, anchor_direction, anchor_axis, anchor_compatible),
+// or when its name follows the anchor_<label> convention. Without an explicit
+// type, the type is the label without its slot index ("dimm_a1" is a "dimm").
+//
+// Positions are in model units. Without an explicit connection axis, the axis is
+// the one closest to the node's -Y direction, and the rotation is what remains
+// once that axis is aligned.
+func (d *Document) Anchors() (models.AnchorPoints, []string) {
+	world := d.WorldTransforms()
+	anchors := models.AnchorPoints{}
+	var warnings []string
+	seen := make(map[string]int)
+
+	for i, node := range d.Nodes {
+		m, reachable := world[i]
+		if !reachable {
+			continue
+		}
+		anchor, ok, err := nodeAnchor(node)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("node %q: %v", nodeLabel(node, i), err))
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if first, dup := seen[anchor.Label]; dup {
+			warnings = append(warnings, fmt.Sprintf("node %q: label %q is already used by node %q", nodeLabel(node, i), anchor.Label, nodeLabel(d.Nodes[first], first)))
+			continue
+		}
+		seen[anchor.Label] = i
+
+		rotation := m.Rotation()
+		if anchor.ConnectionAxis == "" {
+			anchor.ConnectionAxis = DominantAxis(rotation)
+		}
+		t := m.Translation()
+		e := EulerXYZ(AlignedRotation(rotation, anchor.ConnectionAxis))
+		anchor.Position = models.Vector3{X: round(t[0]), Y: round(t[1]), Z: round(t[2])}
+		anchor.Rotation = models.Vector3{X: round(e[0]), Y: round(e[1]), Z: round(e[2])}
+
+		anchors = append(anchors, anchor)
+	}
+	return anchors, warnings
+}
+
+func nodeLabel(node Node, index int) string {
+	if node.Name != "" {
+		return node.Name
+	}
+	return fmt.Sprintf("#%d", index)
+}
+
+// nodeAnchor reads the anchor metadata of a node, without its transform
+func nodeAnchor(node Node) (models.AnchorPoint, bool, error) {
+	var anchor models.AnchorPoint
+	var props map[string]interface{}
+	var keys anchorKeys
+
+	switch nested, ok := node.Extras["anchor"].(map[string]interface{}); {
+	case ok:
+		props, keys = nested, nestedKeys
+	case node.Extras["anchor_type"] != nil:
+		props, keys = node.Extras, flatKeys
+	}
+
+	if match := anchorNodeName.FindStringSubmatch(node.Name); match != nil {
+		anchor.Label = strings.ToLower(match[1])
+	} else if props == nil {
+		return anchor, false, nil
+	}
+
+	if props != nil {
+		var err error
+		fields := []struct {
+			key  string
+			dest *string
+		}{
+			{keys.Type, &anchor.Name},
+			{keys.Label, &anchor.Label},
+			{keys.Direction, &anchor.Direction},
+			{keys.Axis, &anchor.ConnectionAxis},
+		}
+		for _, field := range fields {
+			if err = readString(props, field.key, field.dest); err != nil {
+				return anchor, false, err
+			}
+		}
+		if anchor.CompatibleTypes, err = readStrings(props, keys.Compatible); err != nil {
+			return anchor, false, err
+		}
+	}
+
+	if anchor.Label == "" {
+		anchor.Label = anchor.Name
+	}
+	if anchor.Label == "" {
+		return anchor, false, fmt.Errorf("anchor has no type or label")
+	}
+	if anchor.Name == "" {
+		anchor.Name = indexSuffix.ReplaceAllString(anchor.Label, "")
+	}
+	anchor.ConnectionAxis = strings.ToUpper(anchor.ConnectionAxis)
+	if anchor.ConnectionAxis != "" && !ValidAxis(anchor.ConnectionAxis) {
+		return anchor, false, fmt.Errorf("unknown connection axis %q", anchor.ConnectionAxis)
+	}
+	anchor.Direction = strings.ToLower(anchor.Direction)
+	if anchor.Direction != "" && anchor.Direction != "input" && anchor.Direction != "output" {
+		return anchor, false, fmt.Errorf("direction must be input or output, got %q", anchor.Direction)
+	}
+	return anchor, true, nil
+}
+
+// anchorKeys names the extras properties holding each anchor field
+type anchorKeys struct {
+	Type, Label, Direction, Axis, Compatible string
+}
+
+var (
+	nestedKeys = anchorKeys{"type", "label", "direction", "connection_axis", "compatible_types"}
+	flatKeys   = anchorKeys{"anchor_type", "anchor_label", "anchor_direction", "anchor_axis", "anchor_compatible"}
+)
+
+func readString(props map[string]interface{}, key string, dest *string) error {
+	value, ok := props[key]
+	if !ok || value == nil {
+		return nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("%s must be a string", key)
+	}
+	if s = strings.TrimSpace(s); s != "" {
+		*dest = s
+	}
+	return nil
+}
+
+// readStrings accepts a list of strings or, as Blender custom properties cannot
+// hold lists of strings, a comma-separated string
+func readStrings(props map[string]interface{}, key string) ([]string, error) {
+	var result []string
+	switch value := props[key].(type) {
+	case nil:
+	case string:
+		for _, s := range strings.Split(value, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				result = append(result, s)
+			}
+		}
+	case []interface{}:
+		for _, item := range value {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s must only contain strings", key)
+			}
+			result = append(result, s)
+		}
+	default:
+		return nil, fmt.Errorf("%s must be a list of strings", key)
+	}
+	return result, nil
+}
+
+// round drops floating point noise from matrix arithmetic
+func round(v float64) float64 {
+	r := math.Round(v*1e6) / 1e6
+	if r == 0 {
+		return 0 // no negative zero
+	}
+	return r
+}
@@ -0,0 +1,47 @@
+package gltf
+
+import (
+	"math"
+
+	"fit-pc/models"
+)
+
+// Dimensions are the outer dimensions of a part in a frame: its height along the
+// frame's Y axis, and its length and width, the longer and shorter of the other
+// two extents
+type Dimensions struct {
+	Length float64
+	Width  float64
+	Height float64
+}
+
+// AnchorRotation returns the orientation of an anchor in its model: the stored
+// rotation combined with the alignment of its connection axis, so that the
+// anchor's -Y axis is its insertion direction
+func AnchorRotation(anchor models.AnchorPoint) [3][3]float64 {
+	axis := anchor.ConnectionAxis
+	if !ValidAxis(axis) {
+		axis = AxisYNeg
+	}
+	return UnalignedRotation(EulerMatrix([3]float64{anchor.Rotation.X, anchor.Rotation.Y, anchor.Rotation.Z}), axis)
+}
+
+// Measure returns the dimensions of the box from lo to hi as seen in a frame
+// rotated by r relative to the model. The box is the model's axis-aligned
+// bounding box, so frames not aligned with the model axes measure its corners
+// and overestimate rounded shapes.
+func Measure(lo, hi [3]float64, r [3][3]float64) Dimensions {
+	var extent [3]float64
+	for axis := 0; axis < 3; axis++ {
+		// Extent of the box along the frame axis (column of r): the projection of
+		// each box edge, summed
+		for k := 0; k < 3; k++ {
+			extent[axis] += math.Abs(r[k][axis]) * (hi[k] - lo[k])
+		}
+	}
+	return Dimensions{
+		Length: math.Max(extent[0], extent[2]),
+		Width:  math.Min(extent[0], extent[2]),
+		Height: extent[1],
+	}
+}
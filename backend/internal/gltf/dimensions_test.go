@@ -0,0 +1,31 @@
+package gltf_test
+
+import (
+	"math"
+	"testing"
+
+	"fit-pc/internal/gltf"
+	"fit-pc/models"
+)
+
+func TestMeasure(t *testing.T) {
+	// A graphics card model lying flat: 30 long (X), 4 thick (Y) and 12 tall (Z)
+	lo, hi := [3]float64{-15, 0, 0}, [3]float64{15, 4, 12}
+
+	tests := []struct {
+		name   string
+		anchor models.AnchorPoint
+		want   gltf.Dimensions
+	}{
+		{"model frame", models.AnchorPoint{}, gltf.Dimensions{Length: 30, Width: 12, Height: 4}},
+		// Inserted along -Z: the card's height is its Z extent
+		{"connection axis", models.AnchorPoint{ConnectionAxis: gltf.AxisZNeg}, gltf.Dimensions{Length: 30, Width: 4, Height: 12}},
+		{"rotated anchor", models.AnchorPoint{Rotation: models.Vector3{Z: math.Pi / 2}}, gltf.Dimensions{Length: 12, Width: 4, Height: 30}},
+	}
+	for _, tt := range tests {
+		got := gltf.Measure(lo, hi, gltf.AnchorRotation(tt.anchor))
+		if math.Abs(got.Length-tt.want.Length) > 1e-9 || math.Abs(got.Width-tt.want.Width) > 1e-9 || math.Abs(got.Height-tt.want.Height) > 1e-9 {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
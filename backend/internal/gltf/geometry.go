@@ -0,0 +1,329 @@
+package gltf
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg" // Texture decoders
+	_ "image/png"
+	"math"
+	"strings"
+
+	"fit-pc/internal/imaging"
+)
+
+// ErrExternalBuffer is returned when geometry is stored in a file next to the
+// model rather than in the GLB container or a data URI
+var ErrExternalBuffer = errors.New("model references an external buffer")
+
+// Component types of accessors (glTF 2.0 specification, section 3.6.2.2)
+const (
+	componentByte          = 5120
+	componentUnsignedByte  = 5121
+	componentShort         = 5122
+	componentUnsignedShort = 5123
+	componentUnsignedInt   = 5125
+	componentFloat         = 5126
+)
+
+// defaultColor is the colour of primitives without a material
+var defaultColor = [4]float64{0.8, 0.8, 0.8, 1}
+
+// Triangle is a triangle of the scene in world space, with the base colour of its
+// material (linear RGBA; alpha below 1 only for blended materials)
+type Triangle struct {
+	Vertices [3][3]float64
+	Color    [4]float64
+}
+
+// Apply transforms a point by m
+func (m Mat4) Apply(p [3]float64) [3]float64 {
+	var out [3]float64
+	for row := 0; row < 3; row++ {
+		out[row] = m.at(row, 0)*p[0] + m.at(row, 1)*p[1] + m.at(row, 2)*p[2] + m.at(row, 3)
+	}
+	return out
+}
+
+// buffer returns the data of a buffer: the BIN chunk or a base64 data URI
+func (d *Document) buffer(index int) ([]byte, error) {
+	buffer := d.Buffers[index]
+	switch {
+	case buffer.URI == "":
+		return d.Binary, nil
+	case strings.HasPrefix(buffer.URI, "data:"):
+		_, encoded, ok := strings.Cut(buffer.URI, ";base64,")
+		if !ok {
+			return nil, fmt.Errorf("%w: buffer %d has an unsupported data URI", ErrInvalidModel, index)
+		}
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(data) < buffer.ByteLength {
+			return nil, fmt.Errorf("%w: buffer %d has an invalid data URI", ErrInvalidModel, index)
+		}
+		return data, nil
+	}
+	return nil, ErrExternalBuffer
+}
+
+// bufferView returns the bytes of a buffer view
+func (d *Document) bufferView(index int) ([]byte, error) {
+	view := d.BufferViews[index]
+	data, err := d.buffer(view.Buffer)
+	if err != nil {
+		return nil, err
+	}
+	if view.ByteOffset+view.ByteLength > len(data) {
+		return nil, fmt.Errorf("%w: buffer view %d exceeds its buffer", ErrInvalidModel, index)
+	}
+	return data[view.ByteOffset : view.ByteOffset+view.ByteLength], nil
+}
+
+// ReadAccessor returns the elements of an accessor as a flat list of values,
+// e.g. x, y, z for each element of a VEC3. Normalized integers are mapped to
+// [0, 1] or [-1, 1]; accessors without a buffer view are zero-filled.
+func (d *Document) ReadAccessor(index int) ([]float64, error) {
+	accessor := d.Accessors[index]
+	components := accessorComponents[accessor.Type]
+	values := make([]float64, accessor.Count*components)
+	if accessor.BufferView == nil {
+		return values, nil
+	}
+
+	data, err := d.bufferView(*accessor.BufferView)
+	if err != nil {
+		return nil, err
+	}
+	size := componentSizes[accessor.ComponentType]
+	stride := d.BufferViews[*accessor.BufferView].ByteStride
+	if stride == 0 {
+		stride = components * size
+	}
+
+	for i := 0; i < accessor.Count; i++ {
+		element := data[accessor.ByteOffset+i*stride:]
+		for c := 0; c < components; c++ {
+			values[i*components+c] = readComponent(element[c*size:], accessor.ComponentType, accessor.Normalized)
+		}
+	}
+	return values, nil
+}
+
+// readComponent decodes a little-endian accessor component
+func readComponent(b []byte, componentType int, normalized bool) float64 {
+	switch componentType {
+	case componentByte:
+		v := float64(int8(b[0]))
+		if normalized {
+			return math.Max(v/127, -1)
+		}
+		return v
+	case componentUnsignedByte:
+		if normalized {
+			return float64(b[0]) / 255
+		}
+		return float64(b[0])
+	case componentShort:
+		v := float64(int16(binary.LittleEndian.Uint16(b)))
+		if normalized {
+			return math.Max(v/32767, -1)
+		}
+		return v
+	case componentUnsignedShort:
+		v := float64(binary.LittleEndian.Uint16(b))
+		if normalized {
+			return v / 65535
+		}
+		return v
+	case componentUnsignedInt:
+		return float64(binary.LittleEndian.Uint32(b))
+	default:
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	}
+}
+
+// material is the part of a glTF material that gives its colour
+type material struct {
+	PBR struct {
+		BaseColorFactor  []float64 `json:"baseColorFactor"`
+		BaseColorTexture *struct {
+			Index int `json:"index"`
+		} `json:"baseColorTexture"`
+	} `json:"pbrMetallicRoughness"`
+	AlphaMode string `json:"alphaMode"`
+}
+
+// materialColor returns the base colour of a material: its base colour factor,
+// multiplied by the average colour of its base colour texture when the texture
+// is embedded. Alpha is kept only for blended materials.
+func (d *Document) materialColor(index int) [4]float64 {
+	var m material
+	if err := json.Unmarshal(d.Materials[index], &m); err != nil {
+		return defaultColor
+	}
+	color := [4]float64{1, 1, 1, 1}
+	if len(m.PBR.BaseColorFactor) == 4 {
+		copy(color[:], m.PBR.BaseColorFactor)
+	}
+	if texture := m.PBR.BaseColorTexture; texture != nil {
+		if average, ok := d.textureAverage(texture.Index); ok {
+			for c := range color {
+				color[c] *= average[c]
+			}
+		}
+	}
+	if m.AlphaMode != "BLEND" {
+		color[3] = 1
+	}
+	return color
+}
+
+// textureAverage returns the average linear colour of an embedded texture image
+func (d *Document) textureAverage(index int) ([4]float64, bool) {
+	if index < 0 || index >= len(d.Textures) || d.Textures[index].Source == nil {
+		return [4]float64{}, false
+	}
+	source := d.Images[*d.Textures[index].Source]
+	var data []byte
+	switch {
+	case source.BufferView != nil:
+		view, err := d.bufferView(*source.BufferView)
+		if err != nil {
+			return [4]float64{}, false
+		}
+		data = view
+	case strings.HasPrefix(source.URI, "data:"):
+		_, encoded, ok := strings.Cut(source.URI, ";base64,")
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if !ok || err != nil {
+			return [4]float64{}, false
+		}
+		data = decoded
+	default:
+		return [4]float64{}, false
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || config.Width*config.Height > imaging.MaxPixels {
+		return [4]float64{}, false
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return [4]float64{}, false
+	}
+
+	// Sample a grid of at most 64x64 texels
+	bounds := img.Bounds()
+	stepX, stepY := max(1, bounds.Dx()/64), max(1, bounds.Dy()/64)
+	var sum [4]float64
+	var n float64
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += stepY {
+		for x := bounds.Min.X; x < bounds.Max.X; x += stepX {
+			r, g, b, a := img.At(x, y).RGBA()
+			sum[0] += srgbToLinear(float64(r) / 0xFFFF)
+			sum[1] += srgbToLinear(float64(g) / 0xFFFF)
+			sum[2] += srgbToLinear(float64(b) / 0xFFFF)
+			sum[3] += float64(a) / 0xFFFF
+			n++
+		}
+	}
+	for c := range sum {
+		sum[c] /= n
+	}
+	return sum, true
+}
+
+// srgbToLinear converts an sRGB encoded channel to linear light
+func srgbToLinear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// Triangles returns the triangles of the default scene in world space. Points
+// and lines are skipped. Returns ErrExternalBuffer when geometry is stored
+// outside the model file.
+func (d *Document) Triangles() ([]Triangle, error) {
+	colors := make(map[int][4]float64)
+	var triangles []Triangle
+
+	for index, m := range d.WorldTransforms() {
+		node := d.Nodes[index]
+		if node.Mesh == nil {
+			continue
+		}
+		for _, primitive := range d.Meshes[*node.Mesh].Primitives {
+			mode := ModeTriangles
+			if primitive.Mode != nil {
+				mode = *primitive.Mode
+			}
+			if mode != ModeTriangles && mode != ModeTriangleStrip && mode != ModeTriangleFan {
+				continue
+			}
+
+			positions, err := d.ReadAccessor(primitive.Attributes["POSITION"])
+			if err != nil {
+				return nil, err
+			}
+			count := len(positions) / 3
+			indices := make([]int, count)
+			for i := range indices {
+				indices[i] = i
+			}
+			if primitive.Indices != nil {
+				values, err := d.ReadAccessor(*primitive.Indices)
+				if err != nil {
+					return nil, err
+				}
+				indices = indices[:0]
+				for _, v := range values {
+					if int(v) >= count {
+						return nil, fmt.Errorf("%w: index %d exceeds %d vertices", ErrInvalidModel, int(v), count)
+					}
+					indices = append(indices, int(v))
+				}
+			}
+
+			color := defaultColor
+			if primitive.Material != nil {
+				c, ok := colors[*primitive.Material]
+				if !ok {
+					c = d.materialColor(*primitive.Material)
+					colors[*primitive.Material] = c
+				}
+				color = c
+			}
+
+			vertex := func(i int) [3]float64 {
+				p := indices[i] * 3
+				return m.Apply([3]float64{positions[p], positions[p+1], positions[p+2]})
+			}
+			add := func(a, b, c int) {
+				triangles = append(triangles, Triangle{Vertices: [3][3]float64{vertex(a), vertex(b), vertex(c)}, Color: color})
+			}
+			switch mode {
+			case ModeTriangles:
+				for i := 0; i+2 < len(indices); i += 3 {
+					add(i, i+1, i+2)
+				}
+			case ModeTriangleStrip:
+				for i := 0; i+2 < len(indices); i++ {
+					if i%2 == 0 {
+						add(i, i+1, i+2)
+					} else {
+						add(i+1, i, i+2)
+					}
+				}
+			case ModeTriangleFan:
+				for i := 1; i+1 < len(indices); i++ {
+					add(0, i, i+1)
+				}
+			}
+		}
+	}
+	return triangles, nil
+}
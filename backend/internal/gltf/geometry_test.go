@@ -0,0 +1,75 @@
+package gltf_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+
+	"fit-pc/internal/gltf"
+)
+
+// quad is a 2x2 square in the XY plane drawn as two indexed triangles, moved
+// 10 units along X by its node
+const quad = `{
+	"asset": {"version": "2.0"},
+	"scenes": [{"nodes": [0]}],
+	"nodes": [{"mesh": 0, "translation": [10, 0, 0]}],
+	"meshes": [{"primitives": [
+		{"attributes": {"POSITION": 0}, "indices": 1, "material": 0},
+		{"attributes": {"POSITION": 0}, "mode": 5}
+	]}],
+	"materials": [{"pbrMetallicRoughness": {"baseColorFactor": [1, 0, 0, 0.5]}}],
+	"accessors": [
+		{"bufferView": 0, "componentType": 5126, "count": 4, "type": "VEC3", "min": [-1, -1, 0], "max": [1, 1, 0]},
+		{"bufferView": 1, "componentType": 5123, "count": 6, "type": "SCALAR"}
+	],
+	"bufferViews": [{"buffer": 0, "byteLength": 48}, {"buffer": 0, "byteOffset": 48, "byteLength": 12}],
+	"buffers": [{"byteLength": 60}]
+}`
+
+func quadBuffer() []byte {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, []float32{-1, -1, 0, 1, -1, 0, 1, 1, 0, -1, 1, 0})
+	binary.Write(buf, binary.LittleEndian, []uint16{0, 1, 2, 0, 2, 3})
+	return buf.Bytes()
+}
+
+func TestDocument_Triangles(t *testing.T) {
+	doc, err := gltf.Decode(glb(quad, quadBuffer()))
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	triangles, err := doc.Triangles()
+	if err != nil {
+		t.Fatalf("Triangles failed: %v", err)
+	}
+
+	// Two indexed triangles and two from the strip over the 4 vertices
+	if len(triangles) != 4 {
+		t.Fatalf("expected 4 triangles, got %d", len(triangles))
+	}
+	first := triangles[0]
+	if first.Vertices != [3][3]float64{{9, -1, 0}, {11, -1, 0}, {11, 1, 0}} {
+		t.Errorf("unexpected world-space vertices %v", first.Vertices)
+	}
+	// Alpha is only kept for blended materials
+	if first.Color != [4]float64{1, 0, 0, 1} {
+		t.Errorf("expected the material colour, got %v", first.Color)
+	}
+	if triangles[2].Color != [4]float64{0.8, 0.8, 0.8, 1} {
+		t.Errorf("expected the default colour without a material, got %v", triangles[2].Color)
+	}
+}
+
+func TestDocument_Triangles_ExternalBuffer(t *testing.T) {
+	model := strings.Replace(quad, `"buffers": [{"byteLength": 60}]`, `"buffers": [{"uri": "quad.bin", "byteLength": 60}]`, 1)
+	doc, err := gltf.Decode([]byte(model))
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if _, err := doc.Triangles(); !errors.Is(err, gltf.ErrExternalBuffer) {
+		t.Errorf("expected ErrExternalBuffer, got %v", err)
+	}
+}
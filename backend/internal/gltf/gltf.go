@@ -0,0 +1,415 @@
+// Package gltf reads glTF 2.0 models, either binary (.glb) or JSON (.gltf), far
+// enough to walk their scene graph (scenes, nodes, transforms and extras) and to
+// validate, measure and read their geometry.
+package gltf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// GLB container constants (glTF 2.0 specification, section 4.4)
+const (
+	glbMagic      = 0x46546C67 // "glTF"
+	glbVersion    = 2
+	glbHeaderSize = 12
+	chunkJSON     = 0x4E4F534A // "JSON"
+	chunkBIN      = 0x004E4942 // "BIN\x00"
+)
+
+// Media types of glTF models
+const (
+	MediaTypeBinary = "model/gltf-binary"
+	MediaTypeJSON   = "model/gltf+json"
+)
+
+// ErrInvalidModel is returned when data is neither a valid GLB container nor a glTF 2.0 document
+var ErrInvalidModel = errors.New("invalid glTF model")
+
+// Document is the subset of a glTF document needed to place nodes in a scene and
+// measure their geometry. Binary holds the BIN chunk of a GLB container.
+type Document struct {
+	Asset       Asset             `json:"asset"`
+	Scene       *int              `json:"scene,omitempty"`
+	Scenes      []Scene           `json:"scenes,omitempty"`
+	Nodes       []Node            `json:"nodes,omitempty"`
+	Meshes      []Mesh            `json:"meshes,omitempty"`
+	Accessors   []Accessor        `json:"accessors,omitempty"`
+	BufferViews []BufferView      `json:"bufferViews,omitempty"`
+	Buffers     []Buffer          `json:"buffers,omitempty"`
+	Materials   []json.RawMessage `json:"materials,omitempty"`
+	Textures    []Texture         `json:"textures,omitempty"`
+	Images      []Image           `json:"images,omitempty"`
+	Binary      []byte            `json:"-"`
+}
+
+// Asset holds the glTF asset metadata
+type Asset struct {
+	Version   string `json:"version"`
+	Generator string `json:"generator,omitempty"`
+}
+
+// Scene lists the root nodes of a scene
+type Scene struct {
+	Name  string `json:"name,omitempty"`
+	Nodes []int  `json:"nodes,omitempty"`
+}
+
+// Node is a scene graph node. Its local transform is either Matrix (column-major)
+// or the Translation, Rotation (quaternion x, y, z, w) and Scale properties.
+type Node struct {
+	Name        string                 `json:"name,omitempty"`
+	Children    []int                  `json:"children,omitempty"`
+	Mesh        *int                   `json:"mesh,omitempty"`
+	Matrix      []float64              `json:"matrix,omitempty"`
+	Translation []float64              `json:"translation,omitempty"`
+	Rotation    []float64              `json:"rotation,omitempty"`
+	Scale       []float64              `json:"scale,omitempty"`
+	Extras      map[string]interface{} `json:"extras,omitempty"`
+}
+
+// Mesh is a set of primitives drawn together
+type Mesh struct {
+	Name       string      `json:"name,omitempty"`
+	Primitives []Primitive `json:"primitives"`
+}
+
+// Primitive is geometry drawn with one material. Mode defaults to triangles.
+type Primitive struct {
+	Attributes map[string]int `json:"attributes"`
+	Indices    *int           `json:"indices,omitempty"`
+	Material   *int           `json:"material,omitempty"`
+	Mode       *int           `json:"mode,omitempty"`
+}
+
+// Primitive modes (glTF 2.0 specification, section 5.24.4)
+const (
+	ModePoints        = 0
+	ModeLines         = 1
+	ModeLineLoop      = 2
+	ModeLineStrip     = 3
+	ModeTriangles     = 4
+	ModeTriangleStrip = 5
+	ModeTriangleFan   = 6
+)
+
+// Accessor describes typed elements stored in a buffer view
+type Accessor struct {
+	BufferView    *int      `json:"bufferView,omitempty"`
+	ByteOffset    int       `json:"byteOffset,omitempty"`
+	ComponentType int       `json:"componentType"`
+	Normalized    bool      `json:"normalized,omitempty"`
+	Count         int       `json:"count"`
+	Type          string    `json:"type"`
+	Min           []float64 `json:"min,omitempty"`
+	Max           []float64 `json:"max,omitempty"`
+}
+
+// BufferView is a slice of a buffer
+type BufferView struct {
+	Buffer     int `json:"buffer"`
+	ByteOffset int `json:"byteOffset,omitempty"`
+	ByteLength int `json:"byteLength"`
+	ByteStride int `json:"byteStride,omitempty"`
+}
+
+// Buffer is binary data, external (URI) or the GLB BIN chunk (no URI)
+type Buffer struct {
+	URI        string `json:"uri,omitempty"`
+	ByteLength int    `json:"byteLength"`
+}
+
+// Texture references an image
+type Texture struct {
+	Source *int `json:"source,omitempty"`
+}
+
+// Image is texture data, external (URI) or stored in a buffer view
+type Image struct {
+	URI        string `json:"uri,omitempty"`
+	MimeType   string `json:"mimeType,omitempty"`
+	BufferView *int   `json:"bufferView,omitempty"`
+}
+
+// Decode parses and validates a GLB container or a glTF JSON document. External
+// buffers are not loaded.
+func Decode(data []byte) (*Document, error) {
+	jsonChunk, binChunk := data, []byte(nil)
+	if IsBinary(data) {
+		var err error
+		if jsonChunk, binChunk, err = splitGLB(data); err != nil {
+			return nil, err
+		}
+	}
+
+	var doc Document
+	if err := json.Unmarshal(jsonChunk, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidModel, err)
+	}
+	doc.Binary = binChunk
+	if !strings.HasPrefix(doc.Asset.Version, "2.") {
+		return nil, fmt.Errorf("%w: unsupported glTF version %q", ErrInvalidModel, doc.Asset.Version)
+	}
+	if err := doc.validate(); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// IsBinary reports whether data starts with the GLB magic
+func IsBinary(data []byte) bool {
+	return len(data) >= 4 && binary.LittleEndian.Uint32(data) == glbMagic
+}
+
+// splitGLB returns the JSON chunk and the optional BIN chunk of a GLB container
+func splitGLB(data []byte) (jsonChunk, binChunk []byte, err error) {
+	if len(data) < glbHeaderSize {
+		return nil, nil, fmt.Errorf("%w: truncated GLB header", ErrInvalidModel)
+	}
+	if version := binary.LittleEndian.Uint32(data[4:]); version != glbVersion {
+		return nil, nil, fmt.Errorf("%w: unsupported GLB version %d", ErrInvalidModel, version)
+	}
+	length := binary.LittleEndian.Uint32(data[8:])
+	if int(length) > len(data) || length < glbHeaderSize {
+		return nil, nil, fmt.Errorf("%w: GLB length %d does not match data size %d", ErrInvalidModel, length, len(data))
+	}
+
+	body := data[glbHeaderSize:length]
+	for first := true; len(body) > 0; first = false {
+		if len(body) < 8 {
+			return nil, nil, fmt.Errorf("%w: truncated GLB chunk header", ErrInvalidModel)
+		}
+		chunkLength := binary.LittleEndian.Uint32(body)
+		chunkType := binary.LittleEndian.Uint32(body[4:])
+		if uint64(chunkLength) > uint64(len(body)-8) {
+			return nil, nil, fmt.Errorf("%w: truncated GLB chunk", ErrInvalidModel)
+		}
+		chunk := body[8 : 8+chunkLength]
+		body = body[8+chunkLength:]
+
+		switch {
+		case first && chunkType != chunkJSON:
+			return nil, nil, fmt.Errorf("%w: first GLB chunk is not JSON", ErrInvalidModel)
+		case first:
+			// JSON chunks are padded with trailing spaces
+			jsonChunk = bytes.TrimRight(chunk, " \x00")
+		case chunkType == chunkBIN && binChunk == nil:
+			binChunk = chunk
+		}
+		// Unknown chunk types must be ignored
+	}
+
+	if jsonChunk == nil {
+		return nil, nil, fmt.Errorf("%w: missing GLB JSON chunk", ErrInvalidModel)
+	}
+	return jsonChunk, binChunk, nil
+}
+
+// validate checks node references and transform sizes, rejects cycles and
+// validates the geometry
+func (d *Document) validate() error {
+	valid := func(i int) bool { return i >= 0 && i < len(d.Nodes) }
+
+	parents := make([]int, len(d.Nodes))
+	for i := range parents {
+		parents[i] = -1
+	}
+	for i, node := range d.Nodes {
+		if node.Mesh != nil && (*node.Mesh < 0 || *node.Mesh >= len(d.Meshes)) {
+			return fmt.Errorf("%w: node %d references missing mesh %d", ErrInvalidModel, i, *node.Mesh)
+		}
+		if node.Matrix != nil && len(node.Matrix) != 16 ||
+			node.Translation != nil && len(node.Translation) != 3 ||
+			node.Rotation != nil && len(node.Rotation) != 4 ||
+			node.Scale != nil && len(node.Scale) != 3 {
+			return fmt.Errorf("%w: node %d has a malformed transform", ErrInvalidModel, i)
+		}
+		for _, child := range node.Children {
+			if !valid(child) || child == i || parents[child] != -1 {
+				return fmt.Errorf("%w: node %d has an invalid child %d", ErrInvalidModel, i, child)
+			}
+			parents[child] = i
+		}
+	}
+
+	// With single parents, a cycle is a chain of parents that never ends
+	for i := range d.Nodes {
+		steps := 0
+		for p := parents[i]; p != -1; p = parents[p] {
+			if steps++; steps > len(d.Nodes) {
+				return fmt.Errorf("%w: node hierarchy contains a cycle", ErrInvalidModel)
+			}
+		}
+	}
+
+	if err := d.validateGeometry(); err != nil {
+		return err
+	}
+
+	if d.Scene != nil && (*d.Scene < 0 || *d.Scene >= len(d.Scenes)) {
+		return fmt.Errorf("%w: invalid default scene %d", ErrInvalidModel, *d.Scene)
+	}
+	for i, scene := range d.Scenes {
+		for _, root := range scene.Nodes {
+			if !valid(root) || parents[root] != -1 {
+				return fmt.Errorf("%w: scene %d has an invalid root node %d", ErrInvalidModel, i, root)
+			}
+		}
+	}
+	return nil
+}
+
+// accessorComponents is the number of components of each accessor type
+var accessorComponents = map[string]int{
+	"SCALAR": 1, "VEC2": 2, "VEC3": 3, "VEC4": 4, "MAT2": 4, "MAT3": 9, "MAT4": 16,
+}
+
+// maxZeroFilledValues limits the values of an accessor without a buffer view
+const maxZeroFilledValues = 1 << 22
+
+// componentSizes is the byte size of each accessor component type
+var componentSizes = map[int]int{
+	5120: 1, 5121: 1, 5122: 2, 5123: 2, 5125: 4, 5126: 4,
+}
+
+// validateGeometry checks that meshes, accessors, buffer views, buffers, textures
+// and images reference each other within range and that the data fits its buffers
+func (d *Document) validateGeometry() error {
+	for i, buffer := range d.Buffers {
+		if buffer.ByteLength < 0 {
+			return fmt.Errorf("%w: buffer %d has a negative length", ErrInvalidModel, i)
+		}
+		if i == 0 && buffer.URI == "" && buffer.ByteLength > len(d.Binary) {
+			return fmt.Errorf("%w: buffer 0 needs %d bytes but the BIN chunk has %d", ErrInvalidModel, buffer.ByteLength, len(d.Binary))
+		}
+	}
+
+	for i, view := range d.BufferViews {
+		if view.Buffer < 0 || view.Buffer >= len(d.Buffers) {
+			return fmt.Errorf("%w: buffer view %d references missing buffer %d", ErrInvalidModel, i, view.Buffer)
+		}
+		if view.ByteOffset < 0 || view.ByteLength < 1 || view.ByteLength > d.Buffers[view.Buffer].ByteLength-view.ByteOffset {
+			return fmt.Errorf("%w: buffer view %d exceeds buffer %d", ErrInvalidModel, i, view.Buffer)
+		}
+	}
+
+	for i, accessor := range d.Accessors {
+		components, ok := accessorComponents[accessor.Type]
+		size, known := componentSizes[accessor.ComponentType]
+		if !ok || !known || accessor.Count < 1 {
+			return fmt.Errorf("%w: accessor %d has an invalid type, component type or count", ErrInvalidModel, i)
+		}
+		if accessor.BufferView == nil {
+			// Zero-filled, so nothing in the file bounds its size
+			if accessor.Count > maxZeroFilledValues/components {
+				return fmt.Errorf("%w: accessor %d has too many elements", ErrInvalidModel, i)
+			}
+			continue
+		}
+		if *accessor.BufferView < 0 || *accessor.BufferView >= len(d.BufferViews) {
+			return fmt.Errorf("%w: accessor %d references missing buffer view %d", ErrInvalidModel, i, *accessor.BufferView)
+		}
+		view := d.BufferViews[*accessor.BufferView]
+		element := components * size
+		stride := element
+		if view.ByteStride > 0 {
+			stride = view.ByteStride
+		}
+		// Written so that huge counts cannot overflow
+		if accessor.ByteOffset < 0 || accessor.ByteOffset > view.ByteLength-element || accessor.Count-1 > (view.ByteLength-accessor.ByteOffset-element)/stride {
+			return fmt.Errorf("%w: accessor %d exceeds buffer view %d", ErrInvalidModel, i, *accessor.BufferView)
+		}
+	}
+
+	validAccessor := func(i int) bool { return i >= 0 && i < len(d.Accessors) }
+	for m, mesh := range d.Meshes {
+		if len(mesh.Primitives) == 0 {
+			return fmt.Errorf("%w: mesh %d has no primitives", ErrInvalidModel, m)
+		}
+		for p, primitive := range mesh.Primitives {
+			position, ok := primitive.Attributes["POSITION"]
+			if !ok {
+				return fmt.Errorf("%w: mesh %d primitive %d has no POSITION attribute", ErrInvalidModel, m, p)
+			}
+			for name, accessor := range primitive.Attributes {
+				if !validAccessor(accessor) {
+					return fmt.Errorf("%w: mesh %d primitive %d attribute %s references missing accessor %d", ErrInvalidModel, m, p, name, accessor)
+				}
+			}
+			if positions := d.Accessors[position]; positions.Type != "VEC3" || len(positions.Min) != 3 || len(positions.Max) != 3 {
+				return fmt.Errorf("%w: mesh %d primitive %d POSITION accessor must be a VEC3 with min and max", ErrInvalidModel, m, p)
+			}
+			if primitive.Indices != nil && (!validAccessor(*primitive.Indices) || d.Accessors[*primitive.Indices].Type != "SCALAR") {
+				return fmt.Errorf("%w: mesh %d primitive %d has invalid indices", ErrInvalidModel, m, p)
+			}
+			if primitive.Material != nil && (*primitive.Material < 0 || *primitive.Material >= len(d.Materials)) {
+				return fmt.Errorf("%w: mesh %d primitive %d references missing material %d", ErrInvalidModel, m, p, *primitive.Material)
+			}
+			if primitive.Mode != nil && (*primitive.Mode < ModePoints || *primitive.Mode > ModeTriangleFan) {
+				return fmt.Errorf("%w: mesh %d primitive %d has unknown mode %d", ErrInvalidModel, m, p, *primitive.Mode)
+			}
+		}
+	}
+
+	for i, texture := range d.Textures {
+		if texture.Source != nil && (*texture.Source < 0 || *texture.Source >= len(d.Images)) {
+			return fmt.Errorf("%w: texture %d references missing image %d", ErrInvalidModel, i, *texture.Source)
+		}
+	}
+	for i, image := range d.Images {
+		if image.BufferView != nil && (*image.BufferView < 0 || *image.BufferView >= len(d.BufferViews)) {
+			return fmt.Errorf("%w: image %d references missing buffer view %d", ErrInvalidModel, i, *image.BufferView)
+		}
+		if image.URI == "" && image.BufferView == nil {
+			return fmt.Errorf("%w: image %d has no data", ErrInvalidModel, i)
+		}
+	}
+	return nil
+}
+
+// roots returns the root nodes of the default scene, falling back to the first
+// scene and, for documents without scenes, to every node without a parent
+func (d *Document) roots() []int {
+	switch {
+	case d.Scene != nil:
+		return d.Scenes[*d.Scene].Nodes
+	case len(d.Scenes) > 0:
+		return d.Scenes[0].Nodes
+	}
+
+	isChild := make([]bool, len(d.Nodes))
+	for _, node := range d.Nodes {
+		for _, child := range node.Children {
+			isChild[child] = true
+		}
+	}
+	var roots []int
+	for i := range d.Nodes {
+		if !isChild[i] {
+			roots = append(roots, i)
+		}
+	}
+	return roots
+}
+
+// WorldTransforms returns the world matrix of every node reachable from the
+// default scene, indexed by node
+func (d *Document) WorldTransforms() map[int]Mat4 {
+	world := make(map[int]Mat4, len(d.Nodes))
+	var walk func(index int, parent Mat4)
+	walk = func(index int, parent Mat4) {
+		m := parent.Mul(d.Nodes[index].LocalTransform())
+		world[index] = m
+		for _, child := range d.Nodes[index].Children {
+			walk(child, m)
+		}
+	}
+	for _, root := range d.roots() {
+		walk(root, Identity())
+	}
+	return world
+}
@@ -0,0 +1,374 @@
+package gltf_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"fit-pc/internal/gltf"
+	"fit-pc/models"
+)
+
+// glb wraps a glTF JSON document and an optional binary buffer in a GLB container
+func glb(json string, bin []byte) []byte {
+	pad := func(b []byte, fill byte) []byte {
+		for len(b)%4 != 0 {
+			b = append(b, fill)
+		}
+		return b
+	}
+	chunks := new(bytes.Buffer)
+	for _, chunk := range []struct {
+		kind uint32
+		data []byte
+	}{{0x4E4F534A, pad([]byte(json), ' ')}, {0x004E4942, pad(bin, 0)}} {
+		if chunk.data == nil {
+			continue
+		}
+		binary.Write(chunks, binary.LittleEndian, uint32(len(chunk.data)))
+		binary.Write(chunks, binary.LittleEndian, chunk.kind)
+		chunks.Write(chunk.data)
+	}
+
+	out := new(bytes.Buffer)
+	binary.Write(out, binary.LittleEndian, uint32(0x46546C67))
+	binary.Write(out, binary.LittleEndian, uint32(2))
+	binary.Write(out, binary.LittleEndian, uint32(12+chunks.Len()))
+	out.Write(chunks.Bytes())
+	return out.Bytes()
+}
+
+const motherboard = `{
+	"asset": {"version": "2.0", "generator": "Blender"},
+	"scene": 0,
+	"scenes": [{"nodes": [0]}],
+	"nodes": [
+		{"name": "Motherboard", "translation": [1, 2, 3], "scale": [2, 2, 2], "children": [1, 2, 3, 4]},
+		{"name": "anchor_dimm_a1", "translation": [0.5, 0, 0]},
+		{"name": "Anchor-pcie_x16.001", "rotation": [0.7071068, 0, 0, 0.7071068]},
+		{"name": "Socket", "extras": {"anchor": {"type": "cpu_socket", "label": "CPU", "direction": "output", "compatible_types": ["cpu"]}}},
+		{"name": "Screw"}
+	]
+}`
+
+func TestDecode(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"gltf": []byte(motherboard),
+		"glb":  glb(motherboard, []byte{1, 2, 3}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			doc, err := gltf.Decode(data)
+			if err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+			if len(doc.Nodes) != 5 || doc.Nodes[1].Name != "anchor_dimm_a1" {
+				t.Errorf("unexpected nodes: %+v", doc.Nodes)
+			}
+			if doc.Asset.Generator != "Blender" {
+				t.Errorf("expected generator Blender, got %q", doc.Asset.Generator)
+			}
+		})
+	}
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	truncated := glb(motherboard, nil)
+	tests := map[string][]byte{
+		"not json":       []byte("solid cube"),
+		"glTF 1.0":       []byte(`{"asset":{"version":"1.0"}}`),
+		"truncated glb":  truncated[:len(truncated)-10],
+		"child cycle":    []byte(`{"asset":{"version":"2.0"},"nodes":[{"children":[1]},{"children":[0]}]}`),
+		"shared child":   []byte(`{"asset":{"version":"2.0"},"nodes":[{"children":[2]},{"children":[2]},{}]}`),
+		"missing child":  []byte(`{"asset":{"version":"2.0"},"nodes":[{"children":[5]}]}`),
+		"bad matrix":     []byte(`{"asset":{"version":"2.0"},"nodes":[{"matrix":[1,0,0]}]}`),
+		"bad scene root": []byte(`{"asset":{"version":"2.0"},"scenes":[{"nodes":[1]}],"nodes":[{"children":[1]},{}]}`),
+	}
+
+	for name, data := range tests {
+		if _, err := gltf.Decode(data); !errors.Is(err, gltf.ErrInvalidModel) {
+			t.Errorf("%s: expected ErrInvalidModel, got %v", name, err)
+		}
+	}
+}
+
+func TestDocument_Anchors(t *testing.T) {
+	doc, err := gltf.Decode([]byte(motherboard))
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	anchors, warnings := doc.Anchors()
+	if len(warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+
+	want := models.AnchorPoints{
+		{
+			// Parent translation and scale apply to the position
+			Name: "dimm", Label: "dimm_a1",
+			Position:       models.Vector3{X: 2, Y: 2, Z: 3},
+			ConnectionAxis: "Y_NEG",
+		},
+		{
+			// 90° about X turns -Y into -Z; nothing is left once the axis is aligned
+			Name: "pcie_x16", Label: "pcie_x16",
+			Position:       models.Vector3{X: 1, Y: 2, Z: 3},
+			ConnectionAxis: "Z_NEG",
+		},
+		{
+			Name: "cpu_socket", Label: "CPU",
+			Position:        models.Vector3{X: 1, Y: 2, Z: 3},
+			Direction:       "output",
+			ConnectionAxis:  "Y_NEG",
+			CompatibleTypes: []string{"cpu"},
+		},
+	}
+	if !reflect.DeepEqual(anchors, want) {
+		t.Errorf("got %+v\nwant %+v", anchors, want)
+	}
+}
+
+func TestDocument_Anchors_Extras(t *testing.T) {
+	doc, err := gltf.Decode([]byte(`{
+		"asset": {"version": "2.0"},
+		"nodes": [
+			{"name": "Empty", "rotation": [0.7071068, 0, 0, 0.7071068], "extras": {"anchor_type": "sata_port", "anchor_label": "SATA 1", "anchor_axis": "y_neg", "anchor_compatible": "sata_plug, storage"}},
+			{"name": "anchor_ram_slot_2", "rotation": [0, 0.2588190, 0, 0.9659258], "extras": {"anchor": {"direction": "input"}}},
+			{"name": "anchor_m2", "extras": {"anchor": {"connection_axis": "W_POS"}}},
+			{"name": "anchor_ram_slot_2.001"},
+			{"name": "Unused", "extras": {"anchor": {"type": "orphan"}}, "children": [5]},
+			{"name": "anchor_nested"}
+		],
+		"scenes": [{"nodes": [0, 1, 2, 3]}]
+	}`))
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	anchors, warnings := doc.Anchors()
+	if len(anchors) != 2 {
+		t.Fatalf("expected 2 anchors, got %+v", anchors)
+	}
+
+	sata := anchors[0]
+	if sata.Name != "sata_port" || sata.Label != "SATA 1" || sata.ConnectionAxis != "Y_NEG" {
+		t.Errorf("unexpected flat extras anchor: %+v", sata)
+	}
+	// With an explicit axis, the whole node rotation is kept
+	if math.Abs(sata.Rotation.X-math.Pi/2) > 1e-5 {
+		t.Errorf("expected rotation of π/2 about X, got %+v", sata.Rotation)
+	}
+	if !reflect.DeepEqual(sata.CompatibleTypes, []string{"sata_plug", "storage"}) {
+		t.Errorf("unexpected compatible types: %v", sata.CompatibleTypes)
+	}
+
+	ram := anchors[1]
+	if ram.Name != "ram_slot" || ram.Label != "ram_slot_2" || ram.Direction != "input" || ram.ConnectionAxis != "Y_NEG" {
+		t.Errorf("unexpected named anchor: %+v", ram)
+	}
+	// 30° about Y keeps -Y as the insertion direction
+	if math.Abs(ram.Rotation.Y-math.Pi/6) > 1e-5 || ram.Rotation.X != 0 || ram.Rotation.Z != 0 {
+		t.Errorf("expected rotation of π/6 about Y, got %+v", ram.Rotation)
+	}
+
+	// Nodes outside the scene are ignored; the bad axis and duplicate label are reported
+	if len(warnings) != 2 || !strings.Contains(warnings[0], "W_POS") || !strings.Contains(warnings[1], "already used") {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+}
+
+func TestDominantAxis(t *testing.T) {
+	tests := []struct {
+		quaternion []float64
+		want       string
+	}{
+		{[]float64{0, 0, 0, 1}, "Y_NEG"},
+		{[]float64{1, 0, 0, 0}, "Y_POS"},                  // 180° about X
+		{[]float64{-0.7071068, 0, 0, 0.7071068}, "Z_POS"}, // -90° about X
+		{[]float64{0, 0, 0.7071068, 0.7071068}, "X_POS"},  // 90° about Z
+		{[]float64{0, 0, -0.7071068, 0.7071068}, "X_NEG"}, // -90° about Z
+	}
+
+	for _, tt := range tests {
+		r := gltf.Node{Rotation: tt.quaternion}.LocalTransform().Rotation()
+		if got := gltf.DominantAxis(r); got != tt.want {
+			t.Errorf("DominantAxis(%v) = %s, want %s", tt.quaternion, got, tt.want)
+			continue
+		}
+		// The remainder after aligning an axis-aligned rotation is the identity
+		if e := gltf.EulerXYZ(gltf.AlignedRotation(r, tt.want)); math.Abs(e[0])+math.Abs(e[1])+math.Abs(e[2]) > 1e-5 {
+			t.Errorf("AlignedRotation(%v, %s) left %v", tt.quaternion, tt.want, e)
+		}
+	}
+}
+
+func TestEulerXYZ(t *testing.T) {
+	// Matches three.js Euler.setFromRotationMatrix with the default XYZ order
+	r := gltf.Node{Matrix: []float64{
+		0.8660254, 0.5, 0, 0,
+		-0.5, 0.8660254, 0, 0,
+		0, 0, 1, 0,
+		0, 0, 0, 1,
+	}}.LocalTransform().Rotation()
+	e := gltf.EulerXYZ(r)
+	if math.Abs(e[0]) > 1e-6 || math.Abs(e[1]) > 1e-6 || math.Abs(e[2]-math.Pi/6) > 1e-6 {
+		t.Errorf("expected 30° about Z, got %v", e)
+	}
+}
+
+func TestWithAnchors_RoundTrip(t *testing.T) {
+	model := `{
+		"asset": {"version": "2.0"},
+		"extensionsUsed": ["KHR_materials_unlit"],
+		"scene": 0,
+		"scenes": [{"name": "Main", "nodes": [0, 3]}],
+		"nodes": [
+			{"name": "anchor_old"},
+			{"name": "Fan"},
+			{"name": "anchor_stale_1"},
+			{"name": "Board", "children": [1, 2], "extras": {"artist": "kim"}}
+		],
+		"animations": [{"channels": [{"sampler": 0, "target": {"node": 2, "path": "translation"}}, {"sampler": 0, "target": {"node": 1, "path": "rotation"}}], "samplers": [{"input": 0, "output": 1}]}]
+	}`
+	anchors := models.AnchorPoints{
+		{Name: "cpu_socket", Label: "CPU", Position: models.Vector3{X: 1, Y: 2, Z: 3}, Direction: "output", ConnectionAxis: "Y_NEG", CompatibleTypes: []string{"cpu"}},
+		{Name: "pcie_x16", Label: "PCIe 1", Position: models.Vector3{X: -4, Y: 0.5, Z: 0}, Rotation: models.Vector3{X: 0.1, Y: -0.2, Z: 0.3}, Direction: "output", ConnectionAxis: "Z_NEG", CompatibleTypes: []string{"gpu"}},
+		{Name: "cpu_bottom", Label: "cpu_bottom", Rotation: models.Vector3{Y: math.Pi / 4}, Direction: "input", ConnectionAxis: "X_POS"},
+	}
+
+	out, err := gltf.WithAnchors(glb(model, []byte{1, 2, 3}), anchors)
+	if err != nil {
+		t.Fatalf("WithAnchors failed: %v", err)
+	}
+	if !gltf.IsBinary(out) || !bytes.HasSuffix(out, []byte{1, 2, 3, 0}) {
+		t.Error("expected a GLB keeping the binary buffer")
+	}
+	for _, kept := range []string{"KHR_materials_unlit", `"artist":"kim"`, `"name":"Main"`} {
+		if !bytes.Contains(out, []byte(kept)) {
+			t.Errorf("expected %s to be kept", kept)
+		}
+	}
+
+	doc, err := gltf.Decode(out)
+	if err != nil {
+		t.Fatalf("Decode of the export failed: %v", err)
+	}
+	// Old anchors are replaced, other nodes renumbered
+	if len(doc.Nodes) != 5 || doc.Nodes[0].Name != "Fan" || !reflect.DeepEqual(doc.Nodes[1].Children, []int{0}) {
+		t.Errorf("unexpected nodes: %+v", doc.Nodes)
+	}
+	if !reflect.DeepEqual(doc.Scenes[0].Nodes, []int{1, 2, 3, 4}) {
+		t.Errorf("unexpected scene roots: %v", doc.Scenes[0].Nodes)
+	}
+	if !bytes.Contains(out, []byte(`"target":{"node":0,"path":"rotation"}`)) || bytes.Contains(out, []byte(`"path":"translation"`)) {
+		t.Error("expected the animation channel of the removed anchor to be dropped and the other renumbered")
+	}
+
+	read, warnings := doc.Anchors()
+	if len(warnings) != 0 || len(read) != len(anchors) {
+		t.Fatalf("expected %d anchors back, got %+v (warnings %v)", len(anchors), read, warnings)
+	}
+	near := func(a, b models.Vector3) bool {
+		return math.Abs(a.X-b.X) < 1e-5 && math.Abs(a.Y-b.Y) < 1e-5 && math.Abs(a.Z-b.Z) < 1e-5
+	}
+	for i, want := range anchors {
+		got := read[i]
+		if got.Name != want.Name || got.Label != want.Label || got.Direction != want.Direction || got.ConnectionAxis != want.ConnectionAxis ||
+			!reflect.DeepEqual(got.CompatibleTypes, want.CompatibleTypes) || !near(got.Position, want.Position) || !near(got.Rotation, want.Rotation) {
+			t.Errorf("anchor %d: got %+v, want %+v", i, got, want)
+		}
+	}
+}
+
+func TestWithAnchors_JSON(t *testing.T) {
+	out, err := gltf.WithAnchors([]byte(`{"asset":{"version":"2.0"},"nodes":[{"name":"Case"}]}`), models.AnchorPoints{
+		{Name: "psu_mount", Label: "PSU Mount", ConnectionAxis: "Y_NEG"},
+	})
+	if err != nil {
+		t.Fatalf("WithAnchors failed: %v", err)
+	}
+	if gltf.IsBinary(out) {
+		t.Error("expected glTF JSON for a glTF JSON model")
+	}
+
+	doc, err := gltf.Decode(out)
+	if err != nil {
+		t.Fatalf("Decode of the export failed: %v", err)
+	}
+	// A scene is created so the anchors are part of the model
+	if len(doc.Scenes) != 1 || !reflect.DeepEqual(doc.Scenes[0].Nodes, []int{0, 1}) || doc.Nodes[1].Name != "anchor_psu_mount" {
+		t.Errorf("unexpected export: %s", out)
+	}
+}
+
+// box is a model with one 8-vertex, 12-triangle mesh drawn twice
+const box = `{
+	"asset": {"version": "2.0"},
+	"scenes": [{"nodes": [0, 1]}],
+	"nodes": [
+		{"name": "Box", "mesh": 0},
+		{"name": "Big box", "mesh": 0, "translation": [10, 0, 0], "scale": [2, 2, 2]},
+		{"name": "Hidden", "mesh": 0, "translation": [100, 0, 0]}
+	],
+	"meshes": [{"primitives": [{"attributes": {"POSITION": 0}, "indices": 1, "material": 0}]}],
+	"accessors": [
+		{"bufferView": 0, "componentType": 5126, "count": 8, "type": "VEC3", "min": [-1, -1, -1], "max": [1, 1, 1]},
+		{"bufferView": 1, "componentType": 5123, "count": 36, "type": "SCALAR"}
+	],
+	"bufferViews": [{"buffer": 0, "byteLength": 96}, {"buffer": 0, "byteOffset": 96, "byteLength": 72}],
+	"buffers": [{"byteLength": 168}],
+	"materials": [{"name": "Steel"}],
+	"textures": [{"source": 0}],
+	"images": [{"uri": "steel.png"}]
+}`
+
+func TestDocument_Stats(t *testing.T) {
+	doc, err := gltf.Decode(glb(box, make([]byte, 168)))
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	stats := doc.Stats()
+	// Nodes outside the scene are not counted
+	if stats.Meshes != 2 || stats.Triangles != 24 || stats.Vertices != 16 {
+		t.Errorf("expected 2 meshes, 24 triangles and 16 vertices, got %+v", stats)
+	}
+	if stats.Materials != 1 || stats.Textures != 1 || stats.Images != 1 {
+		t.Errorf("expected 1 material, texture and image, got %+v", stats)
+	}
+	if stats.Min != [3]float64{-1, -2, -2} || stats.Max != [3]float64{12, 2, 2} {
+		t.Errorf("unexpected bounds %v - %v", stats.Min, stats.Max)
+	}
+	if size := stats.Size(); size != [3]float64{13, 4, 4} || stats.Empty {
+		t.Errorf("unexpected size %v", size)
+	}
+}
+
+func TestDecode_InvalidGeometry(t *testing.T) {
+	tests := map[string][2]string{
+		"missing BIN data":         {`"buffers": [{"byteLength": 168}]`, `"buffers": [{"byteLength": 4096}]`},
+		"view outside buffer":      {`{"buffer": 0, "byteOffset": 96, "byteLength": 72}`, `{"buffer": 0, "byteOffset": 120, "byteLength": 72}`},
+		"accessor outside view":    {`"count": 36`, `"count": 37`},
+		"huge accessor count":      {`"count": 36`, `"count": 4611686018427387904`},
+		"huge zero-filled count":   {`{"bufferView": 1, "componentType": 5123, "count": 36`, `{"componentType": 5123, "count": 4611686018427387904`},
+		"huge view offset":         {`"byteOffset": 96, "byteLength": 72`, `"byteOffset": 9223372036854775800, "byteLength": 72`},
+		"no position bounds":       {`"min": [-1, -1, -1], "max": [1, 1, 1]`, `"min": [-1, -1, -1]`},
+		"missing material":         {`"material": 0`, `"material": 3`},
+		"unknown component type":   {`"componentType": 5123`, `"componentType": 42`},
+		"texture without an image": {`"source": 0`, `"source": 2`},
+	}
+
+	for name, edit := range tests {
+		model := strings.Replace(box, edit[0], edit[1], 1)
+		if model == box {
+			t.Fatalf("%s: edit did not apply", name)
+		}
+		if _, err := gltf.Decode(glb(model, make([]byte, 168))); !errors.Is(err, gltf.ErrInvalidModel) {
+			t.Errorf("%s: expected ErrInvalidModel, got %v", name, err)
+		}
+	}
+}
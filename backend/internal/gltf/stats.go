@@ -0,0 +1,98 @@
+package gltf
+
+import "math"
+
+// Stats summarises the geometry of the default scene. Counts include every mesh
+// instance; Min and Max bound the scene in model units.
+type Stats struct {
+	Meshes     int        `json:"meshes"`
+	Primitives int        `json:"primitives"`
+	Triangles  int        `json:"triangles"`
+	Vertices   int        `json:"vertices"`
+	Materials  int        `json:"materials"`
+	Textures   int        `json:"textures"`
+	Images     int        `json:"images"`
+	Min        [3]float64 `json:"min"`
+	Max        [3]float64 `json:"max"`
+	Empty      bool       `json:"empty"` // No geometry in the scene; Min and Max are zero
+}
+
+// Size returns the extent of the bounding box along each axis
+func (s Stats) Size() [3]float64 {
+	return [3]float64{s.Max[0] - s.Min[0], s.Max[1] - s.Min[1], s.Max[2] - s.Min[2]}
+}
+
+// triangles returns the number of triangles drawn by a primitive
+func (d *Document) triangles(primitive Primitive) int {
+	count := d.Accessors[primitive.Attributes["POSITION"]].Count
+	if primitive.Indices != nil {
+		count = d.Accessors[*primitive.Indices].Count
+	}
+
+	mode := ModeTriangles
+	if primitive.Mode != nil {
+		mode = *primitive.Mode
+	}
+	switch mode {
+	case ModeTriangles:
+		return count / 3
+	case ModeTriangleStrip, ModeTriangleFan:
+		if count < 3 {
+			return 0
+		}
+		return count - 2
+	}
+	return 0 // points and lines
+}
+
+// Stats measures the default scene. The bounding box transforms the corners of
+// each POSITION accessor's bounds, so it may be slightly larger than the geometry
+// under rotation but never smaller.
+func (d *Document) Stats() Stats {
+	stats := Stats{
+		Materials: len(d.Materials),
+		Textures:  len(d.Textures),
+		Images:    len(d.Images),
+		Min:       [3]float64{math.Inf(1), math.Inf(1), math.Inf(1)},
+		Max:       [3]float64{math.Inf(-1), math.Inf(-1), math.Inf(-1)},
+	}
+
+	for index, m := range d.WorldTransforms() {
+		node := d.Nodes[index]
+		if node.Mesh == nil {
+			continue
+		}
+		stats.Meshes++
+		for _, primitive := range d.Meshes[*node.Mesh].Primitives {
+			stats.Primitives++
+			stats.Triangles += d.triangles(primitive)
+
+			positions := d.Accessors[primitive.Attributes["POSITION"]]
+			stats.Vertices += positions.Count
+			for corner := 0; corner < 8; corner++ {
+				var p [3]float64
+				for axis := 0; axis < 3; axis++ {
+					if corner&(1<<axis) == 0 {
+						p[axis] = positions.Min[axis]
+					} else {
+						p[axis] = positions.Max[axis]
+					}
+				}
+				for axis := 0; axis < 3; axis++ {
+					v := m.at(axis, 0)*p[0] + m.at(axis, 1)*p[1] + m.at(axis, 2)*p[2] + m.at(axis, 3)
+					stats.Min[axis] = math.Min(stats.Min[axis], v)
+					stats.Max[axis] = math.Max(stats.Max[axis], v)
+				}
+			}
+		}
+	}
+
+	if stats.Primitives == 0 {
+		stats.Empty = true
+		stats.Min, stats.Max = [3]float64{}, [3]float64{}
+	}
+	for axis := 0; axis < 3; axis++ {
+		stats.Min[axis], stats.Max[axis] = round(stats.Min[axis]), round(stats.Max[axis])
+	}
+	return stats
+}
@@ -0,0 +1,224 @@
+package gltf
+
+import "math"
+
+// Mat4 is a 4x4 matrix stored column-major, as in glTF: element (row r, column c)
+// is at index c*4+r
+type Mat4 [16]float64
+
+// Identity returns the identity matrix
+func Identity() Mat4 {
+	return Mat4{1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1}
+}
+
+func (m Mat4) at(row, col int) float64 {
+	return m[col*4+row]
+}
+
+// Mul returns m × n
+func (m Mat4) Mul(n Mat4) Mat4 {
+	var out Mat4
+	for col := 0; col < 4; col++ {
+		for row := 0; row < 4; row++ {
+			var sum float64
+			for k := 0; k < 4; k++ {
+				sum += m.at(row, k) * n.at(k, col)
+			}
+			out[col*4+row] = sum
+		}
+	}
+	return out
+}
+
+// Translation returns the translation part of m
+func (m Mat4) Translation() [3]float64 {
+	return [3]float64{m[12], m[13], m[14]}
+}
+
+// Rotation returns the rotation part of m as a 3x3 row-major matrix, with the
+// scale of each axis removed
+func (m Mat4) Rotation() [3][3]float64 {
+	var r [3][3]float64
+	for col := 0; col < 3; col++ {
+		length := math.Sqrt(m.at(0, col)*m.at(0, col) + m.at(1, col)*m.at(1, col) + m.at(2, col)*m.at(2, col))
+		if length == 0 {
+			length = 1
+		}
+		for row := 0; row < 3; row++ {
+			r[row][col] = m.at(row, col) / length
+		}
+	}
+	return r
+}
+
+// Transform returns the matrix rotating by r (row-major) and then translating by t
+func Transform(t [3]float64, r [3][3]float64) Mat4 {
+	m := Identity()
+	for row := 0; row < 3; row++ {
+		for col := 0; col < 3; col++ {
+			m[col*4+row] = r[row][col]
+		}
+		m[12+row] = t[row]
+	}
+	return m
+}
+
+// LocalTransform returns the node's transform relative to its parent
+func (n Node) LocalTransform() Mat4 {
+	if len(n.Matrix) == 16 {
+		var m Mat4
+		copy(m[:], n.Matrix)
+		return m
+	}
+
+	t := [3]float64{0, 0, 0}
+	q := [4]float64{0, 0, 0, 1}
+	s := [3]float64{1, 1, 1}
+	copy(t[:], n.Translation)
+	copy(q[:], n.Rotation)
+	copy(s[:], n.Scale)
+
+	// T × R × S, written out
+	r := quaternionMatrix(q)
+	m := Identity()
+	for row := 0; row < 3; row++ {
+		for col := 0; col < 3; col++ {
+			m[col*4+row] = r[row][col] * s[col]
+		}
+		m[12+row] = t[row]
+	}
+	return m
+}
+
+// quaternionMatrix converts a unit quaternion (x, y, z, w) to a rotation matrix
+func quaternionMatrix(q [4]float64) [3][3]float64 {
+	x, y, z, w := q[0], q[1], q[2], q[3]
+	if norm := math.Sqrt(x*x + y*y + z*z + w*w); norm > 0 {
+		x, y, z, w = x/norm, y/norm, z/norm, w/norm
+	}
+	return [3][3]float64{
+		{1 - 2*(y*y+z*z), 2 * (x*y - z*w), 2 * (x*z + y*w)},
+		{2 * (x*y + z*w), 1 - 2*(x*x+z*z), 2 * (y*z - x*w)},
+		{2 * (x*z - y*w), 2 * (y*z + x*w), 1 - 2*(x*x+y*y)},
+	}
+}
+
+// EulerXYZ decomposes a rotation matrix into intrinsic X, Y, Z angles in radians,
+// the default rotation order of three.js
+func EulerXYZ(r [3][3]float64) [3]float64 {
+	y := math.Asin(math.Max(-1, math.Min(1, r[0][2])))
+	if math.Abs(r[0][2]) < 0.9999999 {
+		return [3]float64{math.Atan2(-r[1][2], r[2][2]), y, math.Atan2(-r[0][1], r[0][0])}
+	}
+	// Gimbal lock: X and Z rotate about the same axis
+	return [3]float64{math.Atan2(r[2][1], r[1][1]), y, 0}
+}
+
+// EulerMatrix returns the rotation matrix of intrinsic X, Y, Z angles in radians,
+// the inverse of EulerXYZ
+func EulerMatrix(e [3]float64) [3][3]float64 {
+	a, b := math.Cos(e[0]), math.Sin(e[0])
+	c, d := math.Cos(e[1]), math.Sin(e[1])
+	f, g := math.Cos(e[2]), math.Sin(e[2])
+	return [3][3]float64{
+		{c * f, -c * g, d},
+		{a*g + b*f*d, a*f - b*g*d, -b * c},
+		{b*g - a*f*d, b*f + a*g*d, a * c},
+	}
+}
+
+// Quaternion converts a rotation matrix to a unit quaternion (x, y, z, w)
+func Quaternion(r [3][3]float64) [4]float64 {
+	var q [4]float64
+	switch trace := r[0][0] + r[1][1] + r[2][2]; {
+	case trace > 0:
+		s := 0.5 / math.Sqrt(trace+1)
+		q = [4]float64{(r[2][1] - r[1][2]) * s, (r[0][2] - r[2][0]) * s, (r[1][0] - r[0][1]) * s, 0.25 / s}
+	case r[0][0] > r[1][1] && r[0][0] > r[2][2]:
+		s := 2 * math.Sqrt(1+r[0][0]-r[1][1]-r[2][2])
+		q = [4]float64{0.25 * s, (r[0][1] + r[1][0]) / s, (r[0][2] + r[2][0]) / s, (r[2][1] - r[1][2]) / s}
+	case r[1][1] > r[2][2]:
+		s := 2 * math.Sqrt(1+r[1][1]-r[0][0]-r[2][2])
+		q = [4]float64{(r[0][1] + r[1][0]) / s, 0.25 * s, (r[1][2] + r[2][1]) / s, (r[0][2] - r[2][0]) / s}
+	default:
+		s := 2 * math.Sqrt(1+r[2][2]-r[0][0]-r[1][1])
+		q = [4]float64{(r[0][2] + r[2][0]) / s, (r[1][2] + r[2][1]) / s, 0.25 * s, (r[1][0] - r[0][1]) / s}
+	}
+	return q
+}
+
+// Connection axes, as stored on anchor points
+const (
+	AxisXPos = "X_POS"
+	AxisXNeg = "X_NEG"
+	AxisYPos = "Y_POS"
+	AxisYNeg = "Y_NEG"
+	AxisZPos = "Z_POS"
+	AxisZNeg = "Z_NEG"
+)
+
+// axisAlignments maps each connection axis to the rotation that turns the
+// default insertion direction (-Y) onto it
+var axisAlignments = map[string][3][3]float64{
+	AxisYNeg: {{1, 0, 0}, {0, 1, 0}, {0, 0, 1}},
+	AxisYPos: {{1, 0, 0}, {0, -1, 0}, {0, 0, -1}}, // 180° about X
+	AxisZPos: {{1, 0, 0}, {0, 0, 1}, {0, -1, 0}},  // -90° about X
+	AxisZNeg: {{1, 0, 0}, {0, 0, -1}, {0, 1, 0}},  // 90° about X
+	AxisXPos: {{0, -1, 0}, {1, 0, 0}, {0, 0, 1}},  // 90° about Z
+	AxisXNeg: {{0, 1, 0}, {-1, 0, 0}, {0, 0, 1}},  // -90° about Z
+}
+
+// ValidAxis reports whether axis is a known connection axis
+func ValidAxis(axis string) bool {
+	_, ok := axisAlignments[axis]
+	return ok
+}
+
+// DominantAxis returns the connection axis closest to the direction the rotation
+// turns -Y (the default insertion direction) into
+func DominantAxis(r [3][3]float64) string {
+	d := [3]float64{-r[0][1], -r[1][1], -r[2][1]}
+	names := [3][2]string{{AxisXPos, AxisXNeg}, {AxisYPos, AxisYNeg}, {AxisZPos, AxisZNeg}}
+
+	best := 1
+	for i := range d {
+		if math.Abs(d[i]) > math.Abs(d[best])+1e-9 {
+			best = i
+		}
+	}
+	if d[best] < 0 {
+		return names[best][1]
+	}
+	return names[best][0]
+}
+
+// AlignedRotation splits a rotation into the connection axis alignment and the
+// remaining rotation: r = remainder × alignment(axis). Rotating the axis arrow by
+// the remainder then points it where r points -Y.
+func AlignedRotation(r [3][3]float64, axis string) [3][3]float64 {
+	a := axisAlignments[axis]
+	var out [3][3]float64
+	for row := 0; row < 3; row++ {
+		for col := 0; col < 3; col++ {
+			// r × aᵀ, since the inverse of a rotation is its transpose
+			for k := 0; k < 3; k++ {
+				out[row][col] += r[row][k] * a[col][k]
+			}
+		}
+	}
+	return out
+}
+
+// UnalignedRotation is the inverse of AlignedRotation: it returns remainder × alignment(axis)
+func UnalignedRotation(remainder [3][3]float64, axis string) [3][3]float64 {
+	a := axisAlignments[axis]
+	var out [3][3]float64
+	for row := 0; row < 3; row++ {
+		for col := 0; col < 3; col++ {
+			for k := 0; k < 3; k++ {
+				out[row][col] += remainder[row][k] * a[k][col]
+			}
+		}
+	}
+	return out
+}
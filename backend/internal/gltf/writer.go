@@ -0,0 +1,278 @@
+package gltf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"fit-pc/models"
+)
+
+// unsafeNodeName matches the characters not allowed in anchor node names
+var unsafeNodeName = regexp.MustCompile(`[^a-z0-9_\-]+`)
+
+// AnchorNodeName returns the node name an anchor is exported under, following the
+// anchor_<label> convention read by Anchors
+func AnchorNodeName(anchor models.AnchorPoint) string {
+	label := anchor.Label
+	if label == "" {
+		label = anchor.Name
+	}
+	return "anchor_" + strings.Trim(unsafeNodeName.ReplaceAllString(strings.ToLower(label), "_"), "_")
+}
+
+// WithAnchors returns the model with its anchor nodes replaced by one node per
+// anchor point, in the same format (GLB or glTF JSON) as the input. Anchor nodes
+// are added as roots of the default scene with their transform and an "anchor"
+// object in their extras, so Anchors reads the same anchor points back. Every
+// other part of the model, including unknown properties and the binary buffer,
+// is kept as is.
+func WithAnchors(data []byte, anchors models.AnchorPoints) ([]byte, error) {
+	doc, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonChunk, binChunk := data, []byte(nil)
+	binaryModel := IsBinary(data)
+	if binaryModel {
+		if jsonChunk, binChunk, err = splitGLB(data); err != nil {
+			return nil, err
+		}
+	}
+
+	// Work on the generic document so properties this package does not model survive
+	var raw map[string]interface{}
+	if err := json.Unmarshal(jsonChunk, &raw); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidModel, err)
+	}
+
+	removed := make([]bool, len(doc.Nodes))
+	for i, node := range doc.Nodes {
+		if _, ok, err := nodeAnchor(node); ok || err != nil {
+			if len(node.Children) > 0 || node.Mesh != nil {
+				return nil, fmt.Errorf("%w: anchor node %q has children or a mesh", ErrInvalidModel, nodeLabel(node, i))
+			}
+			removed[i] = true
+		}
+	}
+	roots := doc.roots()
+	if err := removeNodes(raw, removed); err != nil {
+		return nil, err
+	}
+
+	nodes, _ := raw["nodes"].([]interface{})
+	sceneRoots := []interface{}{}
+	remap := remapping(removed)
+	for _, root := range roots {
+		if remap[root] >= 0 {
+			sceneRoots = append(sceneRoots, remap[root])
+		}
+	}
+	for _, anchor := range anchors {
+		sceneRoots = append(sceneRoots, len(nodes))
+		nodes = append(nodes, anchorNode(anchor))
+	}
+	raw["nodes"] = nodes
+
+	// Documents without scenes get one, so the anchors are part of the model
+	scenes, _ := raw["scenes"].([]interface{})
+	scene := 0
+	if doc.Scene != nil {
+		scene = *doc.Scene
+	}
+	if len(scenes) == 0 {
+		scenes = []interface{}{map[string]interface{}{}}
+		raw["scene"] = 0
+	}
+	defaultScene, ok := scenes[scene].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: scene %d is not an object", ErrInvalidModel, scene)
+	}
+	defaultScene["nodes"] = sceneRoots
+	raw["scenes"] = scenes
+
+	out, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	if binaryModel {
+		return EncodeGLB(out, binChunk), nil
+	}
+	return out, nil
+}
+
+// anchorNode returns the glTF node of an anchor point. The node rotation combines
+// the anchor rotation with the alignment of its connection axis.
+func anchorNode(anchor models.AnchorPoint) map[string]interface{} {
+	axis := anchor.ConnectionAxis
+	if !ValidAxis(axis) {
+		axis = AxisYNeg
+	}
+	q := Quaternion(AnchorRotation(anchor))
+
+	compatible := anchor.CompatibleTypes
+	if compatible == nil {
+		compatible = []string{}
+	}
+	return map[string]interface{}{
+		"name":        AnchorNodeName(anchor),
+		"translation": []float64{anchor.Position.X, anchor.Position.Y, anchor.Position.Z},
+		"rotation":    q[:],
+		"extras": map[string]interface{}{
+			"anchor": map[string]interface{}{
+				"type":             anchor.Name,
+				"label":            anchor.Label,
+				"direction":        anchor.Direction,
+				"connection_axis":  axis,
+				"compatible_types": compatible,
+			},
+		},
+	}
+}
+
+// remapping returns the new index of every node once the removed ones are dropped,
+// or -1 for removed nodes
+func remapping(removed []bool) []int {
+	remap := make([]int, len(removed))
+	next := 0
+	for i, gone := range removed {
+		if gone {
+			remap[i] = -1
+			continue
+		}
+		remap[i] = next
+		next++
+	}
+	return remap
+}
+
+// removeNodes drops nodes from a generic glTF document and renumbers every node
+// reference: scene roots, children, skins and animation targets
+func removeNodes(raw map[string]interface{}, removed []bool) error {
+	remap := remapping(removed)
+
+	// index returns the new index of a node reference, or -1 if it was removed
+	index := func(v interface{}) int {
+		f, ok := v.(float64)
+		if !ok || int(f) < 0 || int(f) >= len(remap) {
+			return -1
+		}
+		return remap[int(f)]
+	}
+	filter := func(list interface{}) []interface{} {
+		items, _ := list.([]interface{})
+		result := []interface{}{}
+		for _, item := range items {
+			if i := index(item); i >= 0 {
+				result = append(result, i)
+			}
+		}
+		return result
+	}
+
+	nodes, _ := raw["nodes"].([]interface{})
+	kept := []interface{}{}
+	for i, node := range nodes {
+		if removed[i] {
+			continue
+		}
+		if object, ok := node.(map[string]interface{}); ok && object["children"] != nil {
+			if children := filter(object["children"]); len(children) > 0 {
+				object["children"] = children
+			} else {
+				delete(object, "children")
+			}
+		}
+		kept = append(kept, node)
+	}
+	raw["nodes"] = kept
+
+	scenes, _ := raw["scenes"].([]interface{})
+	for _, scene := range scenes {
+		if object, ok := scene.(map[string]interface{}); ok && object["nodes"] != nil {
+			object["nodes"] = filter(object["nodes"])
+		}
+	}
+
+	skins, _ := raw["skins"].([]interface{})
+	for _, skin := range skins {
+		object, ok := skin.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		joints, _ := object["joints"].([]interface{})
+		for j, joint := range joints {
+			i := index(joint)
+			if i < 0 {
+				return fmt.Errorf("%w: an anchor node is a skin joint", ErrInvalidModel)
+			}
+			joints[j] = i
+		}
+		if skeleton, ok := object["skeleton"]; ok {
+			if i := index(skeleton); i >= 0 {
+				object["skeleton"] = i
+			} else {
+				delete(object, "skeleton")
+			}
+		}
+	}
+
+	// Animation channels targeting removed nodes are dropped with them
+	animations, _ := raw["animations"].([]interface{})
+	for _, animation := range animations {
+		object, ok := animation.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		channels, _ := object["channels"].([]interface{})
+		keptChannels := []interface{}{}
+		for _, channel := range channels {
+			fields, _ := channel.(map[string]interface{})
+			target, _ := fields["target"].(map[string]interface{})
+			if node, ok := target["node"]; ok {
+				i := index(node)
+				if i < 0 {
+					continue
+				}
+				target["node"] = i
+			}
+			keptChannels = append(keptChannels, channel)
+		}
+		object["channels"] = keptChannels
+	}
+	return nil
+}
+
+// EncodeGLB packs a glTF JSON document and an optional binary buffer into a GLB container
+func EncodeGLB(jsonChunk, binChunk []byte) []byte {
+	jsonPadded := append([]byte(nil), jsonChunk...)
+	for len(jsonPadded)%4 != 0 {
+		jsonPadded = append(jsonPadded, ' ')
+	}
+	length := glbHeaderSize + 8 + len(jsonPadded)
+
+	var binPadded []byte
+	if binChunk != nil {
+		binPadded = append([]byte(nil), binChunk...)
+		for len(binPadded)%4 != 0 {
+			binPadded = append(binPadded, 0)
+		}
+		length += 8 + len(binPadded)
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, length))
+	for _, word := range []uint32{glbMagic, glbVersion, uint32(length), uint32(len(jsonPadded)), chunkJSON} {
+		binary.Write(out, binary.LittleEndian, word)
+	}
+	out.Write(jsonPadded)
+	if binPadded != nil {
+		binary.Write(out, binary.LittleEndian, uint32(len(binPadded)))
+		binary.Write(out, binary.LittleEndian, uint32(chunkBIN))
+		out.Write(binPadded)
+	}
+	return out.Bytes()
+}
@@ -0,0 +1,114 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+)
+
+// exifOrientationTag is the IFD0 tag holding how a photo's pixels are rotated
+const exifOrientationTag = 0x0112
+
+// Orientation returns the EXIF orientation of a JPEG file, 1 to 8, or 1 when it
+// has none. Cameras store pixels as captured and record the rotation to apply.
+func Orientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		// Start of scan: the metadata segments are over
+		if marker == 0xDA {
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		end := i + 2 + length
+		if length < 2 || end > len(data) {
+			return 1
+		}
+		if segment := data[i+4 : end]; marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+		i = end
+	}
+	return 1
+}
+
+// tiffOrientation reads the orientation tag from the first IFD of an EXIF TIFF block
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for n := 0; n < entries; n++ {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == exifOrientationTag {
+			if o := int(order.Uint16(tiff[entry+8:])); o >= 1 && o <= 8 {
+				return o
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// orient returns the image as it should be displayed for an EXIF orientation
+func orient(img *image.RGBA, orientation int) *image.RGBA {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+
+	// source maps a pixel of the displayed image to the stored one
+	var source func(x, y int) (int, int)
+	dw, dh := w, h
+	switch orientation {
+	case 2: // Mirrored
+		source = func(x, y int) (int, int) { return w - 1 - x, y }
+	case 3: // Upside down
+		source = func(x, y int) (int, int) { return w - 1 - x, h - 1 - y }
+	case 4: // Upside down and mirrored
+		source = func(x, y int) (int, int) { return x, h - 1 - y }
+	case 5: // Transposed
+		dw, dh = h, w
+		source = func(x, y int) (int, int) { return y, x }
+	case 6: // Rotated 90° clockwise to display
+		dw, dh = h, w
+		source = func(x, y int) (int, int) { return y, h - 1 - x }
+	case 7: // Transversed
+		dw, dh = h, w
+		source = func(x, y int) (int, int) { return w - 1 - y, h - 1 - x }
+	case 8: // Rotated 90° counter-clockwise to display
+		dw, dh = h, w
+		source = func(x, y int) (int, int) { return w - 1 - y, x }
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			sx, sy := source(x, y)
+			copy(dst.Pix[y*dst.Stride+x*4:y*dst.Stride+x*4+4], img.Pix[sy*img.Stride+sx*4:])
+		}
+	}
+	return dst
+}
@@ -0,0 +1,198 @@
+// Package imaging produces the resized copies of uploaded product images served
+// in lists, cards and detail views. It decodes PNG and JPEG files, applies the
+// EXIF orientation of photos, scales them down with an area-averaging filter and
+// re-encodes them, which drops every metadata chunk of the original. Opaque
+// images are encoded as JPEG; images with transparency stay PNG.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+)
+
+// Size is a named bounding box images are scaled down to fit
+type Size struct {
+	Name      string
+	MaxWidth  int
+	MaxHeight int
+}
+
+// Sizes are the resized copies made of every uploaded image
+var Sizes = []Size{
+	{Name: "list", MaxWidth: 96, MaxHeight: 96},
+	{Name: "card", MaxWidth: 320, MaxHeight: 320},
+	{Name: "detail", MaxWidth: 1024, MaxHeight: 1024},
+}
+
+// MaxPixels bounds the images decoded, as a decoded image takes 4 bytes per pixel
+const MaxPixels = 40_000_000
+
+// jpegQuality is the quality resized JPEG copies are encoded with
+const jpegQuality = 85
+
+// ErrTooLarge is returned for images with more than MaxPixels pixels
+var ErrTooLarge = errors.New("image is too large to resize")
+
+// Variant is an encoded resized copy of an image
+type Variant struct {
+	Size        Size
+	Width       int
+	Height      int
+	ContentType string
+	Ext         string // File extension for the content type, with its dot
+	Data        []byte
+}
+
+// Decode decodes a PNG or JPEG file and applies its EXIF orientation
+func Decode(data []byte) (*image.RGBA, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("unsupported image: %w", err)
+	}
+	if config.Width*config.Height > MaxPixels {
+		return nil, ErrTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid image: %w", err)
+	}
+	rgba := image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+	return orient(rgba, Orientation(data)), nil
+}
+
+// Resize returns variants of an image file for each of the sizes. Images smaller
+// than a size are re-encoded at their own size, never scaled up.
+func Resize(data []byte, sizes []Size) ([]Variant, error) {
+	img, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	variants := make([]Variant, 0, len(sizes))
+	for _, size := range sizes {
+		width, height := Fit(img.Bounds().Dx(), img.Bounds().Dy(), size.MaxWidth, size.MaxHeight)
+		scaled := Scale(img, width, height)
+
+		variant := Variant{Size: size, Width: width, Height: height}
+		var buf bytes.Buffer
+		if err := Encode(&buf, scaled, &variant); err != nil {
+			return nil, err
+		}
+		variant.Data = buf.Bytes()
+		variants = append(variants, variant)
+	}
+	return variants, nil
+}
+
+// Encode writes an image as JPEG, or as PNG if it has transparency, and records
+// the format on the variant
+func Encode(w io.Writer, img *image.RGBA, variant *Variant) error {
+	if img.Opaque() {
+		variant.ContentType, variant.Ext = "image/jpeg", ".jpg"
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: jpegQuality})
+	}
+	variant.ContentType, variant.Ext = "image/png", ".png"
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	return encoder.Encode(w, img)
+}
+
+// Fit returns the dimensions of a width x height image scaled down to fit in
+// maxWidth x maxHeight, keeping its aspect ratio
+func Fit(width, height, maxWidth, maxHeight int) (int, int) {
+	if width <= maxWidth && height <= maxHeight {
+		return width, height
+	}
+	if width*maxHeight > height*maxWidth {
+		return maxWidth, max(1, (height*maxWidth+width/2)/width)
+	}
+	return max(1, (width*maxHeight+height/2)/height), maxHeight
+}
+
+// contribution is the weight of a source pixel in a destination pixel
+type contribution struct {
+	index  int
+	weight float32
+}
+
+// areaWeights returns, for each of dst pixels along an axis, the source pixels it
+// covers and the fraction of each it covers, normalised to sum to 1
+func areaWeights(src, dst int) [][]contribution {
+	scale := float64(src) / float64(dst)
+	weights := make([][]contribution, dst)
+	for i := range weights {
+		start, end := float64(i)*scale, float64(i+1)*scale
+		for j := int(start); j < src && float64(j) < end; j++ {
+			covered := min(end, float64(j+1)) - max(start, float64(j))
+			if covered > 0 {
+				weights[i] = append(weights[i], contribution{j, float32(covered / scale)})
+			}
+		}
+	}
+	return weights
+}
+
+// Scale resizes an image to width x height by averaging the source pixels each
+// destination pixel covers. Colours are premultiplied by alpha, so transparent
+// pixels do not bleed into their neighbours.
+func Scale(img *image.RGBA, width, height int) *image.RGBA {
+	srcWidth, srcHeight := img.Bounds().Dx(), img.Bounds().Dy()
+	if srcWidth == width && srcHeight == height {
+		return img
+	}
+	columns, rows := areaWeights(srcWidth, width), areaWeights(srcHeight, height)
+
+	// Horizontal pass into a float buffer of width x srcHeight
+	tmp := make([]float32, width*srcHeight*4)
+	for y := 0; y < srcHeight; y++ {
+		row := img.Pix[y*img.Stride:]
+		for x, column := range columns {
+			var r, g, b, a float32
+			for _, c := range column {
+				p := row[c.index*4:]
+				r += float32(p[0]) * c.weight
+				g += float32(p[1]) * c.weight
+				b += float32(p[2]) * c.weight
+				a += float32(p[3]) * c.weight
+			}
+			t := tmp[(y*width+x)*4:]
+			t[0], t[1], t[2], t[3] = r, g, b, a
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y, row := range rows {
+		for x := 0; x < width; x++ {
+			var r, g, b, a float32
+			for _, c := range row {
+				t := tmp[(c.index*width+x)*4:]
+				r += t[0] * c.weight
+				g += t[1] * c.weight
+				b += t[2] * c.weight
+				a += t[3] * c.weight
+			}
+			p := dst.Pix[y*dst.Stride+x*4:]
+			p[0], p[1], p[2], p[3] = clamp(r), clamp(g), clamp(b), clamp(a)
+		}
+	}
+	return dst
+}
+
+// clamp rounds a channel value to a byte
+func clamp(v float32) uint8 {
+	switch {
+	case v <= 0:
+		return 0
+	case v >= 255:
+		return 255
+	default:
+		return uint8(v + 0.5)
+	}
+}
@@ -0,0 +1,138 @@
+package imaging_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"fit-pc/internal/imaging"
+)
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func filled(width, height int, c color.Color) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		width, height, maxWidth, maxHeight int
+		wantWidth, wantHeight              int
+	}{
+		{4000, 2000, 320, 320, 320, 160},
+		{1000, 3000, 320, 320, 107, 320},
+		{200, 100, 320, 320, 200, 100}, // Never scaled up
+		{5000, 1, 96, 96, 96, 1},
+	}
+	for _, tt := range tests {
+		w, h := imaging.Fit(tt.width, tt.height, tt.maxWidth, tt.maxHeight)
+		if w != tt.wantWidth || h != tt.wantHeight {
+			t.Errorf("Fit(%dx%d in %dx%d) = %dx%d, want %dx%d", tt.width, tt.height, tt.maxWidth, tt.maxHeight, w, h, tt.wantWidth, tt.wantHeight)
+		}
+	}
+}
+
+func TestScale_AveragesCoveredPixels(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 1))
+	img.Set(0, 0, color.White)
+	img.Set(1, 0, color.White)
+	img.Set(2, 0, color.Black)
+	img.Set(3, 0, color.White)
+
+	scaled := imaging.Scale(img, 2, 1)
+	if got := scaled.RGBAAt(0, 0); got.R != 255 {
+		t.Errorf("left pixel = %v, want white", got)
+	}
+	if got := scaled.RGBAAt(1, 0); got.R != 128 || got.A != 255 {
+		t.Errorf("right pixel = %v, want mid grey", got)
+	}
+}
+
+func TestResize(t *testing.T) {
+	variants, err := imaging.Resize(encodePNG(t, filled(400, 200, color.NRGBA{200, 10, 10, 255})), imaging.Sizes)
+	if err != nil {
+		t.Fatalf("Resize: %v", err)
+	}
+	want := map[string][2]int{"list": {96, 48}, "card": {320, 160}, "detail": {400, 200}}
+	for _, v := range variants {
+		if dims := want[v.Size.Name]; v.Width != dims[0] || v.Height != dims[1] {
+			t.Errorf("%s = %dx%d, want %dx%d", v.Size.Name, v.Width, v.Height, dims[0], dims[1])
+		}
+		// Opaque images are re-encoded as JPEG
+		if v.ContentType != "image/jpeg" || v.Ext != ".jpg" {
+			t.Errorf("%s encoded as %s", v.Size.Name, v.ContentType)
+		}
+		img, err := jpeg.Decode(bytes.NewReader(v.Data))
+		if err != nil || img.Bounds().Dx() != v.Width {
+			t.Errorf("%s is not a valid JPEG: %v", v.Size.Name, err)
+		}
+	}
+
+	// Transparency is kept
+	variants, err = imaging.Resize(encodePNG(t, filled(300, 300, color.NRGBA{0, 0, 0, 0})), imaging.Sizes[:1])
+	if err != nil {
+		t.Fatalf("Resize: %v", err)
+	}
+	if variants[0].ContentType != "image/png" {
+		t.Errorf("transparent image encoded as %s", variants[0].ContentType)
+	}
+
+	if _, err := imaging.Resize([]byte("not an image"), imaging.Sizes); err == nil {
+		t.Error("expected an error for invalid data")
+	}
+}
+
+// withOrientation inserts an EXIF segment with an orientation into a JPEG file
+func withOrientation(data []byte, orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01")
+	entry := make([]byte, 12)
+	binary.BigEndian.PutUint16(entry, 0x0112)
+	binary.BigEndian.PutUint16(entry[2:], 3) // SHORT
+	binary.BigEndian.PutUint32(entry[4:], 1)
+	binary.BigEndian.PutUint16(entry[8:], orientation)
+	payload := append(append([]byte("Exif\x00\x00"), tiff...), append(entry, 0, 0, 0, 0)...)
+
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+	return append(append([]byte{0xFF, 0xD8}, segment...), data[2:]...)
+}
+
+func TestDecode_AppliesOrientation(t *testing.T) {
+	// A landscape photo taken with the camera held upright
+	var buf bytes.Buffer
+	jpeg.Encode(&buf, filled(40, 20, color.White), nil)
+
+	rotated := withOrientation(buf.Bytes(), 6)
+	if o := imaging.Orientation(rotated); o != 6 {
+		t.Fatalf("Orientation = %d, want 6", o)
+	}
+	img, err := imaging.Decode(rotated)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if img.Bounds().Dx() != 20 || img.Bounds().Dy() != 40 {
+		t.Errorf("decoded %v, want 20x40", img.Bounds())
+	}
+
+	if o := imaging.Orientation(buf.Bytes()); o != 1 {
+		t.Errorf("Orientation without EXIF = %d, want 1", o)
+	}
+}
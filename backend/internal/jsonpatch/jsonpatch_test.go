@@ -0,0 +1,158 @@
+package jsonpatch_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"fit-pc/internal/jsonpatch"
+)
+
+func decode(t *testing.T, s string) interface{} {
+	t.Helper()
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatalf("invalid JSON %s: %v", s, err)
+	}
+	return v
+}
+
+func TestMergePatch(t *testing.T) {
+	// Examples from RFC 7386 Appendix A
+	tests := []struct {
+		target, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+
+	for _, tt := range tests {
+		got := jsonpatch.MergePatch(decode(t, tt.target), decode(t, tt.patch))
+		if want := decode(t, tt.want); !reflect.DeepEqual(got, want) {
+			t.Errorf("MergePatch(%s, %s) = %v, want %v", tt.target, tt.patch, got, want)
+		}
+	}
+}
+
+func TestMergePatch_DoesNotModifyTarget(t *testing.T) {
+	target := decode(t, `{"specs":{"socket":"AM5","tdp":105}}`)
+	jsonpatch.MergePatch(target, decode(t, `{"specs":{"tdp":null}}`))
+
+	if want := decode(t, `{"specs":{"socket":"AM5","tdp":105}}`); !reflect.DeepEqual(target, want) {
+		t.Errorf("target was modified: %v", target)
+	}
+}
+
+func TestPatch_Apply(t *testing.T) {
+	// Examples from RFC 6902 Appendix A
+	tests := []struct {
+		name, doc, patch, want string
+	}{
+		{"add member", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{"add array element", `{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{"remove member", `{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{"remove array element", `{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{"replace", `{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{"move member", `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{"move array element", `{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{"test and add", `{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`, `{"baz":"qux","foo":["a",2,"c"]}`},
+		{"add nested object", `{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"foo":"bar","child":{"grandchild":{}}}`},
+		{"escaped pointer", `{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10}]`, `{"/":9,"~1":10}`},
+		{"append to array", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`},
+		{"null value", `{"foo":1}`, `[{"op":"replace","path":"/foo","value":null}]`, `{"foo":null}`},
+		{"copy", `{"a":{"b":1}}`, `[{"op":"copy","from":"/a","path":"/c"},{"op":"replace","path":"/c/b","value":2}]`, `{"a":{"b":1},"c":{"b":2}}`},
+		{"replace root", `{"a":1}`, `[{"op":"replace","path":"","value":[1]}]`, `[1]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, err := jsonpatch.DecodePatch([]byte(tt.patch))
+			if err != nil {
+				t.Fatalf("DecodePatch failed: %v", err)
+			}
+			got, err := patch.Apply(decode(t, tt.doc))
+			if err != nil {
+				t.Fatalf("Apply failed: %v", err)
+			}
+			if want := decode(t, tt.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestPatch_ApplyErrors(t *testing.T) {
+	tests := []struct {
+		name, doc, patch string
+	}{
+		{"remove missing member", `{"foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`},
+		{"add to missing parent", `{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`},
+		{"index out of bounds", `{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/3","value":"qux"}]`},
+		{"leading zero index", `{"foo":["bar","baz"]}`, `[{"op":"replace","path":"/foo/01","value":"qux"}]`},
+		{"replace missing member", `{"foo":"bar"}`, `[{"op":"replace","path":"/baz","value":1}]`},
+		{"move into own child", `{"a":{"b":{}}}`, `[{"op":"move","from":"/a","path":"/a/b/c"}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, err := jsonpatch.DecodePatch([]byte(tt.patch))
+			if err != nil {
+				t.Fatalf("DecodePatch failed: %v", err)
+			}
+			if _, err := patch.Apply(decode(t, tt.doc)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestPatch_ApplyIsAtomic(t *testing.T) {
+	doc := decode(t, `{"specs":{"tdp":65}}`)
+	patch, _ := jsonpatch.DecodePatch([]byte(`[{"op":"replace","path":"/specs/tdp","value":105},{"op":"remove","path":"/missing"}]`))
+
+	if _, err := patch.Apply(doc); err == nil {
+		t.Fatal("expected an error")
+	}
+	if want := decode(t, `{"specs":{"tdp":65}}`); !reflect.DeepEqual(doc, want) {
+		t.Errorf("document was modified by a failed patch: %v", doc)
+	}
+}
+
+func TestPatch_TestFailed(t *testing.T) {
+	patch, _ := jsonpatch.DecodePatch([]byte(`[{"op":"test","path":"/baz","value":"bar"}]`))
+
+	_, err := patch.Apply(decode(t, `{"baz":"qux"}`))
+	if !errors.Is(err, jsonpatch.ErrTestFailed) {
+		t.Errorf("expected ErrTestFailed, got %v", err)
+	}
+}
+
+func TestDecodePatch_Invalid(t *testing.T) {
+	tests := []string{
+		`{"op":"add"}`,
+		`[{"op":"add","path":"/a"}]`,
+		`[{"op":"frobnicate","path":"/a"}]`,
+		`[{"op":"move","path":"/a"}]`,
+		`[{"op":"remove","path":"a"}]`,
+		`[{"op":"remove","path":"/a~2"}]`,
+		`[{"path":"/a"}]`,
+	}
+
+	for _, patch := range tests {
+		if _, err := jsonpatch.DecodePatch([]byte(patch)); !errors.Is(err, jsonpatch.ErrInvalidPatch) {
+			t.Errorf("DecodePatch(%s): expected ErrInvalidPatch, got %v", patch, err)
+		}
+	}
+}
@@ -0,0 +1,378 @@
+// Package render draws preview images of 3D models on the CPU, for servers
+// without a GPU. Triangles are rasterised into a depth buffer with flat shading
+// from a fixed three-quarter camera angle, framed to fit the whole scene, and
+// supersampled for smooth edges. Blended materials are drawn after opaque ones
+// without writing depth, so glass panels show what is behind them.
+package render
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"fit-pc/internal/gltf"
+)
+
+// Projection is how the camera projects the scene
+type Projection string
+
+// Supported projections
+const (
+	Perspective  Projection = "perspective"
+	Orthographic Projection = "orthographic"
+)
+
+// ParseProjection returns the projection named s, defaulting to perspective
+func ParseProjection(s string) (Projection, error) {
+	switch Projection(s) {
+	case "", Perspective:
+		return Perspective, nil
+	case Orthographic:
+		return Orthographic, nil
+	}
+	return "", fmt.Errorf("unknown projection %q: use %s or %s", s, Perspective, Orthographic)
+}
+
+// Camera placement: looking down at the front-right of the scene, with Y up and
+// +Z towards the viewer as in glTF
+const (
+	cameraYaw   = 35 * math.Pi / 180
+	cameraPitch = 25 * math.Pi / 180
+	fieldOfView = 30 * math.Pi / 180 // Vertical, for perspective
+	margin      = 0.06               // Share of the image left empty on each side
+	supersample = 2                  // Samples per pixel along each axis
+)
+
+// Lighting: a key light above and to the left of the camera, a headlight so no
+// face is black, and ambient light
+const (
+	ambientLight = 0.25
+	keyLight     = 0.6
+	headLight    = 0.15
+)
+
+// ErrEmptyScene is returned when there is nothing to draw
+var ErrEmptyScene = errors.New("scene has no geometry")
+
+// Options configures a rendering
+type Options struct {
+	Width      int
+	Height     int
+	Projection Projection
+	Background color.NRGBA // Transparent when zero
+}
+
+// Scene is the set of world-space triangles to draw
+type Scene struct {
+	triangles []gltf.Triangle
+}
+
+// Add adds triangles to the scene, transformed by transform
+func (s *Scene) Add(triangles []gltf.Triangle, transform gltf.Mat4) {
+	for _, t := range triangles {
+		for i := range t.Vertices {
+			t.Vertices[i] = transform.Apply(t.Vertices[i])
+		}
+		s.triangles = append(s.triangles, t)
+	}
+}
+
+// Len returns the number of triangles in the scene
+func (s *Scene) Len() int {
+	return len(s.triangles)
+}
+
+type vec3 [3]float64
+
+func sub(a, b vec3) vec3           { return vec3{a[0] - b[0], a[1] - b[1], a[2] - b[2]} }
+func dot(a, b vec3) float64        { return a[0]*b[0] + a[1]*b[1] + a[2]*b[2] }
+func scale(a vec3, k float64) vec3 { return vec3{a[0] * k, a[1] * k, a[2] * k} }
+func add(a, b vec3) vec3           { return vec3{a[0] + b[0], a[1] + b[1], a[2] + b[2]} }
+func cross(a, b vec3) vec3 {
+	return vec3{a[1]*b[2] - a[2]*b[1], a[2]*b[0] - a[0]*b[2], a[0]*b[1] - a[1]*b[0]}
+}
+func normalize(a vec3) vec3 {
+	if l := math.Sqrt(dot(a, a)); l > 0 {
+		return scale(a, 1/l)
+	}
+	return a
+}
+
+// camera projects world-space points to the supersampled image
+type camera struct {
+	projection Projection
+	width      float64
+	height     float64
+	toCamera   vec3 // Unit vector from the scene towards the camera
+	right, up  vec3
+	eye        vec3    // Perspective
+	focal      float64 // Perspective: pixels per unit at distance 1
+	center     vec3    // Orthographic: point at the middle of the image
+	scale      float64 // Orthographic: pixels per unit
+}
+
+// newCamera frames the scene in a width x height image
+func newCamera(triangles []gltf.Triangle, projection Projection, width, height int) (*camera, error) {
+	toCamera := vec3{math.Sin(cameraYaw) * math.Cos(cameraPitch), math.Sin(cameraPitch), math.Cos(cameraYaw) * math.Cos(cameraPitch)}
+	right := normalize(cross(vec3{0, 1, 0}, toCamera))
+	c := &camera{
+		projection: projection,
+		width:      float64(width),
+		height:     float64(height),
+		toCamera:   toCamera,
+		right:      right,
+		up:         cross(toCamera, right),
+	}
+
+	// Bounds in world space and in the camera plane
+	lo, hi := vec3{math.Inf(1), math.Inf(1), math.Inf(1)}, vec3{math.Inf(-1), math.Inf(-1), math.Inf(-1)}
+	minX, maxX, minY, maxY := math.Inf(1), math.Inf(-1), math.Inf(1), math.Inf(-1)
+	for _, t := range triangles {
+		for _, v := range t.Vertices {
+			for axis := 0; axis < 3; axis++ {
+				lo[axis], hi[axis] = math.Min(lo[axis], v[axis]), math.Max(hi[axis], v[axis])
+			}
+			x, y := dot(v, c.right), dot(v, c.up)
+			minX, maxX, minY, maxY = math.Min(minX, x), math.Max(maxX, x), math.Min(minY, y), math.Max(maxY, y)
+		}
+	}
+	usable := 1 - 2*margin
+
+	if projection == Orthographic {
+		spanX, spanY := maxX-minX, maxY-minY
+		if spanX <= 0 && spanY <= 0 {
+			return nil, ErrEmptyScene
+		}
+		c.scale = math.Inf(1)
+		if spanX > 0 {
+			c.scale = c.width * usable / spanX
+		}
+		if spanY > 0 {
+			c.scale = math.Min(c.scale, c.height*usable/spanY)
+		}
+		c.center = add(scale(c.right, (minX+maxX)/2), scale(c.up, (minY+maxY)/2))
+		return c, nil
+	}
+
+	// Perspective: back off until the bounding sphere fits the field of view
+	center := scale(add(lo, hi), 0.5)
+	var radius float64
+	for _, t := range triangles {
+		for _, v := range t.Vertices {
+			d := sub(v, center)
+			radius = math.Max(radius, math.Sqrt(dot(d, d)))
+		}
+	}
+	if radius == 0 {
+		return nil, ErrEmptyScene
+	}
+	c.eye = add(center, scale(toCamera, radius/math.Sin(fieldOfView/2)))
+	c.focal = math.Min(c.width, c.height) / 2 * usable / math.Tan(fieldOfView/2)
+	return c, nil
+}
+
+// project returns the image position of a point and its closeness to the camera:
+// a value that grows towards the camera and interpolates linearly across the image
+func (c *camera) project(p vec3) (x, y, closeness float64, ok bool) {
+	if c.projection == Orthographic {
+		v := sub(p, c.center)
+		return c.width/2 + dot(v, c.right)*c.scale, c.height/2 - dot(v, c.up)*c.scale, dot(v, c.toCamera), true
+	}
+	v := sub(p, c.eye)
+	distance := -dot(v, c.toCamera)
+	if distance <= 1e-9 {
+		return 0, 0, 0, false
+	}
+	return c.width/2 + c.focal*dot(v, c.right)/distance, c.height/2 - c.focal*dot(v, c.up)/distance, 1 / distance, true
+}
+
+// viewDirection returns the unit vector from a point towards the camera
+func (c *camera) viewDirection(p vec3) vec3 {
+	if c.projection == Orthographic {
+		return c.toCamera
+	}
+	return normalize(sub(c.eye, p))
+}
+
+// shade returns the lit colour of a triangle, in linear light
+func (c *camera) shade(t gltf.Triangle) [4]float64 {
+	a, b, d := vec3(t.Vertices[0]), vec3(t.Vertices[1]), vec3(t.Vertices[2])
+	normal := normalize(cross(sub(b, a), sub(d, a)))
+	view := c.viewDirection(scale(add(add(a, b), d), 1.0/3))
+	// Faces are lit from whichever side is visible
+	if dot(normal, view) < 0 {
+		normal = scale(normal, -1)
+	}
+	light := normalize(add(add(scale(c.right, -0.35), scale(c.up, 0.6)), scale(c.toCamera, 0.7)))
+
+	intensity := ambientLight + keyLight*math.Max(0, dot(normal, light)) + headLight*math.Abs(dot(normal, view))
+	intensity = math.Min(intensity, 1)
+	return [4]float64{t.Color[0] * intensity, t.Color[1] * intensity, t.Color[2] * intensity, t.Color[3]}
+}
+
+// canvas holds the supersampled image as premultiplied linear colour and depth
+type canvas struct {
+	width, height int
+	color         []float32 // RGBA per sample
+	depth         []float32 // Closeness per sample
+}
+
+// draw rasterises a triangle. Opaque triangles write depth; translucent ones are
+// blended over what is in front of them without hiding what comes after.
+func (cv *canvas) draw(cam *camera, t gltf.Triangle) {
+	var xs, ys, zs [3]float64
+	for i, v := range t.Vertices {
+		x, y, z, ok := cam.project(v)
+		if !ok {
+			return // Behind the camera
+		}
+		xs[i], ys[i], zs[i] = x, y, z
+	}
+	area := (xs[1]-xs[0])*(ys[2]-ys[0]) - (ys[1]-ys[0])*(xs[2]-xs[0])
+	if math.Abs(area) < 1e-12 {
+		return
+	}
+
+	shaded := cam.shade(t)
+	alpha := float32(shaded[3])
+	opaque := alpha >= 1
+	premultiplied := [4]float32{float32(shaded[0]) * alpha, float32(shaded[1]) * alpha, float32(shaded[2]) * alpha, alpha}
+
+	minX := max(0, int(math.Floor(min(xs[0], xs[1], xs[2]))))
+	maxX := min(cv.width-1, int(math.Ceil(max(xs[0], xs[1], xs[2]))))
+	minY := max(0, int(math.Floor(min(ys[0], ys[1], ys[2]))))
+	maxY := min(cv.height-1, int(math.Ceil(max(ys[0], ys[1], ys[2]))))
+
+	for py := minY; py <= maxY; py++ {
+		sy := float64(py) + 0.5
+		for px := minX; px <= maxX; px++ {
+			sx := float64(px) + 0.5
+			// Barycentric weights, positive inside whatever the winding
+			w0 := ((xs[2]-xs[1])*(sy-ys[1]) - (ys[2]-ys[1])*(sx-xs[1])) / area
+			w1 := ((xs[0]-xs[2])*(sy-ys[2]) - (ys[0]-ys[2])*(sx-xs[2])) / area
+			w2 := 1 - w0 - w1
+			if w0 < 0 || w1 < 0 || w2 < 0 {
+				continue
+			}
+
+			i := py*cv.width + px
+			z := float32(w0*zs[0] + w1*zs[1] + w2*zs[2])
+			if z <= cv.depth[i] {
+				continue
+			}
+			pixel := cv.color[i*4 : i*4+4]
+			if opaque {
+				cv.depth[i] = z
+				copy(pixel, premultiplied[:])
+				continue
+			}
+			for c := range pixel {
+				pixel[c] = premultiplied[c] + pixel[c]*(1-alpha)
+			}
+		}
+	}
+}
+
+// Render draws the scene. The image is transparent where nothing is drawn unless
+// a background colour is set.
+func Render(scene *Scene, options Options) (*image.RGBA, error) {
+	if scene.Len() == 0 {
+		return nil, ErrEmptyScene
+	}
+	if options.Width < 1 || options.Height < 1 {
+		return nil, fmt.Errorf("invalid image size %dx%d", options.Width, options.Height)
+	}
+	projection, err := ParseProjection(string(options.Projection))
+	if err != nil {
+		return nil, err
+	}
+
+	width, height := options.Width*supersample, options.Height*supersample
+	cam, err := newCamera(scene.triangles, projection, width, height)
+	if err != nil {
+		return nil, err
+	}
+
+	cv := &canvas{width: width, height: height, color: make([]float32, width*height*4), depth: make([]float32, width*height)}
+	background := [4]float32{}
+	if options.Background.A > 0 {
+		a := float32(options.Background.A) / 255
+		background = [4]float32{
+			float32(srgbToLinear(float64(options.Background.R)/255)) * a,
+			float32(srgbToLinear(float64(options.Background.G)/255)) * a,
+			float32(srgbToLinear(float64(options.Background.B)/255)) * a,
+			a,
+		}
+	}
+	for i := range cv.depth {
+		cv.depth[i] = float32(math.Inf(-1))
+		copy(cv.color[i*4:], background[:])
+	}
+
+	for _, t := range scene.triangles {
+		if t.Color[3] >= 1 {
+			cv.draw(cam, t)
+		}
+	}
+	for _, t := range scene.triangles {
+		if t.Color[3] < 1 {
+			cv.draw(cam, t)
+		}
+	}
+	return cv.resolve(options.Width, options.Height), nil
+}
+
+// resolve averages the samples of each pixel and encodes them as sRGB
+func (cv *canvas) resolve(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	const samples = supersample * supersample
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var sum [4]float32
+			for sy := 0; sy < supersample; sy++ {
+				row := (y*supersample + sy) * cv.width
+				for sx := 0; sx < supersample; sx++ {
+					sample := cv.color[(row+x*supersample+sx)*4:]
+					for c := range sum {
+						sum[c] += sample[c]
+					}
+				}
+			}
+			alpha := float64(sum[3]) / samples
+			pixel := img.Pix[y*img.Stride+x*4:]
+			if alpha <= 0 {
+				continue
+			}
+			for c := 0; c < 3; c++ {
+				// Encode the unpremultiplied colour, then premultiply again
+				linear := float64(sum[c]) / samples / alpha
+				pixel[c] = uint8(math.Round(linearToSRGB(linear) * alpha * 255))
+			}
+			pixel[3] = uint8(math.Round(math.Min(alpha, 1) * 255))
+		}
+	}
+	return img
+}
+
+// srgbToLinear converts an sRGB encoded channel to linear light
+func srgbToLinear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// linearToSRGB encodes a linear channel as sRGB, clamped to [0, 1]
+func linearToSRGB(v float64) float64 {
+	switch {
+	case v <= 0:
+		return 0
+	case v >= 1:
+		return 1
+	case v <= 0.0031308:
+		return v * 12.92
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
@@ -0,0 +1,111 @@
+package render_test
+
+import (
+	"errors"
+	"image/color"
+	"testing"
+
+	"fit-pc/internal/gltf"
+	"fit-pc/internal/render"
+)
+
+// cube returns the 12 triangles of an axis-aligned cube of the given colour
+func cube(size float64, rgba [4]float64) []gltf.Triangle {
+	s := size / 2
+	corner := func(i int) [3]float64 {
+		p := [3]float64{-s, -s, -s}
+		for axis := 0; axis < 3; axis++ {
+			if i&(1<<axis) != 0 {
+				p[axis] = s
+			}
+		}
+		return p
+	}
+	faces := [][4]int{{0, 1, 3, 2}, {4, 5, 7, 6}, {0, 1, 5, 4}, {2, 3, 7, 6}, {0, 2, 6, 4}, {1, 3, 7, 5}}
+	var triangles []gltf.Triangle
+	for _, f := range faces {
+		triangles = append(triangles,
+			gltf.Triangle{Vertices: [3][3]float64{corner(f[0]), corner(f[1]), corner(f[2])}, Color: rgba},
+			gltf.Triangle{Vertices: [3][3]float64{corner(f[0]), corner(f[2]), corner(f[3])}, Color: rgba},
+		)
+	}
+	return triangles
+}
+
+func TestParseProjection(t *testing.T) {
+	for input, want := range map[string]render.Projection{"": render.Perspective, "perspective": render.Perspective, "orthographic": render.Orthographic} {
+		got, err := render.ParseProjection(input)
+		if err != nil || got != want {
+			t.Errorf("ParseProjection(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+	if _, err := render.ParseProjection("fisheye"); err == nil {
+		t.Error("expected an error for an unknown projection")
+	}
+}
+
+func TestRender(t *testing.T) {
+	for _, projection := range []render.Projection{render.Perspective, render.Orthographic} {
+		t.Run(string(projection), func(t *testing.T) {
+			var scene render.Scene
+			// Far from the origin: the camera frames the scene wherever it is
+			scene.Add(cube(2, [4]float64{1, 0, 0, 1}), gltf.Transform([3]float64{100, 50, -20}, [3][3]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}))
+
+			img, err := render.Render(&scene, render.Options{Width: 64, Height: 48, Projection: projection})
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			if img.Bounds().Dx() != 64 || img.Bounds().Dy() != 48 {
+				t.Fatalf("unexpected size %v", img.Bounds())
+			}
+
+			center := img.RGBAAt(32, 24)
+			if center.A != 255 || center.R == 0 || center.G != 0 || center.B != 0 {
+				t.Errorf("expected a shaded red centre, got %v", center)
+			}
+			if corner := img.RGBAAt(0, 0); corner.A != 0 {
+				t.Errorf("expected a transparent corner, got %v", corner)
+			}
+		})
+	}
+}
+
+func TestRender_Background(t *testing.T) {
+	var scene render.Scene
+	scene.Add(cube(1, [4]float64{0, 0, 1, 1}), gltf.Identity())
+	white := color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+
+	img, err := render.Render(&scene, render.Options{Width: 32, Height: 32, Background: white})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if corner := img.RGBAAt(0, 0); corner != (color.RGBA{255, 255, 255, 255}) {
+		t.Errorf("expected the background colour, got %v", corner)
+	}
+}
+
+func TestRender_Translucent(t *testing.T) {
+	var scene render.Scene
+	scene.Add(cube(1, [4]float64{0, 1, 0, 1}), gltf.Identity())
+	// A glass cube around the green one, added first: it must not hide it
+	scene.Add(cube(2, [4]float64{1, 1, 1, 0.3}), gltf.Identity())
+
+	img, err := render.Render(&scene, render.Options{Width: 32, Height: 32, Projection: render.Orthographic})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	center := img.RGBAAt(16, 16)
+	if center.G <= center.R || center.G <= center.B {
+		t.Errorf("expected the opaque cube to show through the glass, got %v", center)
+	}
+	// Glass alone is partly transparent
+	if edge := img.RGBAAt(3, 16); edge.A == 0 || edge.A == 255 {
+		t.Errorf("expected a translucent edge, got %v", edge)
+	}
+}
+
+func TestRender_EmptyScene(t *testing.T) {
+	if _, err := render.Render(&render.Scene{}, render.Options{Width: 8, Height: 8}); !errors.Is(err, render.ErrEmptyScene) {
+		t.Errorf("expected ErrEmptyScene, got %v", err)
+	}
+}
@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/sas"
+)
+
+// DefaultContainer is the container blobs are stored in unless configured otherwise
+const DefaultContainer = "models"
+
+// AzureStore keeps blobs in an Azure Storage container and signs URLs with SAS tokens
+type AzureStore struct {
+	client     *azblob.Client
+	credential *azblob.SharedKeyCredential
+	endpoint   string // Blob service URL without a trailing slash
+	container  string
+}
+
+// NewAzureStore returns a store for a container of the account. An empty endpoint
+// means the public Azure endpoint of the account; set it to use Azurite, e.g.
+// http://127.0.0.1:10000/devstoreaccount1.
+func NewAzureStore(accountName, accountKey, endpoint, container string) (*AzureStore, error) {
+	if accountName == "" || accountKey == "" {
+		return nil, fmt.Errorf("storage account name and key are required")
+	}
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.blob.core.windows.net", accountName)
+	}
+	if _, err := url.Parse(endpoint); err != nil {
+		return nil, fmt.Errorf("invalid storage endpoint: %w", err)
+	}
+	if container == "" {
+		container = DefaultContainer
+	}
+
+	credential, err := azblob.NewSharedKeyCredential(accountName, accountKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage credential: %w", err)
+	}
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	client, err := azblob.NewClientWithSharedKeyCredential(endpoint+"/", credential, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage client: %w", err)
+	}
+
+	return &AzureStore{client: client, credential: credential, endpoint: endpoint, container: container}, nil
+}
+
+func (s *AzureStore) URL(name string) string {
+	return fmt.Sprintf("%s/%s/%s", s.endpoint, s.container, url.PathEscape(name))
+}
+
+// presign returns the blob URL with a SAS token granting permissions
+func (s *AzureStore) presign(name string, permissions sas.BlobPermissions, expiry time.Duration) (string, time.Time, error) {
+	expiryTime := time.Now().UTC().Add(expiry)
+
+	// Azurite is served over plain HTTP
+	protocol := sas.ProtocolHTTPS
+	if strings.HasPrefix(s.endpoint, "http://") {
+		protocol = sas.ProtocolHTTPSandHTTP
+	}
+
+	sasValues := sas.BlobSignatureValues{
+		Protocol:      protocol,
+		StartTime:     time.Now().UTC().Add(-5 * time.Minute),
+		ExpiryTime:    expiryTime,
+		Permissions:   permissions.String(),
+		ContainerName: s.container,
+		BlobName:      name,
+	}
+
+	queryParams, err := sasValues.SignWithSharedKey(s.credential)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to sign SAS token")
+	}
+
+	return fmt.Sprintf("%s?%s", s.URL(name), queryParams.Encode()), expiryTime, nil
+}
+
+func (s *AzureStore) PresignUpload(ctx context.Context, name string, expiry time.Duration) (string, time.Time, error) {
+	return s.presign(name, sas.BlobPermissions{Write: true, Create: true}, expiry)
+}
+
+func (s *AzureStore) PresignDownload(ctx context.Context, name string, expiry time.Duration) (string, time.Time, error) {
+	return s.presign(name, sas.BlobPermissions{Read: true}, expiry)
+}
+
+func (s *AzureStore) Open(ctx context.Context, name string) (io.ReadCloser, BlobInfo, error) {
+	resp, err := s.client.DownloadStream(ctx, s.container, name, nil)
+	if err != nil {
+		return nil, BlobInfo{}, azureError(err)
+	}
+	info := BlobInfo{Name: name}
+	if resp.ContentLength != nil {
+		info.Size = *resp.ContentLength
+	}
+	if resp.ContentType != nil {
+		info.ContentType = *resp.ContentType
+	}
+	if resp.LastModified != nil {
+		info.LastModified = *resp.LastModified
+	}
+	if resp.ETag != nil {
+		info.ETag = string(*resp.ETag)
+	}
+	return resp.Body, info, nil
+}
+
+func (s *AzureStore) Stat(ctx context.Context, name string) (BlobInfo, error) {
+	blobClient := s.client.ServiceClient().NewContainerClient(s.container).NewBlobClient(name)
+	resp, err := blobClient.GetProperties(ctx, nil)
+	if err != nil {
+		return BlobInfo{}, azureError(err)
+	}
+	info := BlobInfo{Name: name}
+	if resp.ContentLength != nil {
+		info.Size = *resp.ContentLength
+	}
+	if resp.ContentType != nil {
+		info.ContentType = *resp.ContentType
+	}
+	if resp.LastModified != nil {
+		info.LastModified = *resp.LastModified
+	}
+	if resp.ETag != nil {
+		info.ETag = string(*resp.ETag)
+	}
+	return info, nil
+}
+
+func (s *AzureStore) Put(ctx context.Context, name string, body io.Reader, contentType string) error {
+	_, err := s.client.UploadStream(ctx, s.container, name, body, &azblob.UploadStreamOptions{
+		HTTPHeaders: &blob.HTTPHeaders{BlobContentType: &contentType},
+	})
+	return err
+}
+
+// copySourceExpiry is how long the service may read the source of a copy
+const copySourceExpiry = 15 * time.Minute
+
+// Copy copies the blob server-side with Put Blob From URL, which completes
+// synchronously for blobs up to 5000 MiB
+func (s *AzureStore) Copy(ctx context.Context, src, dst string) error {
+	if _, err := s.Stat(ctx, src); err != nil {
+		return err
+	}
+	sourceURL, _, err := s.presign(src, sas.BlobPermissions{Read: true}, copySourceExpiry)
+	if err != nil {
+		return err
+	}
+	dstClient := s.client.ServiceClient().NewContainerClient(s.container).NewBlockBlobClient(dst)
+	_, err = dstClient.UploadBlobFromURL(ctx, sourceURL, nil)
+	return azureError(err)
+}
+
+func (s *AzureStore) Delete(ctx context.Context, name string) error {
+	_, err := s.client.DeleteBlob(ctx, s.container, name, nil)
+	return azureError(err)
+}
+
+func (s *AzureStore) List(ctx context.Context, prefix string) ([]BlobInfo, error) {
+	options := &azblob.ListBlobsFlatOptions{}
+	if prefix != "" {
+		options.Prefix = &prefix
+	}
+
+	blobs := []BlobInfo{}
+	pager := s.client.NewListBlobsFlatPager(s.container, options)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, azureError(err)
+		}
+		for _, item := range page.Segment.BlobItems {
+			if item.Name == nil {
+				continue
+			}
+			info := BlobInfo{Name: *item.Name}
+			if p := item.Properties; p != nil {
+				if p.ContentLength != nil {
+					info.Size = *p.ContentLength
+				}
+				if p.ContentType != nil {
+					info.ContentType = *p.ContentType
+				}
+				if p.LastModified != nil {
+					info.LastModified = *p.LastModified
+				}
+				if p.ETag != nil {
+					info.ETag = string(*p.ETag)
+				}
+			}
+			blobs = append(blobs, info)
+		}
+	}
+	return blobs, nil
+}
+
+// azureError maps a missing blob to ErrNotFound
+func azureError(err error) error {
+	if err != nil && bloberror.HasCode(err, bloberror.BlobNotFound, bloberror.ContainerNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
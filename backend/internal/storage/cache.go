@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// cachedURL is a signed download URL and its expiry
+type cachedURL struct {
+	url     string
+	expires time.Time
+}
+
+// DownloadURLCache reuses signed download URLs until shortly before they expire,
+// so popular blobs are not signed again on every request
+type DownloadURLCache struct {
+	store   BlobStore
+	expiry  time.Duration // Lifetime of the URLs signed
+	minLeft time.Duration // URLs closer than this to expiring are signed again
+
+	mu      sync.Mutex
+	entries map[string]cachedURL
+}
+
+// NewDownloadURLCache returns a cache signing URLs valid for expiry and handing
+// them out while they have at least minLeft left
+func NewDownloadURLCache(store BlobStore, expiry, minLeft time.Duration) *DownloadURLCache {
+	return &DownloadURLCache{store: store, expiry: expiry, minLeft: minLeft, entries: map[string]cachedURL{}}
+}
+
+// URL returns a signed download URL for the blob and its expiry
+func (c *DownloadURLCache) URL(ctx context.Context, name string) (string, time.Time, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[name]
+	c.mu.Unlock()
+	if ok && entry.expires.Sub(now) >= c.minLeft {
+		return entry.url, entry.expires, nil
+	}
+
+	url, expires, err := c.store.PresignDownload(ctx, name, c.expiry)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Drop stale entries so the cache only holds URLs still worth handing out
+	for key, e := range c.entries {
+		if e.expires.Sub(now) < c.minLeft {
+			delete(c.entries, key)
+		}
+	}
+	c.entries[name] = cachedURL{url: url, expires: expires}
+	return url, expires, nil
+}
@@ -0,0 +1,294 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxLocalUpload bounds the size of a blob uploaded to a local store
+const maxLocalUpload = 512 << 20
+
+// Permissions carried by local signed URLs, named after their SAS equivalents
+const (
+	permissionRead  = "r"
+	permissionWrite = "w"
+)
+
+// mediaTypes covers the uploadable extensions missing from the system MIME table
+var mediaTypes = map[string]string{
+	".glb":  "model/gltf-binary",
+	".gltf": "model/gltf+json",
+}
+
+// LocalStore keeps blobs in a directory and serves them from the API server.
+// Signed URLs carry an expiry and an HMAC of the blob name and permission; reads
+// and uploads both require one, like SAS URLs on the private Azure container.
+type LocalStore struct {
+	dir     string
+	baseURL string // URL the store is served under, without a trailing slash
+	secret  []byte
+}
+
+// NewLocalStore returns a store for dir, served by ServeHTTP under baseURL
+// (e.g. http://localhost:8080/storage). An empty secret is replaced by a random
+// one, which invalidates signed URLs on restart.
+func NewLocalStore(dir, baseURL string, secret []byte) (*LocalStore, error) {
+	if dir == "" || baseURL == "" {
+		return nil, fmt.Errorf("local storage directory and URL are required")
+	}
+	if _, err := url.Parse(baseURL); err != nil {
+		return nil, fmt.Errorf("invalid local storage URL: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create local storage directory: %w", err)
+	}
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+	}
+	return &LocalStore{dir: dir, baseURL: strings.TrimSuffix(baseURL, "/"), secret: secret}, nil
+}
+
+// validName reports whether name is a plain file name inside the store
+func validName(name string) bool {
+	return name != "" && name != "." && !strings.ContainsAny(name, "/\\") && !strings.Contains(name, "..")
+}
+
+// file returns the path of a blob, or ErrNotFound for names outside the store
+func (s *LocalStore) file(name string) (string, error) {
+	if !validName(name) {
+		return "", ErrNotFound
+	}
+	return filepath.Join(s.dir, name), nil
+}
+
+func (s *LocalStore) URL(name string) string {
+	return fmt.Sprintf("%s/%s", s.baseURL, url.PathEscape(name))
+}
+
+// signature returns the HMAC of a signed URL's blob name, permission and expiry
+func (s *LocalStore) signature(name, permission string, expiry int64) string {
+	mac := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(mac, "%s\n%s\n%d", name, permission, expiry)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// presign returns the blob URL signed for a permission
+func (s *LocalStore) presign(name, permission string, expiry time.Duration) (string, time.Time, error) {
+	if !validName(name) {
+		return "", time.Time{}, fmt.Errorf("invalid blob name %q", name)
+	}
+	expiryTime := time.Now().UTC().Add(expiry).Truncate(time.Second)
+	query := url.Values{
+		"sp":  {permission},
+		"se":  {strconv.FormatInt(expiryTime.Unix(), 10)},
+		"sig": {s.signature(name, permission, expiryTime.Unix())},
+	}
+	return fmt.Sprintf("%s?%s", s.URL(name), query.Encode()), expiryTime, nil
+}
+
+// verify reports whether a request's query carries a valid, unexpired signature
+// for a permission on the blob
+func (s *LocalStore) verify(name, permission string, query url.Values) bool {
+	if query.Get("sp") != permission {
+		return false
+	}
+	expiry, err := strconv.ParseInt(query.Get("se"), 10, 64)
+	if err != nil || time.Now().Unix() > expiry {
+		return false
+	}
+	return hmac.Equal([]byte(query.Get("sig")), []byte(s.signature(name, permission, expiry)))
+}
+
+func (s *LocalStore) PresignUpload(ctx context.Context, name string, expiry time.Duration) (string, time.Time, error) {
+	return s.presign(name, permissionWrite, expiry)
+}
+
+func (s *LocalStore) PresignDownload(ctx context.Context, name string, expiry time.Duration) (string, time.Time, error) {
+	return s.presign(name, permissionRead, expiry)
+}
+
+// info describes a blob file
+func (s *LocalStore) info(name string, fi fs.FileInfo) BlobInfo {
+	contentType := mediaTypes[strings.ToLower(filepath.Ext(name))]
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(name))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return BlobInfo{
+		Name:         name,
+		Size:         fi.Size(),
+		ContentType:  contentType,
+		LastModified: fi.ModTime().UTC(),
+		ETag:         fmt.Sprintf(`"%x-%x"`, fi.ModTime().UnixNano(), fi.Size()),
+	}
+}
+
+func (s *LocalStore) Open(ctx context.Context, name string) (io.ReadCloser, BlobInfo, error) {
+	p, err := s.file(name)
+	if err != nil {
+		return nil, BlobInfo{}, err
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, BlobInfo{}, localError(err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, BlobInfo{}, err
+	}
+	return f, s.info(name, fi), nil
+}
+
+func (s *LocalStore) Stat(ctx context.Context, name string) (BlobInfo, error) {
+	p, err := s.file(name)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	fi, err := os.Stat(p)
+	if err != nil {
+		return BlobInfo{}, localError(err)
+	}
+	return s.info(name, fi), nil
+}
+
+// Put stores the blob; its content type follows from its extension
+func (s *LocalStore) Put(ctx context.Context, name string, body io.Reader, contentType string) error {
+	return s.put(name, body)
+}
+
+func (s *LocalStore) Copy(ctx context.Context, src, dst string) error {
+	body, _, err := s.Open(ctx, src)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	return s.put(dst, body)
+}
+
+func (s *LocalStore) Delete(ctx context.Context, name string) error {
+	p, err := s.file(name)
+	if err != nil {
+		return err
+	}
+	return localError(os.Remove(p))
+}
+
+func (s *LocalStore) List(ctx context.Context, prefix string) ([]BlobInfo, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	blobs := []BlobInfo{}
+	for _, entry := range entries {
+		// Uploads in progress are written to dot files
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") || !strings.HasPrefix(entry.Name(), prefix) {
+			continue
+		}
+		fi, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		blobs = append(blobs, s.info(entry.Name(), fi))
+	}
+	return blobs, nil
+}
+
+// put writes a blob atomically, so readers never see a partial upload
+func (s *LocalStore) put(name string, body io.Reader) error {
+	p, err := s.file(name)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(s.dir, ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+// ServeHTTP serves the store's signed URLs: GET and HEAD read a blob through a
+// signed download URL, PUT uploads one through a signed upload URL. Clients send
+// the same requests as to Azure Storage.
+func (s *LocalStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := path.Base(r.URL.Path)
+	if !validName(name) {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		if !s.verify(name, permissionRead, r.URL.Query()) {
+			http.Error(w, "invalid or expired signature", http.StatusForbidden)
+			return
+		}
+		f, info, err := s.Open(r.Context(), name)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer f.Close()
+		w.Header().Set("Content-Type", info.ContentType)
+		w.Header().Set("ETag", info.ETag)
+		http.ServeContent(w, r, name, info.LastModified, f.(io.ReadSeeker))
+
+	case http.MethodPut:
+		if !s.verify(name, permissionWrite, r.URL.Query()) {
+			http.Error(w, "invalid or expired signature", http.StatusForbidden)
+			return
+		}
+		if err := s.put(name, http.MaxBytesReader(w, r.Body, maxLocalUpload)); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, "blob too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "failed to store blob", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// localError maps a missing file to ErrNotFound
+func localError(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
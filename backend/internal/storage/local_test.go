@@ -0,0 +1,194 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"fit-pc/internal/storage"
+)
+
+// newLocalServer returns a local store served by a test server
+func newLocalServer(t *testing.T) (*storage.LocalStore, *httptest.Server) {
+	t.Helper()
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	store, err := storage.NewLocalStore(t.TempDir(), server.URL+"/storage", []byte("secret"))
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	mux.Handle("/storage/", store)
+	return store, server
+}
+
+func put(t *testing.T, url, body string) int {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPut, url, strings.NewReader(body))
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("PUT %s: %v", url, err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestLocalStore_UploadAndRead(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newLocalServer(t)
+
+	uploadURL, _, err := store.PresignUpload(ctx, "case.glb", time.Minute)
+	if err != nil {
+		t.Fatalf("PresignUpload: %v", err)
+	}
+	if status := put(t, uploadURL, "glTF"); status != http.StatusCreated {
+		t.Fatalf("upload status = %d, want 201", status)
+	}
+
+	info, err := store.Stat(ctx, "case.glb")
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if info.Size != 4 || info.ContentType != "model/gltf-binary" {
+		t.Errorf("Stat = %+v, want 4 bytes of model/gltf-binary", info)
+	}
+
+	url := mustPresignDownload(t, store, "case.glb")
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "glTF" {
+		t.Errorf("GET %s = %d %q, want 200 \"glTF\"", url, resp.StatusCode, body)
+	}
+
+	blobs, err := store.List(ctx, "case")
+	if err != nil || len(blobs) != 1 || blobs[0].Name != "case.glb" {
+		t.Errorf("List = %+v, %v; want case.glb", blobs, err)
+	}
+
+	if err := store.Copy(ctx, "case.glb", "copy.glb"); err != nil {
+		t.Fatalf("Copy: %v", err)
+	}
+	if info, err := store.Stat(ctx, "copy.glb"); err != nil || info.Size != 4 {
+		t.Errorf("Stat(copy) = %+v, %v; want 4 bytes", info, err)
+	}
+	if err := store.Copy(ctx, "missing.glb", "copy.glb"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Copy of a missing blob = %v, want ErrNotFound", err)
+	}
+
+	if err := store.Delete(ctx, "case.glb"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Stat(ctx, "case.glb"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Stat after delete = %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, "case.glb"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("second Delete = %v, want ErrNotFound", err)
+	}
+}
+
+func mustPresignDownload(t *testing.T, store *storage.LocalStore, name string) string {
+	t.Helper()
+	url, _, err := store.PresignDownload(context.Background(), name, time.Minute)
+	if err != nil {
+		t.Fatalf("PresignDownload: %v", err)
+	}
+	return url
+}
+
+func TestLocalStore_RejectsInvalidSignatures(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newLocalServer(t)
+
+	uploadURL, _, _ := store.PresignUpload(ctx, "a.glb", time.Minute)
+	expired, _, _ := store.PresignUpload(ctx, "a.glb", -time.Minute)
+	downloadURL := mustPresignDownload(t, store, "a.glb")
+
+	tests := []struct {
+		name string
+		url  string
+	}{
+		{"unsigned", store.URL("a.glb")},
+		{"other blob", strings.Replace(uploadURL, "a.glb", "b.glb", 1)},
+		{"tampered", strings.Replace(uploadURL, "sig=", "sig=0", 1)},
+		{"expired", expired},
+		{"read permission", downloadURL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := put(t, tt.url, "x"); status != http.StatusForbidden {
+				t.Errorf("status = %d, want 403", status)
+			}
+		})
+	}
+
+	if _, err := store.Stat(ctx, "a.glb"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("rejected uploads stored a blob: %v", err)
+	}
+
+	// Reads need a download signature too
+	if err := store.Put(ctx, "b.glb", strings.NewReader("glTF"), ""); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	expiredDownload, _, _ := store.PresignDownload(ctx, "b.glb", -time.Minute)
+	uploadB, _, _ := store.PresignUpload(ctx, "b.glb", time.Minute)
+	for name, url := range map[string]string{"unsigned": store.URL("b.glb"), "expired": expiredDownload, "write permission": uploadB} {
+		for _, method := range []string{http.MethodGet, http.MethodHead} {
+			req, _ := http.NewRequest(method, url, nil)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("%s %s: %v", method, url, err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusForbidden {
+				t.Errorf("%s %s read = %d, want 403", method, name, resp.StatusCode)
+			}
+		}
+	}
+	if _, err := store.Stat(ctx, "../a.glb"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Stat outside the store = %v, want ErrNotFound", err)
+	}
+}
+
+// countingStore counts the download URLs signed by a store
+type countingStore struct {
+	storage.BlobStore
+	signed int
+}
+
+func (s *countingStore) PresignDownload(ctx context.Context, name string, expiry time.Duration) (string, time.Time, error) {
+	s.signed++
+	return s.BlobStore.PresignDownload(ctx, name, expiry)
+}
+
+func TestDownloadURLCache(t *testing.T) {
+	ctx := context.Background()
+	local, _ := newLocalServer(t)
+	store := &countingStore{BlobStore: local}
+
+	cache := storage.NewDownloadURLCache(store, time.Hour, 15*time.Minute)
+	first, _, _ := cache.URL(ctx, "a.glb")
+	second, _, _ := cache.URL(ctx, "a.glb")
+	cache.URL(ctx, "b.glb")
+	if first != second || store.signed != 2 {
+		t.Errorf("expected one signature per blob, got %d (%q, %q)", store.signed, first, second)
+	}
+
+	// URLs that would expire too soon are signed again
+	stale := storage.NewDownloadURLCache(store, 10*time.Minute, 15*time.Minute)
+	stale.URL(ctx, "a.glb")
+	stale.URL(ctx, "a.glb")
+	if store.signed != 4 {
+		t.Errorf("expected short-lived URLs to be signed every time, got %d signatures", store.signed)
+	}
+}
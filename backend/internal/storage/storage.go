@@ -0,0 +1,84 @@
+// Package storage abstracts the blob store holding uploaded models, images and
+// datasheets. Production uses an Azure Storage container (or Azurite through an
+// endpoint override); development and tests can use a directory on disk whose
+// signed URLs are served by the API server itself.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"fit-pc/internal/config"
+)
+
+// Storage backends selected with STORAGE_BACKEND
+const (
+	BackendAzure = "azure"
+	BackendLocal = "local"
+)
+
+// ErrNotFound is returned when a blob does not exist
+var ErrNotFound = errors.New("blob not found")
+
+// BlobInfo describes a stored blob
+type BlobInfo struct {
+	Name         string    `json:"name"`
+	Size         int64     `json:"size"`
+	ContentType  string    `json:"content_type"`
+	LastModified time.Time `json:"last_modified"`
+	ETag         string    `json:"etag"`
+}
+
+// BlobStore is a flat container of blobs addressed by name. Clients upload and
+// download through short-lived signed URLs; the server reads blobs directly.
+type BlobStore interface {
+	// URL returns the permanent URL of a blob, as stored on products and media
+	URL(name string) string
+	// PresignUpload returns a URL that accepts a PUT of the blob until it expires
+	PresignUpload(ctx context.Context, name string, expiry time.Duration) (string, time.Time, error)
+	// PresignDownload returns a URL that allows reading the blob until it expires
+	PresignDownload(ctx context.Context, name string, expiry time.Duration) (string, time.Time, error)
+	// Open returns the blob's content; the caller closes it
+	Open(ctx context.Context, name string) (io.ReadCloser, BlobInfo, error)
+	// Stat returns a blob's metadata, or ErrNotFound
+	Stat(ctx context.Context, name string) (BlobInfo, error)
+	// Put writes a blob from the server, replacing any blob with the same name
+	Put(ctx context.Context, name string, body io.Reader, contentType string) error
+	// Copy copies a blob's content and content type to another name, replacing
+	// any blob there. Returns ErrNotFound if src does not exist.
+	Copy(ctx context.Context, src, dst string) error
+	// Delete removes a blob. Deleting a missing blob returns ErrNotFound.
+	Delete(ctx context.Context, name string) error
+	// List returns the blobs whose names start with prefix
+	List(ctx context.Context, prefix string) ([]BlobInfo, error)
+}
+
+var store BlobStore
+
+// Init sets the blob store used by the handlers
+func Init(s BlobStore) {
+	store = s
+}
+
+// Get returns the blob store set by Init
+func Get() BlobStore {
+	if store == nil {
+		panic("storage not initialized: call storage.Init() first")
+	}
+	return store
+}
+
+// NewFromConfig creates the blob store selected by the configuration
+func NewFromConfig(cfg *config.Config) (BlobStore, error) {
+	switch cfg.StorageBackend {
+	case BackendAzure, "":
+		return NewAzureStore(cfg.StorageAccountName, cfg.StorageAccountKey, cfg.StorageEndpoint, cfg.StorageContainer)
+	case BackendLocal:
+		return NewLocalStore(cfg.LocalStorageDir, cfg.LocalStorageURL, []byte(cfg.LocalStorageSecret))
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.StorageBackend)
+	}
+}
@@ -1,11 +1,15 @@
 package main
 
 import (
+	"context"
 	"log"
 
 	"fit-pc/db"
 	"fit-pc/handlers"
+	"fit-pc/internal/blobcache"
+	"fit-pc/internal/blobgc"
 	"fit-pc/internal/config"
+	"fit-pc/internal/storage"
 	"fit-pc/middleware"
 
 	"github.com/gin-contrib/cors"
@@ -28,6 +32,25 @@ func main() {
 	}
 	defer db.Close()
 
+	// Initialize blob storage
+	store, err := storage.NewFromConfig(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+	storage.Init(store)
+	log.Printf("Using %s blob storage", cfg.StorageBackend)
+
+	cache, err := blobcache.New(store, cfg.BlobCacheDir, cfg.BlobCacheMaxBytes)
+	if err != nil {
+		log.Fatalf("Failed to initialize blob cache: %v", err)
+	}
+	blobcache.Init(cache)
+
+	// Periodically delete blobs nothing references any more
+	if cfg.BlobGCInterval > 0 {
+		go blobgc.Schedule(context.Background(), store, cache, db.GetDB(), cfg.BlobGCInterval, cfg.BlobGCGrace)
+	}
+
 	// Setup Gin router
 	router := gin.Default()
 
@@ -35,8 +58,8 @@ func main() {
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000", "http://localhost:5173"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-Clerk-User-ID", "X-Clerk-Session-ID"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-Clerk-User-ID", "X-Clerk-Session-ID", "If-Match", "If-None-Match", "x-ms-blob-type"},
+		ExposeHeaders:    []string{"Content-Length", "ETag"},
 		AllowCredentials: true,
 	}))
 
@@ -48,6 +71,13 @@ func main() {
 		})
 	})
 
+	// Local blob storage is served by the API itself (signed reads and uploads)
+	if local, ok := store.(*storage.LocalStore); ok {
+		router.GET("/storage/:name", gin.WrapH(local))  // GET /storage/:name?sp=r&se=&sig=
+		router.HEAD("/storage/:name", gin.WrapH(local)) // HEAD /storage/:name?sp=r&se=&sig=
+		router.PUT("/storage/:name", gin.WrapH(local))  // PUT /storage/:name?sp=w&se=&sig=
+	}
+
 	// API routes
 	api := router.Group("/api")
 	{
@@ -60,10 +90,28 @@ func main() {
 			parts.GET("", handlers.GetParts)                          // GET /api/parts?category=...
 			parts.GET("/:id", handlers.GetPartDetails)                // GET /api/parts/:id
 			parts.GET("/:id/compatible", handlers.GetCompatibleParts) // GET /api/parts/:id/compatible
+			parts.GET("/:id/reviews", handlers.GetProductReviews)     // GET /api/parts/:id/reviews?page=&limit=
 		}
 
-		// Public storage endpoints (read-only access to models)
-		api.GET("/download-token", handlers.GenerateDownloadToken) // GET /api/download-token?blob=...
+		// Category taxonomy (public read access, drives the builder step sequence)
+		api.GET("/categories", handlers.GetCategories) // GET /api/categories
+
+		// Product families (public read access)
+		api.GET("/families/:id", handlers.GetFamily) // GET /api/families/:id
+
+		// Shared builds (read-only access through share links, no owner exposed)
+		shared := api.Group("/shared/builds")
+		{
+			shared.GET("", handlers.GetPublicBuilds)                                               // GET /api/shared/builds?page=&limit=
+			shared.GET("/:slug", handlers.GetSharedBuild)                                          // GET /api/shared/builds/:slug
+			shared.GET("/:slug/preview", handlers.GetSharedBuildPreview)                           // GET /api/shared/builds/:slug/preview
+			shared.POST("/:slug/fork", middleware.ClerkAuthMiddleware(), handlers.ForkSharedBuild) // POST /api/shared/builds/:slug/fork (signed in)
+		}
+
+		// Public storage endpoints (read-only access to catalog models, the caller's builds and shared builds)
+		api.GET("/download-token", middleware.OptionalAuthMiddleware(), handlers.GenerateDownloadToken)  // GET /api/download-token?blob=...&share=...
+		api.POST("/download-tokens", middleware.OptionalAuthMiddleware(), handlers.CreateDownloadTokens) // POST /api/download-tokens?share=...
+		api.GET("/models/:blob", middleware.OptionalAuthMiddleware(), handlers.ServeModel)               // GET /api/models/:blob (Range, ETag, cached)
 
 		// ===================
 		// PROTECTED USER ROUTES
@@ -74,11 +122,29 @@ func main() {
 			// Builds endpoints
 			builds := user.Group("/builds")
 			{
-				builds.GET("", handlers.GetUserBuilds)       // GET /api/user/builds
-				builds.POST("", handlers.SaveBuild)          // POST /api/user/builds
-				builds.GET("/:id", handlers.GetBuildDetails) // GET /api/user/builds/:id
-				builds.PUT("/:id", handlers.UpdateBuild)     // PUT /api/user/builds/:id
-				builds.DELETE("/:id", handlers.DeleteBuild)  // DELETE /api/user/builds/:id
+				builds.GET("", handlers.GetUserBuilds)                                  // GET /api/user/builds
+				builds.POST("", handlers.SaveBuild)                                     // POST /api/user/builds
+				builds.GET("/:id", handlers.GetBuildDetails)                            // GET /api/user/builds/:id?live=
+				builds.GET("/:id/preview", handlers.GetBuildPreview)                    // GET /api/user/builds/:id/preview
+				builds.PUT("/:id", handlers.UpdateBuild)                                // PUT /api/user/builds/:id
+				builds.DELETE("/:id", handlers.DeleteBuild)                             // DELETE /api/user/builds/:id
+				builds.POST("/:id/clone", handlers.CloneBuild)                          // POST /api/user/builds/:id/clone
+				builds.POST("/:id/reprice", handlers.RepriceBuild)                      // POST /api/user/builds/:id/reprice
+				builds.GET("/:id/revisions", handlers.GetBuildRevisions)                // GET /api/user/builds/:id/revisions
+				builds.GET("/:id/revisions/diff", handlers.GetBuildRevisionDiff)        // GET /api/user/builds/:id/revisions/diff?from=&to=
+				builds.POST("/:id/revisions/:version/rollback", handlers.RollbackBuild) // POST /api/user/builds/:id/revisions/:version/rollback
+				builds.GET("/:id/shares", handlers.GetBuildShares)                      // GET /api/user/builds/:id/shares
+				builds.POST("/:id/shares", handlers.CreateBuildShare)                   // POST /api/user/builds/:id/shares
+				builds.DELETE("/:id/shares/:shareId", handlers.RevokeBuildShare)        // DELETE /api/user/builds/:id/shares/:shareId
+			}
+
+			// Product reviews (one per user per product, moderated)
+			reviews := user.Group("/reviews")
+			{
+				reviews.GET("", handlers.GetUserReviews)      // GET /api/user/reviews
+				reviews.POST("", handlers.CreateReview)       // POST /api/user/reviews
+				reviews.PUT("/:id", handlers.UpdateReview)    // PUT /api/user/reviews/:id
+				reviews.DELETE("/:id", handlers.DeleteReview) // DELETE /api/user/reviews/:id
 			}
 		}
 
@@ -91,14 +157,72 @@ func main() {
 			// Admin products management (full CRUD with pagination)
 			adminProducts := admin.Group("/products")
 			{
-				adminProducts.GET("", handlers.GetAdminProducts)                // GET /api/admin/products?page=&limit=&search=&category=
-				adminProducts.GET("/:id", handlers.GetAdminProduct)             // GET /api/admin/products/:id
-				adminProducts.POST("", handlers.CreatePart)                     // POST /api/admin/products
-				adminProducts.PUT("/:id", handlers.UpdateAdminProduct)          // PUT /api/admin/products/:id
-				adminProducts.PATCH("/:id/anchors", handlers.UpdatePartAnchors) // PATCH /api/admin/products/:id/anchors
-				adminProducts.DELETE("/:id", handlers.DeleteAdminProduct)       // DELETE /api/admin/products/:id (soft delete)
+				adminProducts.GET("", handlers.GetAdminProducts)                             // GET /api/admin/products?page=&limit=&search=&category=&status=&model=
+				adminProducts.GET("/:id", handlers.GetAdminProduct)                          // GET /api/admin/products/:id
+				adminProducts.POST("", handlers.CreatePart)                                  // POST /api/admin/products
+				adminProducts.PUT("/:id", handlers.UpdateAdminProduct)                       // PUT /api/admin/products/:id
+				adminProducts.PATCH("/:id", handlers.PatchAdminProduct)                      // PATCH /api/admin/products/:id (merge-patch+json or json-patch+json)
+				adminProducts.PATCH("/:id/anchors", handlers.UpdatePartAnchors)              // PATCH /api/admin/products/:id/anchors
+				adminProducts.DELETE("/:id", handlers.DeleteAdminProduct)                    // DELETE /api/admin/products/:id (soft delete)
+				adminProducts.POST("/:id/status", handlers.ChangeProductStatus)              // POST /api/admin/products/:id/status
+				adminProducts.GET("/:id/preview", handlers.PreviewProduct)                   // GET /api/admin/products/:id/preview
+				adminProducts.POST("/:id/clone", handlers.CloneProduct)                      // POST /api/admin/products/:id/clone
+				adminProducts.POST("/:id/anchors/copy", handlers.CopyProductAnchors)         // POST /api/admin/products/:id/anchors/copy
+				adminProducts.POST("/:id/anchors/template", handlers.ApplyAnchorTemplate)    // POST /api/admin/products/:id/anchors/template
+				adminProducts.POST("/:id/anchors/import", handlers.ImportProductAnchors)     // POST /api/admin/products/:id/anchors/import
+				adminProducts.GET("/:id/anchors/export", handlers.ExportProductModel)        // GET /api/admin/products/:id/anchors/export
+				adminProducts.POST("/:id/model/analyze", handlers.AnalyzeProductModel)       // POST /api/admin/products/:id/model/analyze
+				adminProducts.POST("/:id/thumbnail/render", handlers.RenderProductThumbnail) // POST /api/admin/products/:id/thumbnail/render
+
+				// Media gallery (images, models, LOD variants, datasheets)
+				adminProducts.GET("/:id/media", handlers.GetProductMedia)                // GET /api/admin/products/:id/media
+				adminProducts.POST("/:id/media", handlers.CreateProductMedia)            // POST /api/admin/products/:id/media
+				adminProducts.PUT("/:id/media/:mediaId", handlers.UpdateProductMedia)    // PUT /api/admin/products/:id/media/:mediaId
+				adminProducts.DELETE("/:id/media/:mediaId", handlers.DeleteProductMedia) // DELETE /api/admin/products/:id/media/:mediaId
+			}
+
+			// Category taxonomy management
+			adminCategories := admin.Group("/categories")
+			{
+				adminCategories.POST("", handlers.CreateCategory)       // POST /api/admin/categories
+				adminCategories.PUT("/:id", handlers.UpdateCategory)    // PUT /api/admin/categories/:id
+				adminCategories.DELETE("/:id", handlers.DeleteCategory) // DELETE /api/admin/categories/:id
+			}
+
+			// Product families (variants inherit model, specs and anchors)
+			adminFamilies := admin.Group("/families")
+			{
+				adminFamilies.GET("", handlers.GetAdminFamilies)                  // GET /api/admin/families?category=
+				adminFamilies.GET("/:id", handlers.GetAdminFamily)                // GET /api/admin/families/:id
+				adminFamilies.POST("", handlers.CreateFamily)                     // POST /api/admin/families
+				adminFamilies.PUT("/:id", handlers.UpdateFamily)                  // PUT /api/admin/families/:id
+				adminFamilies.PATCH("/:id/anchors", handlers.UpdateFamilyAnchors) // PATCH /api/admin/families/:id/anchors
+				adminFamilies.DELETE("/:id", handlers.DeleteFamily)               // DELETE /api/admin/families/:id
 			}
 
+			// Anchor templates (versioned anchor sets per form factor)
+			adminAnchorTemplates := admin.Group("/anchor-templates")
+			{
+				adminAnchorTemplates.GET("", handlers.GetAnchorTemplates)                        // GET /api/admin/anchor-templates?category=
+				adminAnchorTemplates.GET("/:id", handlers.GetAnchorTemplate)                     // GET /api/admin/anchor-templates/:id
+				adminAnchorTemplates.POST("", handlers.CreateAnchorTemplate)                     // POST /api/admin/anchor-templates
+				adminAnchorTemplates.POST("/:id/versions", handlers.CreateAnchorTemplateVersion) // POST /api/admin/anchor-templates/:id/versions
+				adminAnchorTemplates.GET("/:id/products", handlers.GetAnchorTemplateProducts)    // GET /api/admin/anchor-templates/:id/products
+				adminAnchorTemplates.DELETE("/:id", handlers.DeleteAnchorTemplate)               // DELETE /api/admin/anchor-templates/:id
+			}
+
+			// Review moderation
+			adminReviews := admin.Group("/reviews")
+			{
+				adminReviews.GET("", handlers.GetAdminReviews)          // GET /api/admin/reviews?status=&product_id=
+				adminReviews.PATCH("/:id", handlers.ModerateReview)     // PATCH /api/admin/reviews/:id
+				adminReviews.DELETE("/:id", handlers.DeleteAdminReview) // DELETE /api/admin/reviews/:id
+			}
+
+			// Catalog data quality
+			admin.GET("/data-quality/dimensions", handlers.GetDimensionReport)           // GET /api/admin/data-quality/dimensions?category=&tolerance_mm=&all=
+			admin.POST("/data-quality/dimensions/autofill", handlers.AutofillDimensions) // POST /api/admin/data-quality/dimensions/autofill
+
 			// Legacy admin parts routes (deprecated, use /products)
 			adminParts := admin.Group("/parts")
 			{
@@ -110,7 +234,12 @@ func main() {
 
 			// Storage endpoints
 			admin.GET("/upload-token", handlers.GenerateUploadToken)
-			admin.GET("/download-token", handlers.GenerateDownloadToken)
+			admin.GET("/download-token", handlers.GenerateAdminDownloadToken)
+			admin.POST("/uploads/:blob/complete", handlers.CompleteUpload) // POST /api/admin/uploads/:blob/complete
+			admin.GET("/storage/gc", handlers.PlanBlobGC)                  // GET /api/admin/storage/gc?grace_hours= (dry run)
+			admin.POST("/storage/gc", handlers.RunBlobGC)                  // POST /api/admin/storage/gc?grace_hours=
+			admin.GET("/storage/dedupe", handlers.PlanAssetDedupe)         // GET /api/admin/storage/dedupe (dry run)
+			admin.POST("/storage/dedupe", handlers.RunAssetDedupe)         // POST /api/admin/storage/dedupe
 		}
 	}
 
@@ -41,23 +41,7 @@ func IsClerkEnabled() bool {
 
 func ClerkAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var userID string
-		var role string
-
-		// If Clerk is enabled, verify the JWT token
-		if clerkEnabled {
-			userID, role = verifyClerkToken(c)
-			if userID == "" {
-				// Check if we should allow fallback to dev mode
-				if os.Getenv("ALLOW_DEV_AUTH") == "true" {
-					userID, role = getDevModeAuth(c)
-				}
-			}
-		} else {
-			// Clerk not configured - use development mode
-			userID, role = getDevModeAuth(c)
-		}
-
+		userID, role := authenticate(c)
 		if userID == "" {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 				"error": "Authorization required",
@@ -73,6 +57,42 @@ func ClerkAuthMiddleware() gin.HandlerFunc {
 	}
 }
 
+// OptionalAuthMiddleware identifies the caller on public routes. Requests sending
+// credentials get the user ID and role in context like ClerkAuthMiddleware;
+// anonymous requests and invalid credentials continue without them.
+func OptionalAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Without credentials dev mode would fall back to the test user
+		if c.GetHeader(HeaderAuthorization) == "" && c.GetHeader(HeaderClerkUserID) == "" {
+			c.Next()
+			return
+		}
+
+		if userID, role := authenticate(c); userID != "" {
+			c.Set(ContextKeyUserID, userID)
+			c.Set(ContextKeyUserRole, role)
+		}
+
+		c.Next()
+	}
+}
+
+// authenticate returns the user ID and role of the request, or an empty user ID
+func authenticate(c *gin.Context) (string, string) {
+	// If Clerk is enabled, verify the JWT token
+	if clerkEnabled {
+		userID, role := verifyClerkToken(c)
+		if userID == "" && os.Getenv("ALLOW_DEV_AUTH") == "true" {
+			// Fall back to dev mode when allowed
+			return getDevModeAuth(c)
+		}
+		return userID, role
+	}
+
+	// Clerk not configured - use development mode
+	return getDevModeAuth(c)
+}
+
 // verifyClerkToken verifies the JWT token with Clerk and extracts org role
 func verifyClerkToken(c *gin.Context) (string, string) {
 	authHeader := c.GetHeader(HeaderAuthorization)
@@ -223,6 +223,57 @@ func TestClerkAuthMiddleware_DevMode(t *testing.T) {
 	}
 }
 
+func TestOptionalAuthMiddleware_DevMode(t *testing.T) {
+	tests := []struct {
+		name       string
+		headers    map[string]string
+		wantUserID string
+		wantExist  bool
+	}{
+		{
+			name:      "anonymous request",
+			headers:   map[string]string{},
+			wantExist: false,
+		},
+		{
+			name: "X-Clerk-User-ID header",
+			headers: map[string]string{
+				middleware.HeaderClerkUserID: "custom-user-123",
+			},
+			wantUserID: "custom-user-123",
+			wantExist:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			var capturedUserID string
+			var exists bool
+			router := gin.New()
+			router.Use(middleware.OptionalAuthMiddleware())
+			router.GET("/", func(c *gin.Context) {
+				capturedUserID, exists = middleware.GetUserIDFromContext(c)
+				c.Status(http.StatusOK)
+			})
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusOK)
+			}
+			if exists != tt.wantExist || capturedUserID != tt.wantUserID {
+				t.Errorf("userID = %q (exists %v), want %q (exists %v)", capturedUserID, exists, tt.wantUserID, tt.wantExist)
+			}
+		})
+	}
+}
+
 func TestAdminUserRole(t *testing.T) {
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -4,6 +4,7 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 
 	"gorm.io/gorm"
@@ -105,20 +106,363 @@ func (c *ComponentIDs) Scan(value interface{}) error {
 	return json.Unmarshal(bytes, c)
 }
 
+// StringList is a list of strings stored as JSONB
+type StringList []string
+
+// Value implements driver.Valuer for database serialization
+func (s StringList) Value() (driver.Value, error) {
+	if s == nil {
+		return nil, nil
+	}
+	return json.Marshal(s)
+}
+
+// Scan implements sql.Scanner for database deserialization
+func (s *StringList) Scan(value interface{}) error {
+	if value == nil {
+		*s = nil
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("failed to unmarshal StringList value")
+	}
+
+	return json.Unmarshal(bytes, s)
+}
+
+// ImageSizes maps the name of a resized copy of an image (list, card, detail) to
+// its URL, stored as JSONB
+type ImageSizes map[string]string
+
+// Value implements driver.Valuer for database serialization
+func (s ImageSizes) Value() (driver.Value, error) {
+	if s == nil {
+		return nil, nil
+	}
+	return json.Marshal(s)
+}
+
+// Scan implements sql.Scanner for database deserialization
+func (s *ImageSizes) Scan(value interface{}) error {
+	if value == nil {
+		*s = nil
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("failed to unmarshal ImageSizes value")
+	}
+
+	return json.Unmarshal(bytes, s)
+}
+
+// ModelMetadata describes a product's 3D model as analysed after upload. Sizes
+// are in centimetres, the unit models are authored in.
+type ModelMetadata struct {
+	ModelURL   string     `json:"model_url"` // Model the metadata was computed for
+	Valid      bool       `json:"valid"`
+	Errors     StringList `json:"errors,omitempty"`
+	Warnings   StringList `json:"warnings,omitempty"`
+	SizeBytes  int64      `json:"size_bytes"`
+	Triangles  int        `json:"triangles"`
+	Vertices   int        `json:"vertices"`
+	Meshes     int        `json:"meshes"`
+	Materials  int        `json:"materials"`
+	Textures   int        `json:"textures"`
+	BoundsMin  Vector3    `json:"bounds_min"`
+	BoundsMax  Vector3    `json:"bounds_max"`
+	Dimensions Vector3    `json:"dimensions"`            // Bounding box size: width (x), height (y), depth (z)
+	OverBudget StringList `json:"over_budget,omitempty"` // Budgets the model exceeds, see ModelBudget
+	AnalyzedAt time.Time  `json:"analyzed_at"`
+}
+
+// Value implements driver.Valuer for database serialization
+func (m ModelMetadata) Value() (driver.Value, error) {
+	return json.Marshal(m)
+}
+
+// Scan implements sql.Scanner for database deserialization
+func (m *ModelMetadata) Scan(value interface{}) error {
+	if value == nil {
+		*m = ModelMetadata{}
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("failed to unmarshal ModelMetadata value")
+	}
+
+	return json.Unmarshal(bytes, m)
+}
+
+// ModelBudget limits the size and complexity of 3D models. Zero disables a limit.
+type ModelBudget struct {
+	MaxSizeBytes int64
+	MaxTriangles int
+	MaxMaterials int
+	MaxTextures  int
+}
+
+// Category is an entry of the managed product taxonomy. Product.Category and
+// AnchorPoint.CompatibleTypes reference categories by slug.
+type Category struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	Slug          string     `gorm:"uniqueIndex;not null;size:50" json:"slug"`
+	DisplayName   string     `gorm:"not null;size:100" json:"display_name"`
+	SortOrder     int        `gorm:"not null;default:0" json:"sort_order"`
+	ParentID      *uint      `gorm:"index" json:"parent_id"`
+	Icon          string     `gorm:"size:100" json:"icon"`
+	Required      bool       `gorm:"not null;default:false" json:"required"` // Whether the builder requires a part of this category
+	SpecSchema    string     `gorm:"size:255" json:"spec_schema"`            // Reference to the schema describing the category's technical specs
+	RequiredSpecs StringList `gorm:"type:jsonb" json:"required_specs"`       // TechnicalSpecs keys every product of the category must define
+	AnchorTypes   StringList `gorm:"type:jsonb" json:"anchor_types"`         // Extra values (anchor types, sockets) accepted in CompatibleTypes
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
 // Product represents a PC component/part in the system
 type Product struct {
+	ID                    uint           `gorm:"primaryKey" json:"id"`
+	Name                  string         `gorm:"not null;size:255" json:"name"`
+	SKU                   string         `gorm:"uniqueIndex;size:100" json:"sku"`
+	Category              string         `gorm:"index;size:50" json:"category"`
+	Price                 float64        `gorm:"type:decimal(10,2)" json:"price"`
+	ModelURL              string         `gorm:"size:500" json:"model_url"`
+	ThumbnailURL          string         `gorm:"size:500" json:"thumbnail_url"`
+	ThumbnailSizes        ImageSizes     `gorm:"type:jsonb" json:"sizes"`                 // Resized copies of the thumbnail, see Asset.Sizes
+	GeneratedThumbnailURL string         `gorm:"size:500" json:"generated_thumbnail_url"` // Preview rendered from the 3D model
+	TechnicalSpecs        TechnicalSpecs `gorm:"type:jsonb" json:"technical_specs"`
+	AnchorPoints          AnchorPoints   `gorm:"type:jsonb" json:"anchor_points"`
+	FamilyID              *uint          `gorm:"index" json:"family_id"`
+	Family                *ProductFamily `json:"family,omitempty"`
+	Media                 []MediaAsset   `gorm:"constraint:OnDelete:CASCADE" json:"media,omitempty"`
+	RatingAverage         float64        `gorm:"type:decimal(3,2);not null;default:0;index" json:"rating_average"`
+	RatingCount           int            `gorm:"not null;default:0" json:"rating_count"`
+	Reviews               []Review       `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	Version               uint           `gorm:"not null;default:1" json:"version"`                      // Incremented on every change, exposed as ETag
+	Status                string         `gorm:"not null;size:20;default:published;index" json:"status"` // Lifecycle status, see ProductStatus*
+	PublishAt             *time.Time     `gorm:"index" json:"publish_at"`                                // Scheduled publish time; nil publishes immediately
+	AnchorTemplateID      *uint          `gorm:"index" json:"anchor_template_id"`                        // Template the anchor points were last applied from
+	AnchorTemplateVersion *int           `json:"anchor_template_version"`
+	ModelMetadata         *ModelMetadata `gorm:"type:jsonb" json:"model_metadata"`                  // Set by analysing the model after upload
+	ModelFlagged          bool           `gorm:"not null;default:false;index" json:"model_flagged"` // Model is invalid or over budget
+	CreatedAt             time.Time      `json:"created_at"`
+	UpdatedAt             time.Time      `json:"updated_at"`
+	DeletedAt             gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// Product lifecycle statuses. Only published products whose publish time has
+// passed are visible in the public catalog.
+const (
+	ProductStatusDraft     = "draft"
+	ProductStatusInReview  = "in_review"
+	ProductStatusPublished = "published"
+	ProductStatusArchived  = "archived"
+)
+
+// ProductFamily holds the data shared by product variants (e.g. RAM kits in
+// different capacities or GPUs with the same chip). Variants inherit the
+// family's model, specs and anchor points and only store overrides.
+type ProductFamily struct {
 	ID             uint           `gorm:"primaryKey" json:"id"`
 	Name           string         `gorm:"not null;size:255" json:"name"`
-	SKU            string         `gorm:"uniqueIndex;size:100" json:"sku"`
 	Category       string         `gorm:"index;size:50" json:"category"`
-	Price          float64        `gorm:"type:decimal(10,2)" json:"price"`
 	ModelURL       string         `gorm:"size:500" json:"model_url"`
 	ThumbnailURL   string         `gorm:"size:500" json:"thumbnail_url"`
+	ThumbnailSizes ImageSizes     `gorm:"type:jsonb" json:"sizes"`
 	TechnicalSpecs TechnicalSpecs `gorm:"type:jsonb" json:"technical_specs"`
 	AnchorPoints   AnchorPoints   `gorm:"type:jsonb" json:"anchor_points"`
 	CreatedAt      time.Time      `json:"created_at"`
 	UpdatedAt      time.Time      `json:"updated_at"`
-	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// Media asset kinds
+const (
+	MediaKindImage     = "image"
+	MediaKindModel     = "model"
+	MediaKindModelLOD  = "model_lod"
+	MediaKindDatasheet = "datasheet"
+)
+
+// MediaAsset is an additional file attached to a product: photos, 3D models
+// (including lower-detail LOD variants) and PDF datasheets
+type MediaAsset struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ProductID uint      `gorm:"index;not null" json:"product_id"`
+	Kind      string    `gorm:"not null;size:20" json:"kind"`
+	BlobName  string    `gorm:"not null;size:255" json:"blob_name"`
+	URL       string    `gorm:"size:500" json:"url"`
+	AltText   string    `gorm:"size:255" json:"alt_text"`
+	SortOrder int       `gorm:"not null;default:0" json:"sort_order"`
+	LODLevel  int       `gorm:"not null;default:0" json:"lod_level"` // 0 is full detail, higher levels have fewer polygons
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Asset is an uploaded blob whose upload was confirmed: it exists in storage and
+// its content matches its extension. Only assets may be referenced as a model or
+// thumbnail. Assets are content-addressed: the blob is named after the SHA-256 of
+// its content, so identical uploads share one asset.
+type Asset struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	BlobName    string     `gorm:"uniqueIndex;not null;size:255" json:"blob_name"`
+	URL         string     `gorm:"index;not null;size:500" json:"url"`
+	Kind        string     `gorm:"not null;size:20" json:"kind"` // MediaKindModel, MediaKindImage or MediaKindDatasheet
+	ContentType string     `gorm:"not null;size:100" json:"content_type"`
+	SizeBytes   int64      `gorm:"not null" json:"size_bytes"`
+	SHA256      string     `gorm:"index;not null;size:64" json:"sha256"`
+	Sizes       ImageSizes `gorm:"type:jsonb" json:"sizes,omitempty"` // Resized copies of images, stored next to the original
+	UploadedBy  string     `gorm:"size:255" json:"uploaded_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// AssetUpload records an uploaded blob, or a blob referenced before uploads were
+// content-addressed, and the asset its content resolved to. The blob itself is
+// removed once its content is stored under the asset's name.
+type AssetUpload struct {
+	BlobName   string    `gorm:"primaryKey;size:255" json:"blob_name"`
+	AssetID    uint      `gorm:"index;not null" json:"asset_id"`
+	UploadedBy string    `gorm:"size:255" json:"uploaded_by"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// AnchorTemplate is a named, reusable set of anchor points for a form factor
+// (e.g. the DIMM, PCIe and M.2 slots of an ATX motherboard). Its anchor points
+// live in numbered versions so fixes never change what was applied earlier.
+type AnchorTemplate struct {
+	ID              uint                    `gorm:"primaryKey" json:"id"`
+	Name            string                  `gorm:"uniqueIndex;not null;size:100" json:"name"`
+	Category        string                  `gorm:"index;not null;size:50" json:"category"`
+	FormFactor      string                  `gorm:"size:50" json:"form_factor"`
+	Description     string                  `gorm:"size:500" json:"description"`
+	LatestVersion   int                     `gorm:"not null;default:1" json:"latest_version"`
+	OriginProductID *uint                   `json:"origin_product_id"` // Product the first version was captured from
+	Versions        []AnchorTemplateVersion `gorm:"foreignKey:TemplateID;constraint:OnDelete:CASCADE" json:"versions,omitempty"`
+	CreatedAt       time.Time               `json:"created_at"`
+	UpdatedAt       time.Time               `json:"updated_at"`
+}
+
+// AnchorTemplateVersion is an immutable snapshot of a template's anchor points.
+// Positions are relative to the template's reference frame (its origin).
+type AnchorTemplateVersion struct {
+	ID              uint         `gorm:"primaryKey" json:"id"`
+	TemplateID      uint         `gorm:"not null;uniqueIndex:idx_anchor_template_version" json:"template_id"`
+	Version         int          `gorm:"not null;uniqueIndex:idx_anchor_template_version" json:"version"`
+	AnchorPoints    AnchorPoints `gorm:"type:jsonb" json:"anchor_points"`
+	Changelog       string       `gorm:"size:500" json:"changelog"`
+	SourceProductID *uint        `json:"source_product_id"`
+	CreatedAt       time.Time    `json:"created_at"`
+}
+
+// Review moderation statuses
+const (
+	ReviewStatusPending  = "pending"
+	ReviewStatusApproved = "approved"
+	ReviewStatusRejected = "rejected"
+)
+
+// Review is a user's rating of a product. Each user can review a product once;
+// only approved reviews count towards the product's aggregated rating.
+type Review struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	ProductID      uint       `gorm:"not null;uniqueIndex:idx_reviews_product_user" json:"product_id"`
+	UserID         string     `gorm:"not null;size:255;uniqueIndex:idx_reviews_product_user" json:"user_id"`
+	BuildID        *uint      `gorm:"index" json:"build_id"` // Optional build proving the reviewer used the part
+	Rating         int        `gorm:"not null" json:"rating"`
+	Body           string     `gorm:"type:text" json:"body"`
+	Status         string     `gorm:"not null;size:20;default:pending;index" json:"status"`
+	ModerationNote string     `gorm:"size:500" json:"moderation_note,omitempty"`
+	ModeratedBy    string     `gorm:"size:255" json:"moderated_by,omitempty"`
+	ModeratedAt    *time.Time `json:"moderated_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// Transformed returns a copy of the anchor points with every position scaled
+// per axis and then translated by offset. Rotations are kept, since anchors
+// of a template share its orientation.
+func (a AnchorPoints) Transformed(offset, scale Vector3) AnchorPoints {
+	if a == nil {
+		return nil
+	}
+	result := make(AnchorPoints, len(a))
+	for i, anchor := range a {
+		anchor.Position = Vector3{
+			X: anchor.Position.X*scale.X + offset.X,
+			Y: anchor.Position.Y*scale.Y + offset.Y,
+			Z: anchor.Position.Z*scale.Z + offset.Z,
+		}
+		anchor.CompatibleTypes = append([]string(nil), anchor.CompatibleTypes...)
+		result[i] = anchor
+	}
+	return result
+}
+
+// Check returns the budgets a model exceeds
+func (b ModelBudget) Check(m ModelMetadata) []string {
+	var problems []string
+	if b.MaxSizeBytes > 0 && m.SizeBytes > b.MaxSizeBytes {
+		problems = append(problems, fmt.Sprintf("file size %.1f MB exceeds %.1f MB", float64(m.SizeBytes)/(1<<20), float64(b.MaxSizeBytes)/(1<<20)))
+	}
+	if b.MaxTriangles > 0 && m.Triangles > b.MaxTriangles {
+		problems = append(problems, fmt.Sprintf("%d triangles exceed %d", m.Triangles, b.MaxTriangles))
+	}
+	if b.MaxMaterials > 0 && m.Materials > b.MaxMaterials {
+		problems = append(problems, fmt.Sprintf("%d materials exceed %d", m.Materials, b.MaxMaterials))
+	}
+	if b.MaxTextures > 0 && m.Textures > b.MaxTextures {
+		problems = append(problems, fmt.Sprintf("%d textures exceed %d", m.Textures, b.MaxTextures))
+	}
+	return problems
+}
+
+// IsVisible reports whether the product is shown in the public catalog at the given time
+func (p Product) IsVisible(now time.Time) bool {
+	return p.Status == ProductStatusPublished && (p.PublishAt == nil || !p.PublishAt.After(now))
+}
+
+// InheritsAnchors reports whether the product takes its anchor points from its family
+func (p Product) InheritsAnchors() bool {
+	return p.FamilyID != nil && len(p.AnchorPoints) == 0
+}
+
+// Resolved returns the product with values inherited from its family filled in.
+// Fields set on the variant always take precedence; technical specs are merged
+// key by key. The Family association must be loaded for inheritance to apply.
+func (p Product) Resolved() Product {
+	if p.Family == nil {
+		return p
+	}
+	family := p.Family
+	p.Family = nil
+
+	if p.ModelURL == "" {
+		p.ModelURL = family.ModelURL
+	}
+	if p.ThumbnailURL == "" {
+		p.ThumbnailURL = family.ThumbnailURL
+		p.ThumbnailSizes = family.ThumbnailSizes
+	}
+	if len(p.AnchorPoints) == 0 {
+		p.AnchorPoints = family.AnchorPoints
+	}
+	if len(family.TechnicalSpecs) > 0 {
+		specs := make(TechnicalSpecs, len(family.TechnicalSpecs)+len(p.TechnicalSpecs))
+		for k, v := range family.TechnicalSpecs {
+			specs[k] = v
+		}
+		for k, v := range p.TechnicalSpecs {
+			specs[k] = v
+		}
+		p.TechnicalSpecs = specs
+	}
+
+	return p
 }
 
 // BuildComponent represents a component snapshot saved with a build
@@ -166,11 +510,51 @@ type Build struct {
 	Name       string          `gorm:"not null;size:255" json:"name"`
 	Components BuildComponents `gorm:"type:jsonb" json:"components"`
 	TotalPrice float64         `gorm:"type:decimal(10,2)" json:"total_price"`
+	Visibility string          `gorm:"not null;size:20;default:private;index" json:"visibility"` // Who may open share links, see BuildVisibility*
+	ForkedFrom *uint           `gorm:"index" json:"forked_from"`                                 // Build this one was cloned or forked from
+	Version    uint            `gorm:"not null;default:1" json:"version"`                        // Incremented on every change, exposed as ETag
 	CreatedAt  time.Time       `json:"created_at"`
 	UpdatedAt  time.Time       `json:"updated_at"`
 	DeletedAt  gorm.DeletedAt  `gorm:"index" json:"-"`
 }
 
+// BuildRevision is a saved state of a build, recorded whenever its name or
+// components change so earlier configurations can be compared and restored
+type BuildRevision struct {
+	ID         uint            `gorm:"primaryKey" json:"id"`
+	BuildID    uint            `gorm:"not null;uniqueIndex:idx_build_revision" json:"build_id"`
+	Version    uint            `gorm:"not null;uniqueIndex:idx_build_revision" json:"version"` // Build version this state was saved as
+	Name       string          `gorm:"not null;size:255" json:"name"`
+	Components BuildComponents `gorm:"type:jsonb" json:"components"`
+	TotalPrice float64         `gorm:"type:decimal(10,2)" json:"total_price"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// Build visibilities
+const (
+	BuildVisibilityPrivate  = "private"  // Only the owner; share links are disabled
+	BuildVisibilityUnlisted = "unlisted" // Anyone with a share link
+	BuildVisibilityPublic   = "public"   // Anyone with a share link, and listed among the public builds
+)
+
+// BuildShare is a read-only link to a build, identified by an unguessable slug.
+// Links stop working when revoked, once expired, or while the build is private.
+type BuildShare struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	BuildID      uint       `gorm:"index;not null" json:"build_id"`
+	Slug         string     `gorm:"uniqueIndex;not null;size:32" json:"slug"`
+	ExpiresAt    *time.Time `json:"expires_at"` // Nil never expires
+	RevokedAt    *time.Time `json:"revoked_at"`
+	ViewCount    int64      `gorm:"not null;default:0" json:"view_count"`
+	LastViewedAt *time.Time `json:"last_viewed_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// Active reports whether the link can be opened at now, the build's visibility aside
+func (s BuildShare) Active(now time.Time) bool {
+	return s.RevokedAt == nil && (s.ExpiresAt == nil || now.Before(*s.ExpiresAt))
+}
+
 // TableName specifies the table name for Product
 func (Product) TableName() string {
 	return "products"
@@ -180,3 +564,53 @@ func (Product) TableName() string {
 func (Build) TableName() string {
 	return "builds"
 }
+
+// TableName specifies the table name for BuildRevision
+func (BuildRevision) TableName() string {
+	return "build_revisions"
+}
+
+// TableName specifies the table name for BuildShare
+func (BuildShare) TableName() string {
+	return "build_shares"
+}
+
+// TableName specifies the table name for Category
+func (Category) TableName() string {
+	return "categories"
+}
+
+// TableName specifies the table name for Asset
+func (Asset) TableName() string {
+	return "assets"
+}
+
+// TableName specifies the table name for AssetUpload
+func (AssetUpload) TableName() string {
+	return "asset_uploads"
+}
+
+// TableName specifies the table name for MediaAsset
+func (MediaAsset) TableName() string {
+	return "media_assets"
+}
+
+// TableName specifies the table name for Review
+func (Review) TableName() string {
+	return "reviews"
+}
+
+// TableName specifies the table name for ProductFamily
+func (ProductFamily) TableName() string {
+	return "product_families"
+}
+
+// TableName specifies the table name for AnchorTemplate
+func (AnchorTemplate) TableName() string {
+	return "anchor_templates"
+}
+
+// TableName specifies the table name for AnchorTemplateVersion
+func (AnchorTemplateVersion) TableName() string {
+	return "anchor_template_versions"
+}
@@ -2,7 +2,9 @@ package models_test
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
+	"time"
 
 	"fit-pc/models"
 )
@@ -331,3 +333,244 @@ func TestBuild_TableName(t *testing.T) {
 		t.Errorf("expected 'builds', got '%s'", b.TableName())
 	}
 }
+
+func TestProduct_Resolved(t *testing.T) {
+	familyID := uint(1)
+	family := &models.ProductFamily{
+		ID:           familyID,
+		ModelURL:     "https://example.com/ram.glb",
+		ThumbnailURL: "https://example.com/ram.png",
+		TechnicalSpecs: models.TechnicalSpecs{
+			"type":      "DDR5",
+			"speed_mhz": 6000,
+		},
+		AnchorPoints: models.AnchorPoints{
+			{Name: "ram_edge", CompatibleTypes: []string{"ram_slot"}},
+		},
+	}
+
+	variant := models.Product{
+		Name:         "RAM 32GB",
+		FamilyID:     &familyID,
+		Family:       family,
+		ThumbnailURL: "https://example.com/ram-white.png",
+		TechnicalSpecs: models.TechnicalSpecs{
+			"capacity_gb": 32,
+			"speed_mhz":   6400,
+		},
+	}
+
+	resolved := variant.Resolved()
+
+	if resolved.Family != nil {
+		t.Error("expected family association to be cleared")
+	}
+	if resolved.ModelURL != family.ModelURL {
+		t.Errorf("expected inherited model URL, got '%s'", resolved.ModelURL)
+	}
+	if resolved.ThumbnailURL != variant.ThumbnailURL {
+		t.Errorf("expected variant thumbnail URL, got '%s'", resolved.ThumbnailURL)
+	}
+	if len(resolved.AnchorPoints) != 1 {
+		t.Errorf("expected 1 inherited anchor point, got %d", len(resolved.AnchorPoints))
+	}
+	if resolved.TechnicalSpecs["type"] != "DDR5" {
+		t.Errorf("expected inherited spec 'type', got %v", resolved.TechnicalSpecs["type"])
+	}
+	if resolved.TechnicalSpecs["speed_mhz"] != 6400 {
+		t.Errorf("expected overridden spec 'speed_mhz' = 6400, got %v", resolved.TechnicalSpecs["speed_mhz"])
+	}
+	if _, ok := family.TechnicalSpecs["capacity_gb"]; ok {
+		t.Error("resolving a variant must not modify the family specs")
+	}
+}
+
+func TestProduct_Resolved_ThumbnailSizes(t *testing.T) {
+	familyID := uint(1)
+	family := &models.ProductFamily{
+		ID:             familyID,
+		ThumbnailURL:   "https://example.com/ram.png",
+		ThumbnailSizes: models.ImageSizes{"card": "https://example.com/ram-card.jpg"},
+	}
+
+	inherited := models.Product{FamilyID: &familyID, Family: family}.Resolved()
+	if inherited.ThumbnailSizes["card"] != family.ThumbnailSizes["card"] {
+		t.Errorf("expected inherited thumbnail sizes, got %v", inherited.ThumbnailSizes)
+	}
+
+	own := models.Product{FamilyID: &familyID, Family: family, ThumbnailURL: "https://example.com/ram-white.png"}.Resolved()
+	if own.ThumbnailSizes != nil {
+		t.Errorf("expected the family sizes to go with the family thumbnail, got %v", own.ThumbnailSizes)
+	}
+}
+
+func TestProduct_Resolved_NoFamily(t *testing.T) {
+	p := models.Product{Name: "Standalone", ModelURL: "https://example.com/a.glb"}
+	resolved := p.Resolved()
+	if resolved.ModelURL != p.ModelURL {
+		t.Errorf("expected unchanged model URL, got '%s'", resolved.ModelURL)
+	}
+}
+
+func TestProduct_IsVisible(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	tests := []struct {
+		name      string
+		status    string
+		publishAt *time.Time
+		want      bool
+	}{
+		{"published", models.ProductStatusPublished, nil, true},
+		{"published in the past", models.ProductStatusPublished, &past, true},
+		{"scheduled", models.ProductStatusPublished, &future, false},
+		{"draft", models.ProductStatusDraft, nil, false},
+		{"in review", models.ProductStatusInReview, nil, false},
+		{"archived", models.ProductStatusArchived, &past, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := models.Product{Status: tt.status, PublishAt: tt.publishAt}
+			if got := p.IsVisible(now); got != tt.want {
+				t.Errorf("IsVisible() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnchorPoints_Transformed(t *testing.T) {
+	anchors := models.AnchorPoints{
+		{Name: "ram_slot", Position: models.Vector3{X: 1, Y: 2, Z: 3}, Rotation: models.Vector3{Y: 1.5}, CompatibleTypes: []string{"DDR5"}},
+	}
+
+	result := anchors.Transformed(models.Vector3{X: 10, Y: 0, Z: -1}, models.Vector3{X: 2, Y: 1, Z: 0.5})
+
+	want := models.Vector3{X: 12, Y: 2, Z: 0.5}
+	if result[0].Position != want {
+		t.Errorf("expected position %+v, got %+v", want, result[0].Position)
+	}
+	if result[0].Rotation != anchors[0].Rotation {
+		t.Errorf("expected rotation to be kept, got %+v", result[0].Rotation)
+	}
+
+	result[0].CompatibleTypes[0] = "DDR4"
+	if anchors[0].CompatibleTypes[0] != "DDR5" || anchors[0].Position.X != 1 {
+		t.Error("expected original anchor points to be unchanged")
+	}
+}
+
+func TestModelBudget_Check(t *testing.T) {
+	budget := models.ModelBudget{MaxSizeBytes: 10 << 20, MaxTriangles: 100000, MaxTextures: 8}
+	meta := models.ModelMetadata{SizeBytes: 12 << 20, Triangles: 100000, Materials: 500, Textures: 9}
+
+	problems := budget.Check(meta)
+	// Limits are inclusive and a zero limit is disabled
+	if len(problems) != 2 || !strings.Contains(problems[0], "12.0 MB") || !strings.Contains(problems[1], "9 textures") {
+		t.Errorf("unexpected problems: %v", problems)
+	}
+
+	if problems := (models.ModelBudget{}).Check(meta); len(problems) != 0 {
+		t.Errorf("expected an empty budget to allow everything, got %v", problems)
+	}
+}
+
+func TestModelMetadata_ValueScan(t *testing.T) {
+	meta := models.ModelMetadata{
+		ModelURL:   "https://example.com/gpu.glb",
+		Valid:      true,
+		Triangles:  42000,
+		Dimensions: models.Vector3{X: 30.5, Y: 4, Z: 12},
+		OverBudget: models.StringList{"too many triangles"},
+	}
+
+	value, err := meta.Value()
+	if err != nil {
+		t.Fatalf("Value failed: %v", err)
+	}
+
+	var scanned models.ModelMetadata
+	if err := scanned.Scan(value); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if scanned.ModelURL != meta.ModelURL || scanned.Triangles != 42000 || scanned.Dimensions != meta.Dimensions || len(scanned.OverBudget) != 1 {
+		t.Errorf("round trip mismatch: %+v", scanned)
+	}
+
+	if err := scanned.Scan("not bytes"); err == nil {
+		t.Error("expected an error for a non-byte value")
+	}
+}
+
+func TestStringList_Scan(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     interface{}
+		wantErr   bool
+		wantCount int
+	}{
+		{
+			name:      "nil value",
+			input:     nil,
+			wantErr:   false,
+			wantCount: 0,
+		},
+		{
+			name:      "valid JSON",
+			input:     []byte(`["ram_slot","DDR5"]`),
+			wantErr:   false,
+			wantCount: 2,
+		},
+		{
+			name:    "wrong type",
+			input:   "string",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var list models.StringList
+			err := list.Scan(tt.input)
+			if tt.wantErr && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !tt.wantErr && len(list) != tt.wantCount {
+				t.Errorf("count mismatch: got %d, want %d", len(list), tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestCategory_TableName(t *testing.T) {
+	c := models.Category{}
+	if c.TableName() != "categories" {
+		t.Errorf("expected 'categories', got '%s'", c.TableName())
+	}
+}
+
+func TestBuildShare_Active(t *testing.T) {
+	now := time.Now()
+	later, earlier := now.Add(time.Hour), now.Add(-time.Hour)
+
+	tests := []struct {
+		name  string
+		share models.BuildShare
+		want  bool
+	}{
+		{"no expiry", models.BuildShare{}, true},
+		{"not yet expired", models.BuildShare{ExpiresAt: &later}, true},
+		{"expired", models.BuildShare{ExpiresAt: &earlier}, false},
+		{"revoked", models.BuildShare{RevokedAt: &earlier, ExpiresAt: &later}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.share.Active(now); got != tt.want {
+			t.Errorf("%s: Active() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -3,15 +3,32 @@ package tests
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"mime"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	"fit-pc/db"
 	"fit-pc/handlers"
+	"fit-pc/internal/blobcache"
+	"fit-pc/internal/blobgc"
+	"fit-pc/internal/config"
+	"fit-pc/internal/storage"
 	"fit-pc/middleware"
 	"fit-pc/models"
 
@@ -27,6 +44,8 @@ import (
 var testRouter *gin.Engine
 var testDB *gorm.DB
 var pgContainer testcontainers.Container
+var testStore *storage.LocalStore
+var testBlobDir string
 
 func TestMain(m *testing.M) {
 	gin.SetMode(gin.TestMode)
@@ -64,10 +83,28 @@ func TestMain(m *testing.M) {
 		panic(err)
 	}
 
-	testDB.AutoMigrate(&models.Product{}, &models.Build{})
+	testDB.AutoMigrate(&models.Category{}, &models.ProductFamily{}, &models.Product{}, &models.MediaAsset{}, &models.Asset{}, &models.AssetUpload{}, &models.AnchorTemplate{}, &models.AnchorTemplateVersion{}, &models.Build{}, &models.BuildRevision{}, &models.BuildShare{}, &models.Review{})
+	seedTestCategories()
 
 	db.DB = testDB
 
+	testBlobDir, err = os.MkdirTemp("", "fit-pc-blobs-")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(testBlobDir)
+	testStore, err = storage.NewLocalStore(testBlobDir, "http://storage.test/storage", []byte("test"))
+	if err != nil {
+		panic(err)
+	}
+	config.Init(&config.Config{ModelBudget: models.ModelBudget{MaxTriangles: 500000}})
+	storage.Init(testStore)
+	cache, err := blobcache.New(testStore, filepath.Join(testBlobDir, ".cache"), 1<<20)
+	if err != nil {
+		panic(err)
+	}
+	blobcache.Init(cache)
+
 	testRouter = setupRouter()
 
 	code := m.Run()
@@ -86,6 +123,9 @@ func TestMain(m *testing.M) {
 func setupRouter() *gin.Engine {
 	r := gin.New()
 
+	r.GET("/storage/:name", gin.WrapH(testStore))
+	r.PUT("/storage/:name", gin.WrapH(testStore))
+
 	api := r.Group("/api")
 	{
 		parts := api.Group("/parts")
@@ -93,8 +133,23 @@ func setupRouter() *gin.Engine {
 			parts.GET("", handlers.GetParts)
 			parts.GET("/:id", handlers.GetPartDetails)
 			parts.GET("/:id/compatible", handlers.GetCompatibleParts)
+			parts.GET("/:id/reviews", handlers.GetProductReviews)
+		}
+
+		api.GET("/categories", handlers.GetCategories)
+		api.GET("/families/:id", handlers.GetFamily)
+		shared := api.Group("/shared/builds")
+		{
+			shared.GET("", handlers.GetPublicBuilds)
+			shared.GET("/:slug", handlers.GetSharedBuild)
+			shared.GET("/:slug/preview", handlers.GetSharedBuildPreview)
+			shared.POST("/:slug/fork", middleware.ClerkAuthMiddleware(), handlers.ForkSharedBuild)
 		}
 
+		api.GET("/download-token", middleware.OptionalAuthMiddleware(), handlers.GenerateDownloadToken)
+		api.POST("/download-tokens", middleware.OptionalAuthMiddleware(), handlers.CreateDownloadTokens)
+		api.GET("/models/:blob", middleware.OptionalAuthMiddleware(), handlers.ServeModel)
+
 		user := api.Group("/user")
 		user.Use(middleware.ClerkAuthMiddleware())
 		{
@@ -103,8 +158,25 @@ func setupRouter() *gin.Engine {
 				builds.GET("", handlers.GetUserBuilds)
 				builds.POST("", handlers.SaveBuild)
 				builds.GET("/:id", handlers.GetBuildDetails)
+				builds.GET("/:id/preview", handlers.GetBuildPreview)
 				builds.PUT("/:id", handlers.UpdateBuild)
 				builds.DELETE("/:id", handlers.DeleteBuild)
+				builds.POST("/:id/clone", handlers.CloneBuild)
+				builds.POST("/:id/reprice", handlers.RepriceBuild)
+				builds.GET("/:id/revisions", handlers.GetBuildRevisions)
+				builds.GET("/:id/revisions/diff", handlers.GetBuildRevisionDiff)
+				builds.POST("/:id/revisions/:version/rollback", handlers.RollbackBuild)
+				builds.GET("/:id/shares", handlers.GetBuildShares)
+				builds.POST("/:id/shares", handlers.CreateBuildShare)
+				builds.DELETE("/:id/shares/:shareId", handlers.RevokeBuildShare)
+			}
+
+			reviews := user.Group("/reviews")
+			{
+				reviews.GET("", handlers.GetUserReviews)
+				reviews.POST("", handlers.CreateReview)
+				reviews.PUT("/:id", handlers.UpdateReview)
+				reviews.DELETE("/:id", handlers.DeleteReview)
 			}
 		}
 
@@ -114,10 +186,59 @@ func setupRouter() *gin.Engine {
 			adminProducts := admin.Group("/products")
 			{
 				adminProducts.GET("", handlers.GetAdminProducts)
+				adminProducts.GET("/:id", handlers.GetAdminProduct)
 				adminProducts.POST("", handlers.CreatePart)
 				adminProducts.PUT("/:id", handlers.UpdateAdminProduct)
+				adminProducts.PATCH("/:id", handlers.PatchAdminProduct)
 				adminProducts.PATCH("/:id/anchors", handlers.UpdatePartAnchors)
 				adminProducts.DELETE("/:id", handlers.DeleteAdminProduct)
+				adminProducts.POST("/:id/status", handlers.ChangeProductStatus)
+				adminProducts.GET("/:id/preview", handlers.PreviewProduct)
+				adminProducts.POST("/:id/clone", handlers.CloneProduct)
+				adminProducts.POST("/:id/anchors/copy", handlers.CopyProductAnchors)
+				adminProducts.POST("/:id/anchors/template", handlers.ApplyAnchorTemplate)
+				adminProducts.POST("/:id/anchors/import", handlers.ImportProductAnchors)
+				adminProducts.GET("/:id/anchors/export", handlers.ExportProductModel)
+				adminProducts.POST("/:id/model/analyze", handlers.AnalyzeProductModel)
+				adminProducts.POST("/:id/thumbnail/render", handlers.RenderProductThumbnail)
+				adminProducts.GET("/:id/media", handlers.GetProductMedia)
+				adminProducts.POST("/:id/media", handlers.CreateProductMedia)
+				adminProducts.PUT("/:id/media/:mediaId", handlers.UpdateProductMedia)
+				adminProducts.DELETE("/:id/media/:mediaId", handlers.DeleteProductMedia)
+			}
+
+			adminCategories := admin.Group("/categories")
+			{
+				adminCategories.POST("", handlers.CreateCategory)
+				adminCategories.PUT("/:id", handlers.UpdateCategory)
+				adminCategories.DELETE("/:id", handlers.DeleteCategory)
+			}
+
+			adminFamilies := admin.Group("/families")
+			{
+				adminFamilies.GET("", handlers.GetAdminFamilies)
+				adminFamilies.GET("/:id", handlers.GetAdminFamily)
+				adminFamilies.POST("", handlers.CreateFamily)
+				adminFamilies.PUT("/:id", handlers.UpdateFamily)
+				adminFamilies.PATCH("/:id/anchors", handlers.UpdateFamilyAnchors)
+				adminFamilies.DELETE("/:id", handlers.DeleteFamily)
+			}
+
+			adminAnchorTemplates := admin.Group("/anchor-templates")
+			{
+				adminAnchorTemplates.GET("", handlers.GetAnchorTemplates)
+				adminAnchorTemplates.GET("/:id", handlers.GetAnchorTemplate)
+				adminAnchorTemplates.POST("", handlers.CreateAnchorTemplate)
+				adminAnchorTemplates.POST("/:id/versions", handlers.CreateAnchorTemplateVersion)
+				adminAnchorTemplates.GET("/:id/products", handlers.GetAnchorTemplateProducts)
+				adminAnchorTemplates.DELETE("/:id", handlers.DeleteAnchorTemplate)
+			}
+
+			adminReviews := admin.Group("/reviews")
+			{
+				adminReviews.GET("", handlers.GetAdminReviews)
+				adminReviews.PATCH("/:id", handlers.ModerateReview)
+				adminReviews.DELETE("/:id", handlers.DeleteAdminReview)
 			}
 
 			adminParts := admin.Group("/parts")
@@ -127,15 +248,49 @@ func setupRouter() *gin.Engine {
 				adminParts.PATCH("/:id/anchors", handlers.UpdatePartAnchors)
 				adminParts.DELETE("/:id", handlers.DeletePart)
 			}
+
+			admin.GET("/upload-token", handlers.GenerateUploadToken)
+			admin.GET("/download-token", handlers.GenerateAdminDownloadToken)
+			admin.POST("/uploads/:blob/complete", handlers.CompleteUpload)
+			admin.GET("/storage/gc", handlers.PlanBlobGC)
+			admin.POST("/storage/gc", handlers.RunBlobGC)
+			admin.GET("/storage/dedupe", handlers.PlanAssetDedupe)
+			admin.POST("/storage/dedupe", handlers.RunAssetDedupe)
+			admin.GET("/data-quality/dimensions", handlers.GetDimensionReport)
+			admin.POST("/data-quality/dimensions/autofill", handlers.AutofillDimensions)
 		}
 	}
 
 	return r
 }
 
+// seedTestCategories creates the categories referenced by the test fixtures.
+// Categories are not removed by cleanupDatabase.
+func seedTestCategories() {
+	categories := []models.Category{
+		{Slug: "case", DisplayName: "Case", SortOrder: 10},
+		{Slug: "motherboard", DisplayName: "Motherboard", SortOrder: 20, AnchorTypes: models.StringList{"cpu_socket", "ram_slot"}},
+		{Slug: "cpu", DisplayName: "CPU", SortOrder: 30, RequiredSpecs: models.StringList{"socket"}, AnchorTypes: models.StringList{"cpu_bottom", "LGA1700"}},
+		{Slug: "ram", DisplayName: "RAM", SortOrder: 50, AnchorTypes: models.StringList{"ram_edge", "DDR5"}},
+		{Slug: "gpu", DisplayName: "GPU", SortOrder: 60, AnchorTypes: models.StringList{"pcie_edge"}},
+	}
+	for _, category := range categories {
+		testDB.Where(models.Category{Slug: category.Slug}).FirstOrCreate(&category)
+	}
+}
+
 func cleanupDatabase() {
+	testDB.Exec("DELETE FROM reviews")
+	testDB.Exec("DELETE FROM build_shares")
+	testDB.Exec("DELETE FROM build_revisions")
 	testDB.Exec("DELETE FROM builds")
+	testDB.Exec("DELETE FROM media_assets")
+	testDB.Exec("DELETE FROM asset_uploads")
+	testDB.Exec("DELETE FROM assets")
 	testDB.Exec("DELETE FROM products")
+	testDB.Exec("DELETE FROM anchor_template_versions")
+	testDB.Exec("DELETE FROM anchor_templates")
+	testDB.Exec("DELETE FROM product_families")
 }
 
 func createTestProduct(t *testing.T) models.Product {
@@ -361,7 +516,7 @@ func TestUpdatePartAnchors_Admin(t *testing.T) {
 				"rotation": map[string]float64{
 					"x": 0, "y": 0, "z": 0,
 				},
-				"compatible_types": []string{"test"},
+				"compatible_types": []string{"cpu_socket"},
 			},
 		},
 	}
@@ -369,6 +524,7 @@ func TestUpdatePartAnchors_Admin(t *testing.T) {
 
 	req := httptest.NewRequest("PATCH", fmt.Sprintf("/api/admin/parts/%d/anchors", product.ID), bytes.NewBuffer(jsonBody))
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", fmt.Sprintf(`"%d"`, product.Version))
 	req.Header.Set(middleware.HeaderClerkUserID, "admin")
 	w := httptest.NewRecorder()
 	testRouter.ServeHTTP(w, req)
@@ -556,6 +712,7 @@ func TestUpdateBuild(t *testing.T) {
 
 	req := httptest.NewRequest("PUT", fmt.Sprintf("/api/user/builds/%d", build.ID), bytes.NewBuffer(jsonBody))
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", fmt.Sprintf(`"%d"`, build.Version))
 	req.Header.Set(middleware.HeaderClerkUserID, "test-user")
 	w := httptest.NewRecorder()
 	testRouter.ServeHTTP(w, req)
@@ -747,6 +904,7 @@ func TestUpdateAdminProduct(t *testing.T) {
 
 	req := httptest.NewRequest("PUT", fmt.Sprintf("/api/admin/products/%d", product.ID), bytes.NewBuffer(jsonBody))
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", fmt.Sprintf(`"%d"`, product.Version))
 	req.Header.Set(middleware.HeaderClerkUserID, "admin")
 	w := httptest.NewRecorder()
 	testRouter.ServeHTTP(w, req)
@@ -781,6 +939,7 @@ func TestUpdateAdminProduct_ZeroPrice(t *testing.T) {
 
 	req := httptest.NewRequest("PUT", fmt.Sprintf("/api/admin/products/%d", product.ID), bytes.NewBuffer(jsonBody))
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", fmt.Sprintf(`"%d"`, product.Version))
 	req.Header.Set(middleware.HeaderClerkUserID, "admin")
 	w := httptest.NewRecorder()
 	testRouter.ServeHTTP(w, req)
@@ -800,6 +959,7 @@ func TestUpdateAdminProduct_ZeroPrice(t *testing.T) {
 func TestUpdateAdminProduct_EmptyString(t *testing.T) {
 	cleanupDatabase()
 	product := createTestProduct(t)
+	testDB.Model(&product).Update("status", models.ProductStatusDraft)
 
 	emptyURL := ""
 	body := map[string]interface{}{
@@ -809,6 +969,7 @@ func TestUpdateAdminProduct_EmptyString(t *testing.T) {
 
 	req := httptest.NewRequest("PUT", fmt.Sprintf("/api/admin/products/%d", product.ID), bytes.NewBuffer(jsonBody))
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", fmt.Sprintf(`"%d"`, product.Version))
 	req.Header.Set(middleware.HeaderClerkUserID, "admin")
 	w := httptest.NewRecorder()
 	testRouter.ServeHTTP(w, req)
@@ -842,6 +1003,183 @@ func TestUpdateAdminProduct_NotFound(t *testing.T) {
 	}
 }
 
+func TestUpdateAdminProduct_MissingIfMatch(t *testing.T) {
+	cleanupDatabase()
+	product := createTestProduct(t)
+
+	jsonBody, _ := json.Marshal(map[string]interface{}{"name": "Blind Write"})
+
+	req := httptest.NewRequest("PUT", fmt.Sprintf("/api/admin/products/%d", product.ID), bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.HeaderClerkUserID, "admin")
+	w := httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+
+	if w.Code != http.StatusPreconditionRequired {
+		t.Errorf("expected status %d, got %d: %s", http.StatusPreconditionRequired, w.Code, w.Body.String())
+	}
+}
+
+func TestUpdateAdminProduct_StaleVersion(t *testing.T) {
+	cleanupDatabase()
+	product := createTestProduct(t)
+
+	update := func(name string) *httptest.ResponseRecorder {
+		jsonBody, _ := json.Marshal(map[string]interface{}{"name": name})
+		req := httptest.NewRequest("PUT", fmt.Sprintf("/api/admin/products/%d", product.ID), bytes.NewBuffer(jsonBody))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", fmt.Sprintf(`"%d"`, product.Version))
+		req.Header.Set(middleware.HeaderClerkUserID, "admin")
+		w := httptest.NewRecorder()
+		testRouter.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := update("First Editor"); w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	w := update("Second Editor")
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusPreconditionFailed, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Data models.Product `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Data.Name != "First Editor" {
+		t.Errorf("expected current state with name 'First Editor', got '%s'", response.Data.Name)
+	}
+	if etag := w.Header().Get("ETag"); etag != fmt.Sprintf(`"%d"`, response.Data.Version) {
+		t.Errorf("expected ETag for version %d, got %s", response.Data.Version, etag)
+	}
+}
+
+func TestGetPartDetails_NotModified(t *testing.T) {
+	cleanupDatabase()
+	product := createTestProduct(t)
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/api/parts/%d", product.ID), nil)
+	w := httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected ETag header")
+	}
+
+	req = httptest.NewRequest("GET", fmt.Sprintf("/api/parts/%d", product.ID), nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotModified {
+		t.Errorf("expected status %d, got %d", http.StatusNotModified, w.Code)
+	}
+}
+
+func patchProduct(product models.Product, contentType, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("PATCH", fmt.Sprintf("/api/admin/products/%d", product.ID), bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("If-Match", fmt.Sprintf(`"%d"`, product.Version))
+	req.Header.Set(middleware.HeaderClerkUserID, "admin")
+	w := httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+	return w
+}
+
+func TestPatchAdminProduct_MergePatchSpecKey(t *testing.T) {
+	cleanupDatabase()
+	product := createTestProduct(t)
+	testDB.Model(&product).Update("status", models.ProductStatusDraft)
+
+	w := patchProduct(product, "application/merge-patch+json", `{"technical_specs":{"cores":16,"socket":null}}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var updated models.Product
+	testDB.First(&updated, product.ID)
+	if updated.TechnicalSpecs["cores"].(float64) != 16 {
+		t.Errorf("expected cores 16, got %v", updated.TechnicalSpecs["cores"])
+	}
+	if _, ok := updated.TechnicalSpecs["socket"]; ok {
+		t.Error("expected socket to be removed")
+	}
+	if updated.Name != product.Name || updated.Price != product.Price {
+		t.Error("expected untouched fields to be preserved")
+	}
+	if updated.Version != product.Version+1 {
+		t.Errorf("expected version %d, got %d", product.Version+1, updated.Version)
+	}
+}
+
+func TestPatchAdminProduct_JSONPatch(t *testing.T) {
+	cleanupDatabase()
+	product := createTestProduct(t)
+
+	body := `[
+		{"op":"test","path":"/technical_specs/socket","value":"LGA1700"},
+		{"op":"add","path":"/technical_specs/tdp","value":125},
+		{"op":"add","path":"/anchor_points","value":[]},
+		{"op":"add","path":"/anchor_points/-","value":{"name":"cpu_bottom","compatible_types":["cpu_socket"]}}
+	]`
+	w := patchProduct(product, "application/json-patch+json", body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var updated models.Product
+	testDB.First(&updated, product.ID)
+	if updated.TechnicalSpecs["tdp"].(float64) != 125 || updated.TechnicalSpecs["socket"] != "LGA1700" {
+		t.Errorf("unexpected specs %v", updated.TechnicalSpecs)
+	}
+	if len(updated.AnchorPoints) != 1 || updated.AnchorPoints[0].Name != "cpu_bottom" {
+		t.Errorf("unexpected anchor points %v", updated.AnchorPoints)
+	}
+}
+
+func TestPatchAdminProduct_FailedTestIsAtomic(t *testing.T) {
+	cleanupDatabase()
+	product := createTestProduct(t)
+
+	body := `[
+		{"op":"replace","path":"/price","value":1},
+		{"op":"test","path":"/technical_specs/socket","value":"AM5"}
+	]`
+	w := patchProduct(product, "application/json-patch+json", body)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusUnprocessableEntity, w.Code, w.Body.String())
+	}
+
+	var updated models.Product
+	testDB.First(&updated, product.ID)
+	if updated.Price != product.Price || updated.Version != product.Version {
+		t.Error("expected product to be unchanged after a failed patch")
+	}
+}
+
+func TestPatchAdminProduct_ReadOnlyField(t *testing.T) {
+	cleanupDatabase()
+	product := createTestProduct(t)
+
+	w := patchProduct(product, "application/merge-patch+json", `{"rating_average":5}`)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status %d, got %d: %s", http.StatusUnprocessableEntity, w.Code, w.Body.String())
+	}
+}
+
+func TestPatchAdminProduct_UnsupportedContentType(t *testing.T) {
+	cleanupDatabase()
+	product := createTestProduct(t)
+
+	w := patchProduct(product, "application/json", `{"name":"Plain JSON"}`)
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("expected status %d, got %d: %s", http.StatusUnsupportedMediaType, w.Code, w.Body.String())
+	}
+}
+
 func TestDeleteAdminProduct_SoftDelete(t *testing.T) {
 	cleanupDatabase()
 	product := createTestProduct(t)
@@ -884,3 +1222,2658 @@ func TestDeleteAdminProduct_NotFound(t *testing.T) {
 		t.Errorf("expected status %d, got %d", http.StatusNotFound, w.Code)
 	}
 }
+
+func createTestFamily(t *testing.T) models.ProductFamily {
+	family := models.ProductFamily{
+		Name:     "Test DDR5 Kit",
+		Category: "ram",
+		ModelURL: "https://example.com/ram.glb",
+		TechnicalSpecs: models.TechnicalSpecs{
+			"type":      "DDR5",
+			"speed_mhz": 6000,
+		},
+		AnchorPoints: models.AnchorPoints{
+			{
+				Name:            "ram_edge",
+				Position:        models.Vector3{X: 0, Y: 0, Z: 0},
+				CompatibleTypes: []string{"ram_slot"},
+			},
+		},
+	}
+	if err := testDB.Create(&family).Error; err != nil {
+		t.Fatalf("failed to create test family: %v", err)
+	}
+	return family
+}
+
+func TestGetFamily_ResolvesVariants(t *testing.T) {
+	cleanupDatabase()
+	family := createTestFamily(t)
+
+	variant := models.Product{
+		Name:     "Test DDR5 32GB",
+		SKU:      fmt.Sprintf("TEST-RAM-%d", testDB.NowFunc().UnixNano()),
+		Category: "ram",
+		Price:    129.99,
+		FamilyID: &family.ID,
+		TechnicalSpecs: models.TechnicalSpecs{
+			"capacity_gb": 32,
+		},
+	}
+	testDB.Create(&variant)
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/api/families/%d", family.ID), nil)
+	w := httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Data struct {
+			Variants []models.Product `json:"variants"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if len(response.Data.Variants) != 1 {
+		t.Fatalf("expected 1 variant, got %d", len(response.Data.Variants))
+	}
+	resolved := response.Data.Variants[0]
+	if resolved.ModelURL != family.ModelURL {
+		t.Errorf("expected inherited model URL '%s', got '%s'", family.ModelURL, resolved.ModelURL)
+	}
+	if resolved.TechnicalSpecs["type"] != "DDR5" {
+		t.Errorf("expected inherited spec type 'DDR5', got %v", resolved.TechnicalSpecs["type"])
+	}
+	if len(resolved.AnchorPoints) != 1 {
+		t.Errorf("expected 1 inherited anchor point, got %d", len(resolved.AnchorPoints))
+	}
+}
+
+func TestCreatePart_FamilyCategoryMismatch(t *testing.T) {
+	cleanupDatabase()
+	family := createTestFamily(t)
+
+	body := map[string]interface{}{
+		"name":      "Wrong Variant",
+		"sku":       "WRONG-001",
+		"category":  "gpu",
+		"price":     10,
+		"family_id": family.ID,
+	}
+	jsonBody, _ := json.Marshal(body)
+
+	req := httptest.NewRequest("POST", "/api/admin/products", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.HeaderClerkUserID, "admin")
+	w := httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+}
+
+func TestUpdatePartAnchors_InheritedUpdatesFamily(t *testing.T) {
+	cleanupDatabase()
+	family := createTestFamily(t)
+
+	variants := make([]models.Product, 2)
+	for i := range variants {
+		variants[i] = models.Product{
+			Name:     fmt.Sprintf("Variant %d", i),
+			SKU:      fmt.Sprintf("TEST-VAR-%d-%d", i, testDB.NowFunc().UnixNano()),
+			Category: "ram",
+			FamilyID: &family.ID,
+		}
+		testDB.Create(&variants[i])
+	}
+
+	body := map[string]interface{}{
+		"anchor_points": []map[string]interface{}{
+			{"name": "ram_edge", "position": map[string]float64{"x": 0, "y": 5, "z": 0}},
+			{"name": "ram_edge_alt", "position": map[string]float64{"x": 1, "y": 5, "z": 0}},
+		},
+	}
+	jsonBody, _ := json.Marshal(body)
+
+	req := httptest.NewRequest("PATCH", fmt.Sprintf("/api/admin/products/%d/anchors", variants[0].ID), bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", fmt.Sprintf(`"%d"`, variants[0].Version))
+	req.Header.Set(middleware.HeaderClerkUserID, "admin")
+	w := httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var other models.Product
+	testDB.Preload("Family").First(&other, variants[1].ID)
+	if got := len(other.Resolved().AnchorPoints); got != 2 {
+		t.Errorf("expected sibling variant to inherit 2 anchor points, got %d", got)
+	}
+	if other.Version <= variants[1].Version {
+		t.Errorf("expected sibling variant version to be bumped, got %d", other.Version)
+	}
+}
+
+func TestDeleteFamily_WithVariants(t *testing.T) {
+	cleanupDatabase()
+	family := createTestFamily(t)
+	testDB.Create(&models.Product{
+		Name:     "Variant",
+		SKU:      fmt.Sprintf("TEST-VAR-%d", testDB.NowFunc().UnixNano()),
+		Category: "ram",
+		FamilyID: &family.ID,
+	})
+
+	req := httptest.NewRequest("DELETE", fmt.Sprintf("/api/admin/families/%d", family.ID), nil)
+	req.Header.Set(middleware.HeaderClerkUserID, "admin")
+	w := httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected status %d, got %d", http.StatusConflict, w.Code)
+	}
+}
+
+func TestGetCategories(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/categories", nil)
+	w := httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var response struct {
+		Data []models.Category `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if len(response.Data) == 0 {
+		t.Fatal("expected at least one category")
+	}
+	for i := 1; i < len(response.Data); i++ {
+		if response.Data[i-1].SortOrder > response.Data[i].SortOrder {
+			t.Errorf("expected categories ordered by sort_order, got %d before %d", response.Data[i-1].SortOrder, response.Data[i].SortOrder)
+		}
+	}
+}
+
+func TestCreatePart_UnknownCategory(t *testing.T) {
+	cleanupDatabase()
+
+	body := map[string]interface{}{
+		"name":     "Typo Cooler",
+		"sku":      "COOLER-TYPO",
+		"category": "CPU_COLER",
+		"price":    49.99,
+	}
+	jsonBody, _ := json.Marshal(body)
+
+	req := httptest.NewRequest("POST", "/api/admin/products", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.HeaderClerkUserID, "admin")
+	w := httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+}
+
+func TestUpdatePartAnchors_UnknownCompatibleType(t *testing.T) {
+	cleanupDatabase()
+	product := createTestMotherboard(t)
+
+	body := map[string]interface{}{
+		"anchor_points": []map[string]interface{}{
+			{"name": "ram_slot", "compatible_types": []string{"ram_egde"}},
+		},
+	}
+	jsonBody, _ := json.Marshal(body)
+
+	req := httptest.NewRequest("PATCH", fmt.Sprintf("/api/admin/products/%d/anchors", product.ID), bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", fmt.Sprintf(`"%d"`, product.Version))
+	req.Header.Set(middleware.HeaderClerkUserID, "admin")
+	w := httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+}
+
+func TestDeleteCategory_InUse(t *testing.T) {
+	cleanupDatabase()
+	createTestProduct(t)
+
+	var category models.Category
+	testDB.Where("slug = ?", "cpu").First(&category)
+
+	req := httptest.NewRequest("DELETE", fmt.Sprintf("/api/admin/categories/%d", category.ID), nil)
+	req.Header.Set(middleware.HeaderClerkUserID, "admin")
+	w := httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected status %d, got %d", http.StatusConflict, w.Code)
+	}
+}
+
+func TestGetPartDetails_IncludesMediaGallery(t *testing.T) {
+	cleanupDatabase()
+	product := createTestProduct(t)
+
+	testDB.Create(&models.MediaAsset{ProductID: product.ID, Kind: models.MediaKindImage, BlobName: "b.png", URL: "https://example.com/b.png", SortOrder: 2})
+	testDB.Create(&models.MediaAsset{ProductID: product.ID, Kind: models.MediaKindModelLOD, BlobName: "a.glb", URL: "https://example.com/a.glb", SortOrder: 1, LODLevel: 1})
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/api/parts/%d", product.ID), nil)
+	w := httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var response struct {
+		Data models.Product `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if len(response.Data.Media) != 2 {
+		t.Fatalf("expected 2 media assets, got %d", len(response.Data.Media))
+	}
+	if response.Data.Media[0].BlobName != "a.glb" {
+		t.Errorf("expected media ordered by sort_order, got '%s' first", response.Data.Media[0].BlobName)
+	}
+}
+
+func TestCreateProductMedia(t *testing.T) {
+	cleanupDatabase()
+	product := createTestProduct(t)
+	blobURL := uploadModel(t, "lod.gltf", cubeModel(4))
+	asset := confirmUpload(t, blobURL)
+
+	mediaPath := fmt.Sprintf("/api/admin/products/%d/media", product.ID)
+	// The upload blob is removed once its content is stored under the asset's name
+	w := adminJSON("POST", mediaPath, map[string]interface{}{"blob_name": path.Base(blobURL), "kind": models.MediaKindModelLOD, "lod_level": 1}, 0)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d for an upload that is not an asset, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+
+	w = adminJSON("POST", mediaPath, map[string]interface{}{"blob_name": asset.BlobName, "kind": models.MediaKindModelLOD, "lod_level": 1}, 0)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var response struct {
+		Data models.MediaAsset `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Data.URL != asset.URL {
+		t.Errorf("expected the asset URL %s, got %s", asset.URL, response.Data.URL)
+	}
+}
+
+func TestDeleteProductMedia(t *testing.T) {
+	cleanupDatabase()
+	product := createTestProduct(t)
+
+	media := models.MediaAsset{ProductID: product.ID, Kind: models.MediaKindDatasheet, BlobName: "spec.pdf"}
+	testDB.Create(&media)
+
+	req := httptest.NewRequest("DELETE", fmt.Sprintf("/api/admin/products/%d/media/%d", product.ID, media.ID), nil)
+	req.Header.Set(middleware.HeaderClerkUserID, "admin")
+	w := httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var count int64
+	testDB.Model(&models.MediaAsset{}).Where("id = ?", media.ID).Count(&count)
+	if count != 0 {
+		t.Error("expected media to be deleted")
+	}
+}
+
+func postReview(t *testing.T, userID string, body map[string]interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	jsonBody, _ := json.Marshal(body)
+
+	req := httptest.NewRequest("POST", "/api/user/reviews", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.HeaderClerkUserID, userID)
+	w := httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+	return w
+}
+
+func TestCreateReview_OnePerUser(t *testing.T) {
+	cleanupDatabase()
+	product := createTestProduct(t)
+
+	body := map[string]interface{}{"product_id": product.ID, "rating": 4, "body": "Runs cool"}
+
+	if w := postReview(t, "reviewer", body); w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	if w := postReview(t, "reviewer", body); w.Code != http.StatusConflict {
+		t.Errorf("expected status %d for second review, got %d", http.StatusConflict, w.Code)
+	}
+}
+
+func TestCreateReview_InvalidRating(t *testing.T) {
+	cleanupDatabase()
+	product := createTestProduct(t)
+
+	w := postReview(t, "reviewer", map[string]interface{}{"product_id": product.ID, "rating": 6})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestCreateReview_BuildMustContainProduct(t *testing.T) {
+	cleanupDatabase()
+	product := createTestProduct(t)
+
+	build := models.Build{UserID: "reviewer", Name: "Empty", Components: models.BuildComponents{}}
+	testDB.Create(&build)
+
+	w := postReview(t, "reviewer", map[string]interface{}{"product_id": product.ID, "rating": 5, "build_id": build.ID})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestModerateReview_UpdatesRatingAndSort(t *testing.T) {
+	cleanupDatabase()
+	low := createTestProduct(t)
+	high := createTestProduct(t)
+
+	reviews := []models.Review{
+		{ProductID: low.ID, UserID: "u1", Rating: 2, Status: models.ReviewStatusPending},
+		{ProductID: high.ID, UserID: "u1", Rating: 5, Status: models.ReviewStatusPending},
+		{ProductID: high.ID, UserID: "u2", Rating: 4, Status: models.ReviewStatusPending},
+	}
+	for i := range reviews {
+		testDB.Create(&reviews[i])
+
+		jsonBody, _ := json.Marshal(map[string]interface{}{"status": "approved"})
+		req := httptest.NewRequest("PATCH", fmt.Sprintf("/api/admin/reviews/%d", reviews[i].ID), bytes.NewBuffer(jsonBody))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(middleware.HeaderClerkUserID, "admin")
+		w := httptest.NewRecorder()
+		testRouter.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+	}
+
+	var updated models.Product
+	testDB.First(&updated, high.ID)
+	if updated.RatingCount != 2 || updated.RatingAverage != 4.5 {
+		t.Errorf("expected rating 4.5 from 2 reviews, got %.2f from %d", updated.RatingAverage, updated.RatingCount)
+	}
+
+	req := httptest.NewRequest("GET", "/api/parts?sort=rating", nil)
+	w := httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+
+	var response struct {
+		Data []models.Product `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if len(response.Data) < 2 || response.Data[0].ID != high.ID {
+		t.Errorf("expected highest rated product first")
+	}
+}
+
+func createDraftProduct(t *testing.T, specs models.TechnicalSpecs, anchors models.AnchorPoints) models.Product {
+	product := models.Product{
+		Name:           "Draft CPU",
+		SKU:            fmt.Sprintf("TEST-DRAFT-%d", testDB.NowFunc().UnixNano()),
+		Category:       "cpu",
+		Price:          249.99,
+		ModelURL:       "https://example.com/draft.glb",
+		TechnicalSpecs: specs,
+		AnchorPoints:   anchors,
+		Status:         models.ProductStatusDraft,
+	}
+	if err := testDB.Create(&product).Error; err != nil {
+		t.Fatalf("failed to create draft product: %v", err)
+	}
+	return product
+}
+
+func changeStatus(product models.Product, body map[string]interface{}) *httptest.ResponseRecorder {
+	jsonBody, _ := json.Marshal(body)
+	req := httptest.NewRequest("POST", fmt.Sprintf("/api/admin/products/%d/status", product.ID), bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.HeaderClerkUserID, "admin")
+	w := httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+	return w
+}
+
+func TestCreatePart_StartsAsDraft(t *testing.T) {
+	cleanupDatabase()
+
+	body := map[string]interface{}{
+		"name":     "Unfinished CPU",
+		"sku":      fmt.Sprintf("TEST-NEW-%d", testDB.NowFunc().UnixNano()),
+		"category": "cpu",
+		"price":    199.99,
+	}
+	jsonBody, _ := json.Marshal(body)
+
+	req := httptest.NewRequest("POST", "/api/admin/products", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.HeaderClerkUserID, "admin")
+	w := httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	var created struct {
+		Data models.Product `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if created.Data.Status != models.ProductStatusDraft {
+		t.Errorf("expected status draft, got %s", created.Data.Status)
+	}
+
+	req = httptest.NewRequest("GET", fmt.Sprintf("/api/parts/%d", created.Data.ID), nil)
+	w = httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected draft to be hidden from the public catalog, got status %d", w.Code)
+	}
+}
+
+func TestChangeProductStatus_PublishBlocked(t *testing.T) {
+	cleanupDatabase()
+	product := createDraftProduct(t, models.TechnicalSpecs{"cores": 8}, nil)
+
+	w := changeStatus(product, map[string]interface{}{"status": "published"})
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusUnprocessableEntity, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Problems []string `json:"problems"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if len(response.Problems) != 2 {
+		t.Errorf("expected missing anchors and socket spec, got %v", response.Problems)
+	}
+}
+
+func TestUpdatePublishedProduct_Unpublishable(t *testing.T) {
+	cleanupDatabase()
+	product := createDraftProduct(t, models.TechnicalSpecs{"socket": "LGA1700"}, models.AnchorPoints{{Name: "cpu_bottom"}})
+	if w := changeStatus(product, map[string]interface{}{"status": "published"}); w.Code != http.StatusOK {
+		t.Fatalf("failed to publish product: %s", w.Body.String())
+	}
+	testDB.First(&product, product.ID)
+
+	path := fmt.Sprintf("/api/admin/products/%d", product.ID)
+	w := adminJSON("PUT", path, map[string]interface{}{"anchor_points": []interface{}{}}, product.Version)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusUnprocessableEntity, w.Code, w.Body.String())
+	}
+	var response struct {
+		Problems []string `json:"problems"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if len(response.Problems) != 1 {
+		t.Errorf("expected missing anchors, got %v", response.Problems)
+	}
+
+	w = patchProduct(product, "application/merge-patch+json", `{"technical_specs":{"socket":null}}`)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusUnprocessableEntity, w.Code, w.Body.String())
+	}
+
+	var stored models.Product
+	testDB.First(&stored, product.ID)
+	if len(stored.AnchorPoints) != 1 || stored.TechnicalSpecs["socket"] != "LGA1700" || stored.Version != product.Version {
+		t.Errorf("expected product to be unchanged, got %+v", stored)
+	}
+
+	w = adminJSON("PUT", path, map[string]interface{}{"price": 199.99}, product.Version)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	// Products published before the checks existed can still be edited
+	legacy := createTestProduct(t)
+	w = adminJSON("PUT", fmt.Sprintf("/api/admin/products/%d", legacy.ID), map[string]interface{}{"price": 299.99}, legacy.Version)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+}
+
+func TestUpdateFamily_UnpublishableVariant(t *testing.T) {
+	cleanupDatabase()
+	family := models.ProductFamily{
+		Name:           "Test CPU Family",
+		Category:       "cpu",
+		ModelURL:       "https://example.com/cpu.glb",
+		TechnicalSpecs: models.TechnicalSpecs{"socket": "LGA1700"},
+		AnchorPoints:   models.AnchorPoints{{Name: "cpu_bottom"}},
+	}
+	if err := testDB.Create(&family).Error; err != nil {
+		t.Fatalf("failed to create family: %v", err)
+	}
+	variant := createDraftProduct(t, nil, nil)
+	testDB.Model(&variant).Updates(map[string]interface{}{"family_id": family.ID, "model_url": ""})
+	if w := changeStatus(variant, map[string]interface{}{"status": "published"}); w.Code != http.StatusOK {
+		t.Fatalf("failed to publish variant: %s", w.Body.String())
+	}
+
+	path := fmt.Sprintf("/api/admin/families/%d", family.ID)
+	w := adminJSON("PUT", path, map[string]interface{}{"technical_specs": map[string]interface{}{"cores": 8}}, 0)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusUnprocessableEntity, w.Code, w.Body.String())
+	}
+
+	var stored models.ProductFamily
+	testDB.First(&stored, family.ID)
+	if stored.TechnicalSpecs["socket"] != "LGA1700" {
+		t.Errorf("expected family specs to be unchanged, got %v", stored.TechnicalSpecs)
+	}
+
+	w = adminJSON("PUT", path, map[string]interface{}{"name": "Renamed CPU Family"}, 0)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+}
+
+func TestUpdatePartAnchors_Unpublishable(t *testing.T) {
+	cleanupDatabase()
+	product := createDraftProduct(t, models.TechnicalSpecs{"socket": "LGA1700"}, models.AnchorPoints{{Name: "cpu_bottom"}})
+	if w := changeStatus(product, map[string]interface{}{"status": "published"}); w.Code != http.StatusOK {
+		t.Fatalf("failed to publish product: %s", w.Body.String())
+	}
+	testDB.First(&product, product.ID)
+
+	empty := map[string]interface{}{"anchor_points": []interface{}{}}
+	w := adminJSON("PATCH", fmt.Sprintf("/api/admin/products/%d/anchors", product.ID), empty, product.Version)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status %d, got %d: %s", http.StatusUnprocessableEntity, w.Code, w.Body.String())
+	}
+
+	// Family anchors are checked against every published variant
+	family := models.ProductFamily{
+		Name:         "Test CPU Family",
+		Category:     "cpu",
+		AnchorPoints: models.AnchorPoints{{Name: "cpu_bottom"}},
+	}
+	if err := testDB.Create(&family).Error; err != nil {
+		t.Fatalf("failed to create family: %v", err)
+	}
+	variant := createDraftProduct(t, models.TechnicalSpecs{"socket": "LGA1700"}, nil)
+	testDB.Model(&variant).Update("family_id", family.ID)
+	if w := changeStatus(variant, map[string]interface{}{"status": "published"}); w.Code != http.StatusOK {
+		t.Fatalf("failed to publish variant: %s", w.Body.String())
+	}
+	draft := createDraftProduct(t, models.TechnicalSpecs{"socket": "LGA1700"}, nil)
+	testDB.Model(&draft).Update("family_id", family.ID)
+	testDB.First(&draft, draft.ID)
+
+	w = adminJSON("PATCH", fmt.Sprintf("/api/admin/products/%d/anchors", draft.ID), empty, draft.Version)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusUnprocessableEntity, w.Code, w.Body.String())
+	}
+	var stored models.ProductFamily
+	testDB.First(&stored, family.ID)
+	if len(stored.AnchorPoints) != 1 {
+		t.Errorf("expected family anchors to be unchanged, got %v", stored.AnchorPoints)
+	}
+
+	w = adminJSON("PATCH", fmt.Sprintf("/api/admin/products/%d/anchors", draft.ID), map[string]interface{}{
+		"anchor_points": []map[string]interface{}{{"name": "cpu_bottom"}, {"name": "cooler_mount"}},
+	}, draft.Version)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+}
+
+func TestChangeProductStatus_Publish(t *testing.T) {
+	cleanupDatabase()
+	product := createDraftProduct(t, models.TechnicalSpecs{"socket": "LGA1700"}, models.AnchorPoints{{Name: "cpu_bottom"}})
+
+	w := changeStatus(product, map[string]interface{}{"status": "published"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	req := httptest.NewRequest("GET", "/api/parts", nil)
+	w = httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+
+	var response struct {
+		Count int `json:"count"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Count != 1 {
+		t.Errorf("expected published product in the catalog, got %d products", response.Count)
+	}
+}
+
+func TestChangeProductStatus_Scheduled(t *testing.T) {
+	cleanupDatabase()
+	product := createDraftProduct(t, models.TechnicalSpecs{"socket": "LGA1700"}, models.AnchorPoints{{Name: "cpu_bottom"}})
+
+	publishAt := time.Now().Add(24 * time.Hour)
+	w := changeStatus(product, map[string]interface{}{"status": "published", "publish_at": publishAt})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/api/parts/%d", product.ID), nil)
+	w = httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected scheduled product to be hidden, got status %d", w.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/api/admin/products?status=scheduled", nil)
+	req.Header.Set(middleware.HeaderClerkUserID, "admin")
+	w = httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+
+	var response struct {
+		Meta handlers.PaginationMeta `json:"meta"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Meta.Total != 1 {
+		t.Errorf("expected 1 scheduled product, got %d", response.Meta.Total)
+	}
+}
+
+func TestChangeProductStatus_InvalidTransition(t *testing.T) {
+	cleanupDatabase()
+	product := createDraftProduct(t, nil, nil)
+	testDB.Model(&product).Update("status", models.ProductStatusArchived)
+
+	w := changeStatus(product, map[string]interface{}{"status": "in_review"})
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected status %d, got %d: %s", http.StatusConflict, w.Code, w.Body.String())
+	}
+}
+
+func TestPreviewProduct_Draft(t *testing.T) {
+	cleanupDatabase()
+	product := createDraftProduct(t, models.TechnicalSpecs{}, nil)
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/api/admin/products/%d/preview", product.ID), nil)
+	req.Header.Set(middleware.HeaderClerkUserID, "admin")
+	w := httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Visible         bool     `json:"visible"`
+		PublishProblems []string `json:"publish_problems"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Visible {
+		t.Error("expected draft to be reported as not visible")
+	}
+	if len(response.PublishProblems) == 0 {
+		t.Error("expected publish problems for an incomplete draft")
+	}
+}
+
+func TestCloneProduct(t *testing.T) {
+	cleanupDatabase()
+	product := createTestMotherboard(t)
+	testDB.Create(&models.MediaAsset{ProductID: product.ID, Kind: models.MediaKindImage, BlobName: "front.png", URL: "https://example.com/front.png"})
+
+	req := httptest.NewRequest("POST", fmt.Sprintf("/api/admin/products/%d/clone", product.ID), nil)
+	req.Header.Set(middleware.HeaderClerkUserID, "admin")
+	w := httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Data models.Product `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	var clone models.Product
+	testDB.Preload("Media").First(&clone, response.Data.ID)
+	if clone.ID == product.ID || clone.SKU == product.SKU {
+		t.Fatal("expected a new product with a different SKU")
+	}
+	if !strings.HasPrefix(clone.SKU, product.SKU+"-COPY-") {
+		t.Errorf("expected placeholder SKU, got %s", clone.SKU)
+	}
+	if clone.Status != models.ProductStatusDraft {
+		t.Errorf("expected clone to be a draft, got %s", clone.Status)
+	}
+	if len(clone.AnchorPoints) != len(product.AnchorPoints) || clone.ModelURL != product.ModelURL {
+		t.Error("expected anchors and model to be copied")
+	}
+	if len(clone.Media) != 1 {
+		t.Errorf("expected media gallery to be copied, got %d assets", len(clone.Media))
+	}
+}
+
+func TestCloneProduct_PlaceholderSKU(t *testing.T) {
+	cleanupDatabase()
+	product := createTestProduct(t)
+	longSKU := strings.Repeat("X", 100)
+	testDB.Model(&product).Update("sku", longSKU)
+
+	clone := func(id uint) models.Product {
+		t.Helper()
+		w := adminJSON("POST", fmt.Sprintf("/api/admin/products/%d/clone", id), nil, 0)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+		}
+		var response struct {
+			Data models.Product `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response.Data
+	}
+
+	first := clone(product.ID)
+	if len(first.SKU) != 100 || !strings.HasPrefix(first.SKU, strings.Repeat("X", 86)+"-COPY-") {
+		t.Errorf("expected SKU truncated to 100 characters, got %s", first.SKU)
+	}
+
+	second := clone(first.ID)
+	if strings.Count(second.SKU, "-COPY-") != 1 || len(second.SKU) != 100 || second.SKU == first.SKU {
+		t.Errorf("expected a single copy suffix, got %s", second.SKU)
+	}
+
+	// Multibyte SKUs and names are cut between characters
+	testDB.Model(&product).Updates(map[string]interface{}{"sku": strings.Repeat("Ü", 100), "name": strings.Repeat("名", 255)})
+	third := clone(product.ID)
+	if !utf8.ValidString(third.SKU) || utf8.RuneCountInString(third.SKU) != 100 || !strings.HasPrefix(third.SKU, strings.Repeat("Ü", 86)+"-COPY-") {
+		t.Errorf("expected SKU truncated to 100 characters, got %s", third.SKU)
+	}
+	if !utf8.ValidString(third.Name) || utf8.RuneCountInString(third.Name) != 255 || !strings.HasSuffix(third.Name, " (Copy)") {
+		t.Errorf("expected name truncated to 255 characters, got %s", third.Name)
+	}
+}
+
+func TestCloneProduct_DuplicateSKU(t *testing.T) {
+	cleanupDatabase()
+	product := createTestProduct(t)
+
+	jsonBody, _ := json.Marshal(map[string]interface{}{"sku": product.SKU})
+	req := httptest.NewRequest("POST", fmt.Sprintf("/api/admin/products/%d/clone", product.ID), bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.HeaderClerkUserID, "admin")
+	w := httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected status %d, got %d: %s", http.StatusConflict, w.Code, w.Body.String())
+	}
+}
+
+func TestCopyProductAnchors(t *testing.T) {
+	cleanupDatabase()
+	source := createTestMotherboard(t)
+	target := models.Product{
+		Name:     "Bare Motherboard",
+		SKU:      fmt.Sprintf("TEST-MB-BARE-%d", testDB.NowFunc().UnixNano()),
+		Category: "motherboard",
+	}
+	testDB.Create(&target)
+
+	jsonBody, _ := json.Marshal(map[string]interface{}{"source_product_id": source.ID})
+	req := httptest.NewRequest("POST", fmt.Sprintf("/api/admin/products/%d/anchors/copy", target.ID), bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", fmt.Sprintf(`"%d"`, target.Version))
+	req.Header.Set(middleware.HeaderClerkUserID, "admin")
+	w := httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var updated models.Product
+	testDB.First(&updated, target.ID)
+	if len(updated.AnchorPoints) != len(source.AnchorPoints) {
+		t.Errorf("expected %d anchor points, got %d", len(source.AnchorPoints), len(updated.AnchorPoints))
+	}
+}
+
+func TestCopyProductAnchors_CategoryMismatch(t *testing.T) {
+	cleanupDatabase()
+	source := createTestMotherboard(t)
+	target := createTestProduct(t)
+
+	jsonBody, _ := json.Marshal(map[string]interface{}{"source_product_id": source.ID})
+	req := httptest.NewRequest("POST", fmt.Sprintf("/api/admin/products/%d/anchors/copy", target.ID), bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", fmt.Sprintf(`"%d"`, target.Version))
+	req.Header.Set(middleware.HeaderClerkUserID, "admin")
+	w := httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+}
+
+func adminJSON(method, path string, body interface{}, ifMatch uint) *httptest.ResponseRecorder {
+	jsonBody, _ := json.Marshal(body)
+	req := httptest.NewRequest(method, path, bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	if ifMatch != 0 {
+		req.Header.Set("If-Match", fmt.Sprintf(`"%d"`, ifMatch))
+	}
+	req.Header.Set(middleware.HeaderClerkUserID, "admin")
+	w := httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+	return w
+}
+
+func createTestAnchorTemplate(t *testing.T, source models.Product) models.AnchorTemplate {
+	w := adminJSON("POST", "/api/admin/anchor-templates", map[string]interface{}{
+		"name":              fmt.Sprintf("ATX %d", testDB.NowFunc().UnixNano()),
+		"category":          "motherboard",
+		"form_factor":       "ATX",
+		"source_product_id": source.ID,
+		"origin":            map[string]float64{"x": 0, "y": 1, "z": 0},
+	}, 0)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("failed to create anchor template: %d %s", w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Data models.AnchorTemplate `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return response.Data
+}
+
+func TestCreateAnchorTemplate_FromProduct(t *testing.T) {
+	cleanupDatabase()
+	source := createTestMotherboard(t)
+	template := createTestAnchorTemplate(t, source)
+
+	if template.LatestVersion != 1 || len(template.Versions) != 1 {
+		t.Fatalf("expected a single initial version, got %+v", template)
+	}
+	anchors := template.Versions[0].AnchorPoints
+	if len(anchors) != len(source.AnchorPoints) {
+		t.Fatalf("expected %d anchors, got %d", len(source.AnchorPoints), len(anchors))
+	}
+	if anchors[0].Position.Y != source.AnchorPoints[0].Position.Y-1 {
+		t.Errorf("expected positions relative to the origin, got %+v", anchors[0].Position)
+	}
+}
+
+func TestApplyAnchorTemplate(t *testing.T) {
+	cleanupDatabase()
+	source := createTestMotherboard(t)
+	template := createTestAnchorTemplate(t, source)
+
+	target := models.Product{
+		Name:     "Bare ATX Board",
+		SKU:      fmt.Sprintf("TEST-MB-TPL-%d", testDB.NowFunc().UnixNano()),
+		Category: "motherboard",
+	}
+	testDB.Create(&target)
+
+	w := adminJSON("POST", fmt.Sprintf("/api/admin/products/%d/anchors/template", target.ID), map[string]interface{}{
+		"template_id": template.ID,
+		"offset":      map[string]float64{"x": 5, "y": 1, "z": 0},
+		"scale":       map[string]float64{"x": 2, "y": 1, "z": 1},
+	}, target.Version)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var updated models.Product
+	testDB.First(&updated, target.ID)
+	if updated.AnchorTemplateID == nil || *updated.AnchorTemplateID != template.ID || *updated.AnchorTemplateVersion != 1 {
+		t.Fatalf("expected template provenance to be recorded, got %v/%v", updated.AnchorTemplateID, updated.AnchorTemplateVersion)
+	}
+	want := source.AnchorPoints[0].Position.X*2 + 5
+	if updated.AnchorPoints[0].Position.X != want || updated.AnchorPoints[0].Position.Y != source.AnchorPoints[0].Position.Y {
+		t.Errorf("expected transformed position x=%v, got %+v", want, updated.AnchorPoints[0].Position)
+	}
+}
+
+func TestAnchorTemplateVersions_OutdatedProducts(t *testing.T) {
+	cleanupDatabase()
+	source := createTestMotherboard(t)
+	template := createTestAnchorTemplate(t, source)
+
+	target := createTestMotherboard(t)
+	w := adminJSON("POST", fmt.Sprintf("/api/admin/products/%d/anchors/template", target.ID), map[string]interface{}{
+		"template_id": template.ID,
+	}, target.Version)
+	if w.Code != http.StatusOK {
+		t.Fatalf("failed to apply template: %d %s", w.Code, w.Body.String())
+	}
+
+	w = adminJSON("POST", fmt.Sprintf("/api/admin/anchor-templates/%d/versions", template.ID), map[string]interface{}{
+		"anchor_points": []map[string]interface{}{
+			{"name": "cpu_socket", "position": map[string]float64{"x": 0, "y": 0.5, "z": 0}},
+		},
+		"changelog": "Fix socket height",
+	}, 0)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/api/admin/anchor-templates/%d/products", template.ID), nil)
+	req.Header.Set(middleware.HeaderClerkUserID, "admin")
+	w = httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+
+	var response struct {
+		Count         int `json:"count"`
+		OutdatedCount int `json:"outdated_count"`
+		LatestVersion int `json:"latest_version"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Count != 1 || response.OutdatedCount != 1 || response.LatestVersion != 2 {
+		t.Errorf("expected one outdated product on version 2 template, got %+v", response)
+	}
+}
+
+func TestApplyAnchorTemplate_CategoryMismatch(t *testing.T) {
+	cleanupDatabase()
+	template := createTestAnchorTemplate(t, createTestMotherboard(t))
+	target := createTestProduct(t)
+
+	w := adminJSON("POST", fmt.Sprintf("/api/admin/products/%d/anchors/template", target.ID), map[string]interface{}{
+		"template_id": template.ID,
+	}, target.Version)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+}
+
+func TestAnchorWrites_ClearTemplate(t *testing.T) {
+	cleanupDatabase()
+	source := createTestMotherboard(t)
+	template := createTestAnchorTemplate(t, source)
+	target := createTestMotherboard(t)
+
+	apply := func() models.Product {
+		t.Helper()
+		testDB.First(&target, target.ID)
+		w := adminJSON("POST", fmt.Sprintf("/api/admin/products/%d/anchors/template", target.ID), map[string]interface{}{
+			"template_id": template.ID,
+		}, target.Version)
+		if w.Code != http.StatusOK {
+			t.Fatalf("failed to apply template: %d %s", w.Code, w.Body.String())
+		}
+		testDB.First(&target, target.ID)
+		return target
+	}
+	writes := map[string]func(models.Product) *httptest.ResponseRecorder{
+		"editor": func(p models.Product) *httptest.ResponseRecorder {
+			return adminJSON("PATCH", fmt.Sprintf("/api/admin/parts/%d/anchors", p.ID), map[string]interface{}{"anchor_points": source.AnchorPoints}, p.Version)
+		},
+		"copy": func(p models.Product) *httptest.ResponseRecorder {
+			return adminJSON("POST", fmt.Sprintf("/api/admin/products/%d/anchors/copy", p.ID), map[string]interface{}{"source_product_id": source.ID}, p.Version)
+		},
+		"update": func(p models.Product) *httptest.ResponseRecorder {
+			return adminJSON("PUT", fmt.Sprintf("/api/admin/products/%d", p.ID), map[string]interface{}{"anchor_points": source.AnchorPoints}, p.Version)
+		},
+	}
+
+	for name, write := range writes {
+		if w := write(apply()); w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status %d, got %d: %s", name, http.StatusOK, w.Code, w.Body.String())
+		}
+		var updated models.Product
+		testDB.First(&updated, target.ID)
+		if updated.AnchorTemplateID != nil || updated.AnchorTemplateVersion != nil {
+			t.Errorf("%s: expected template provenance to be cleared, got %v/%v", name, updated.AnchorTemplateID, updated.AnchorTemplateVersion)
+		}
+	}
+}
+
+func TestApplyAnchorTemplate_InheritedAnchors(t *testing.T) {
+	cleanupDatabase()
+	source := createTestMotherboard(t)
+	template := createTestAnchorTemplate(t, source)
+
+	family := models.ProductFamily{
+		Name:         "Test ATX Boards",
+		Category:     "motherboard",
+		AnchorPoints: models.AnchorPoints{{Name: "ram_slot_1"}},
+	}
+	testDB.Create(&family)
+	variant := models.Product{
+		Name:     "Family ATX Board",
+		SKU:      fmt.Sprintf("TEST-MB-FAM-%d", testDB.NowFunc().UnixNano()),
+		Category: "motherboard",
+		FamilyID: &family.ID,
+	}
+	testDB.Create(&variant)
+
+	path := fmt.Sprintf("/api/admin/products/%d/anchors/template", variant.ID)
+	w := adminJSON("POST", path, map[string]interface{}{"template_id": template.ID}, variant.Version)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	testDB.First(&family, family.ID)
+	testDB.First(&variant, variant.ID)
+	if len(family.AnchorPoints) != len(source.AnchorPoints) || len(variant.AnchorPoints) != 0 || variant.AnchorTemplateID != nil {
+		t.Fatalf("expected the family anchors to be replaced, got family %v, variant %v (template %v)", family.AnchorPoints, variant.AnchorPoints, variant.AnchorTemplateID)
+	}
+
+	w = adminJSON("POST", path+"?scope=variant", map[string]interface{}{"template_id": template.ID}, variant.Version)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	testDB.First(&variant, variant.ID)
+	if len(variant.AnchorPoints) != len(source.AnchorPoints) || variant.AnchorTemplateID == nil || *variant.AnchorTemplateID != template.ID {
+		t.Errorf("expected an override with template provenance, got %v (template %v)", variant.AnchorPoints, variant.AnchorTemplateID)
+	}
+}
+
+func importAnchors(product models.Product, contentType, model string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", fmt.Sprintf("/api/admin/products/%d/anchors/import", product.ID), strings.NewReader(model))
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set(middleware.HeaderClerkUserID, "admin")
+	w := httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+	return w
+}
+
+const anchoredMotherboard = `{
+	"asset": {"version": "2.0"},
+	"scenes": [{"nodes": [0]}],
+	"nodes": [
+		{"name": "Board", "translation": [0, 1, 0], "children": [1, 2]},
+		{"name": "anchor_cpu_socket", "translation": [0.5, 0, 0]},
+		{"name": "PCIe", "rotation": [0.7071068, 0, 0, 0.7071068], "extras": {"anchor": {"type": "pcie_x16", "label": "pcie_1", "direction": "output", "compatible_types": ["gpu"]}}}
+	]
+}`
+
+func TestImportProductAnchors(t *testing.T) {
+	cleanupDatabase()
+	product := createTestMotherboard(t)
+
+	w := importAnchors(product, "model/gltf+json", anchoredMotherboard)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if etag := w.Header().Get("ETag"); etag != fmt.Sprintf(`"%d"`, product.Version) {
+		t.Errorf("expected ETag of the product, got %q", etag)
+	}
+
+	var response struct {
+		Data handlers.AnchorImportResponse `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	proposal := response.Data
+
+	if len(proposal.Imported) != 2 || len(proposal.AnchorPoints) != 3 {
+		t.Fatalf("expected 2 imported and 3 merged anchors, got %+v", proposal)
+	}
+	if !reflect.DeepEqual(proposal.Updated, []string{"cpu_socket"}) || !reflect.DeepEqual(proposal.Added, []string{"pcie_1"}) || !reflect.DeepEqual(proposal.Kept, []string{"ram_slot_1"}) {
+		t.Errorf("unexpected merge outcome: updated %v, added %v, kept %v", proposal.Updated, proposal.Added, proposal.Kept)
+	}
+
+	// The existing socket moves but keeps its compatible types
+	socket := proposal.AnchorPoints[0]
+	if socket.Position.X != 0.5 || socket.Position.Y != 1 || socket.ConnectionAxis != "Y_NEG" || !reflect.DeepEqual(socket.CompatibleTypes, []string{"cpu", "LGA1700"}) {
+		t.Errorf("unexpected merged socket: %+v", socket)
+	}
+	if pcie := proposal.AnchorPoints[2]; pcie.Name != "pcie_x16" || pcie.ConnectionAxis != "Z_NEG" {
+		t.Errorf("unexpected imported PCIe slot: %+v", pcie)
+	}
+
+	// Nothing is applied until the proposal is saved
+	var unchanged models.Product
+	testDB.First(&unchanged, product.ID)
+	if unchanged.Version != product.Version || len(unchanged.AnchorPoints) != 2 || unchanged.AnchorPoints[0].Position.X != 0 {
+		t.Error("expected the product to be left unchanged")
+	}
+
+	w = adminJSON("PATCH", fmt.Sprintf("/api/admin/products/%d/anchors", product.ID), map[string]interface{}{
+		"anchor_points": proposal.AnchorPoints,
+	}, product.Version)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the proposal to be saved, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestImportProductAnchors_GLB(t *testing.T) {
+	cleanupDatabase()
+	product := createTestMotherboard(t)
+
+	// GLB container with only the JSON chunk
+	chunk := []byte(anchoredMotherboard)
+	for len(chunk)%4 != 0 {
+		chunk = append(chunk, ' ')
+	}
+	model := new(bytes.Buffer)
+	for _, word := range []uint32{0x46546C67, 2, uint32(20 + len(chunk)), uint32(len(chunk)), 0x4E4F534A} {
+		binary.Write(model, binary.LittleEndian, word)
+	}
+	model.Write(chunk)
+
+	w := importAnchors(product, "model/gltf-binary", model.String())
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+}
+
+func TestImportProductAnchors_Invalid(t *testing.T) {
+	cleanupDatabase()
+	product := createTestMotherboard(t)
+
+	tests := []struct {
+		name, contentType, model string
+		status                   int
+	}{
+		{"unsupported content type", "application/json", anchoredMotherboard, http.StatusUnsupportedMediaType},
+		{"not a model", "application/octet-stream", "solid cube", http.StatusUnprocessableEntity},
+		{"no anchors", "model/gltf+json", `{"asset":{"version":"2.0"},"nodes":[{"name":"Board"}]}`, http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		if w := importAnchors(product, tt.contentType, tt.model); w.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.name, tt.status, w.Code, w.Body.String())
+		}
+	}
+}
+
+// uploadModel uploads a model through a signed upload URL, as the admin
+// dashboard does, and returns its blob URL
+func uploadModel(t *testing.T, filename, model string) string {
+	w := adminJSON("GET", "/api/admin/upload-token?filename="+filename, nil, 0)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected an upload token, got %d: %s", w.Code, w.Body.String())
+	}
+	var token handlers.UploadTokenResponse
+	json.Unmarshal(w.Body.Bytes(), &token)
+
+	req := httptest.NewRequest("PUT", token.UploadURL, strings.NewReader(model))
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+	w = httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected the upload to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+	return token.BlobURL
+}
+
+func TestImportProductAnchors_StoredModel(t *testing.T) {
+	cleanupDatabase()
+	product := createTestMotherboard(t)
+	blobURL := uploadModel(t, "board.gltf", anchoredMotherboard)
+	if !strings.HasPrefix(blobURL, "http://storage.test/storage/") {
+		t.Fatalf("unexpected blob URL %q", blobURL)
+	}
+	testDB.Model(&product).Updates(map[string]interface{}{"model_url": blobURL, "sku": `MB "ATX" 1`})
+
+	// Without a body the stored model is read
+	w := importAnchors(product, "application/json", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	w = adminJSON("GET", fmt.Sprintf("/api/admin/products/%d/anchors/export", product.ID), nil, 0)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "model/gltf+json" {
+		t.Fatalf("expected the exported model, got %d: %s", w.Code, w.Body.String())
+	}
+	if _, params, err := mime.ParseMediaType(w.Header().Get("Content-Disposition")); err != nil || params["filename"] != `MB "ATX" 1-anchors.gltf` {
+		t.Errorf("expected the SKU as file name, got %q", w.Header().Get("Content-Disposition"))
+	}
+
+	// Blobs are read back through a signed download URL only
+	req := httptest.NewRequest("GET", blobURL, nil)
+	w = httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected an unsigned read to be rejected, got %d", w.Code)
+	}
+	downloadURL, _, err := testStore.PresignDownload(context.Background(), path.Base(blobURL), time.Minute)
+	if err != nil {
+		t.Fatalf("PresignDownload: %v", err)
+	}
+	req = httptest.NewRequest("GET", downloadURL, nil)
+	w = httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != anchoredMotherboard {
+		t.Errorf("expected the stored model, got %d", w.Code)
+	}
+}
+
+func completeUpload(blobURL string) *httptest.ResponseRecorder {
+	return adminJSON("POST", "/api/admin/uploads/"+path.Base(blobURL)+"/complete", nil, 0)
+}
+
+// confirmUpload confirms an upload and returns the asset it resolved to
+func confirmUpload(t *testing.T, blobURL string) models.Asset {
+	w := completeUpload(blobURL)
+	if w.Code != http.StatusCreated && w.Code != http.StatusOK {
+		t.Fatalf("expected the upload to be confirmed, got %d: %s", w.Code, w.Body.String())
+	}
+	var response struct {
+		Data models.Asset `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return response.Data
+}
+
+func TestCompleteUpload(t *testing.T) {
+	cleanupDatabase()
+	blobURL := uploadModel(t, "board.gltf", anchoredMotherboard)
+
+	w := completeUpload(blobURL)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var response struct {
+		Data models.Asset `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	asset := response.Data
+	if asset.BlobName != asset.SHA256+".gltf" || asset.URL != testStore.URL(asset.BlobName) || asset.Kind != models.MediaKindModel ||
+		asset.ContentType != "model/gltf+json" || asset.SizeBytes != int64(len(anchoredMotherboard)) || len(asset.SHA256) != 64 || asset.UploadedBy != "admin" {
+		t.Errorf("unexpected asset: %+v", asset)
+	}
+
+	// The content moved to the asset's name
+	if _, err := testStore.Stat(context.Background(), asset.BlobName); err != nil {
+		t.Errorf("expected the asset blob to exist: %v", err)
+	}
+	if _, err := testStore.Stat(context.Background(), path.Base(blobURL)); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("expected the upload blob to be removed, got %v", err)
+	}
+
+	// Confirming again returns the same asset
+	w = completeUpload(blobURL)
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if w.Code != http.StatusOK || response.Data.ID != asset.ID {
+		t.Errorf("expected the registered asset, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if w := adminJSON("POST", "/api/admin/uploads/missing.glb/complete", nil, 0); w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for a missing blob, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestCompleteUpload_Deduplicates(t *testing.T) {
+	cleanupDatabase()
+	first := confirmUpload(t, uploadModel(t, "variant-black.glb", "glTF shared case"))
+
+	second := uploadModel(t, "variant-white.glb", "glTF shared case")
+	w := completeUpload(second)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var response struct {
+		Data         models.Asset `json:"data"`
+		Deduplicated bool         `json:"deduplicated"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if !response.Deduplicated || response.Data.ID != first.ID || response.Data.URL != first.URL {
+		t.Errorf("expected the existing asset, got %s", w.Body.String())
+	}
+	if _, err := testStore.Stat(context.Background(), path.Base(second)); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("expected the duplicate upload to be removed, got %v", err)
+	}
+
+	var assets int64
+	testDB.Model(&models.Asset{}).Count(&assets)
+	if assets != 1 {
+		t.Errorf("expected a single asset, got %d", assets)
+	}
+
+	// Each upload still resolves to the shared asset
+	if asset := confirmUpload(t, second); asset.ID != first.ID {
+		t.Errorf("expected the duplicate upload to resolve to asset %d, got %d", first.ID, asset.ID)
+	}
+
+	if different := confirmUpload(t, uploadModel(t, "variant-red.glb", "glTF red case")); different.ID == first.ID {
+		t.Error("expected different content to get its own asset")
+	}
+}
+
+// encodeTestPNG returns an opaque width x height PNG
+func encodeTestPNG(width, height int) string {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for i := range img.Pix {
+		img.Pix[i] = 0xFF
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	return buf.String()
+}
+
+func TestCompleteUpload_ImageSizes(t *testing.T) {
+	cleanupDatabase()
+	ctx := context.Background()
+
+	asset := confirmUpload(t, uploadModel(t, "cpu-photo.png", encodeTestPNG(1600, 800)))
+	if len(asset.Sizes) != 3 {
+		t.Fatalf("expected list, card and detail sizes, got %v", asset.Sizes)
+	}
+	for name, want := range map[string][2]int{"list": {96, 48}, "card": {320, 160}, "detail": {1024, 512}} {
+		blob := path.Base(asset.Sizes[name])
+		if blob != asset.SHA256+"-"+name+".jpg" {
+			t.Errorf("unexpected %s blob %q", name, blob)
+			continue
+		}
+		body, _, err := testStore.Open(ctx, blob)
+		if err != nil {
+			t.Errorf("expected the %s size to be stored: %v", name, err)
+			continue
+		}
+		config, err := jpeg.DecodeConfig(body)
+		body.Close()
+		if err != nil || config.Width != want[0] || config.Height != want[1] {
+			t.Errorf("%s size: got %dx%d (%v), want %dx%d", name, config.Width, config.Height, err, want[0], want[1])
+		}
+	}
+
+	product := createTestProduct(t)
+	w := adminJSON("PUT", fmt.Sprintf("/api/admin/products/%d", product.ID), map[string]interface{}{"thumbnail_url": asset.URL}, product.Version)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	w = publicJSON("GET", fmt.Sprintf("/api/parts/%d", product.ID), "", nil)
+	var response struct {
+		Data models.Product `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if !reflect.DeepEqual(response.Data.ThumbnailSizes, asset.Sizes) {
+		t.Errorf("expected the product to expose the thumbnail sizes, got %s", w.Body.String())
+	}
+
+	// The resized copies are kept as long as the thumbnail is used
+	refs, err := blobgc.References(testDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !refs[path.Base(asset.Sizes["card"])] {
+		t.Error("expected the resized copies to be referenced")
+	}
+}
+
+func TestAssetDedupe(t *testing.T) {
+	cleanupDatabase()
+	ctx := context.Background()
+
+	// Blobs referenced before uploads were content-addressed
+	black := createTestProduct(t)
+	white := createTestProduct(t)
+	red := createTestProduct(t)
+	blackModel := uploadModel(t, "black.glb", "glTF shared case")
+	whiteModel := uploadModel(t, "white.glb", "glTF shared case")
+	redModel := uploadModel(t, "red.glb", "glTF red case")
+	testDB.Model(&black).Updates(map[string]interface{}{"model_url": blackModel, "model_metadata": models.ModelMetadata{ModelURL: blackModel, Valid: true}})
+	testDB.Model(&white).Update("model_url", whiteModel)
+	testDB.Model(&red).Update("model_url", redModel)
+	media := models.MediaAsset{ProductID: red.ID, Kind: models.MediaKindModelLOD, BlobName: path.Base(whiteModel), URL: whiteModel, LODLevel: 1}
+	testDB.Create(&media)
+
+	w := adminJSON("GET", "/api/admin/storage/dedupe", nil, 0)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var response struct {
+		Data handlers.AssetDedupeReport `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	plan := response.Data
+	// The test products' example.com thumbnail is not in the store
+	if !plan.DryRun || plan.Scanned != 4 || len(plan.External) != 1 || len(plan.Moves) != 3 || len(plan.Failed) != 0 || plan.SavedBytes != int64(len("glTF shared case")) {
+		t.Fatalf("unexpected dry run report: %+v", plan)
+	}
+	testDB.First(&black, black.ID)
+	if black.ModelURL != blackModel {
+		t.Fatal("expected the dry run to change nothing")
+	}
+
+	w = adminJSON("POST", "/api/admin/storage/dedupe", nil, 0)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Data.ProductUpdates != 3 || response.Data.MediaUpdates != 1 || len(response.Data.Failed) != 0 {
+		t.Errorf("unexpected report: %+v", response.Data)
+	}
+
+	testDB.First(&black, black.ID)
+	testDB.First(&white, white.ID)
+	testDB.First(&red, red.ID)
+	if black.ModelURL != white.ModelURL || black.ModelURL == red.ModelURL || black.ModelURL == blackModel {
+		t.Errorf("expected identical models to share an asset: %s, %s, %s", black.ModelURL, white.ModelURL, red.ModelURL)
+	}
+	if black.Version != 2 || black.ModelMetadata == nil || black.ModelMetadata.ModelURL != black.ModelURL {
+		t.Errorf("expected the version and metadata to follow the new URL: %d %+v", black.Version, black.ModelMetadata)
+	}
+	var asset models.Asset
+	if err := testDB.Where("url = ?", black.ModelURL).First(&asset).Error; err != nil || asset.BlobName != path.Base(black.ModelURL) {
+		t.Errorf("expected a content-addressed asset for %s: %v", black.ModelURL, err)
+	}
+	testDB.First(&media, media.ID)
+	if media.URL != black.ModelURL || media.BlobName != path.Base(black.ModelURL) {
+		t.Errorf("expected the media to follow the asset, got %s (%s)", media.URL, media.BlobName)
+	}
+	if _, err := testStore.Stat(ctx, path.Base(blackModel)); err != nil {
+		t.Errorf("expected the replaced blob to be left to the collector: %v", err)
+	}
+
+	// A second run has nothing left to move
+	w = adminJSON("GET", "/api/admin/storage/dedupe", nil, 0)
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if len(response.Data.Moves) != 0 || response.Data.Addressed != 2 {
+		t.Errorf("expected every reference to be content-addressed: %+v", response.Data)
+	}
+}
+
+func TestCompleteUpload_ContentMismatch(t *testing.T) {
+	cleanupDatabase()
+
+	tests := []struct{ filename, content string }{
+		{"photo.png", "not an image"},
+		{"model.glb", anchoredMotherboard}, // glTF JSON uploaded as GLB
+		{"datasheet.pdf", ""},
+	}
+	for _, tt := range tests {
+		blobURL := uploadModel(t, tt.filename, tt.content)
+		if w := completeUpload(blobURL); w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.filename, http.StatusUnprocessableEntity, w.Code, w.Body.String())
+		}
+		if _, err := testStore.Stat(context.Background(), path.Base(blobURL)); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("%s: expected the rejected blob to be deleted, got %v", tt.filename, err)
+		}
+	}
+}
+
+func TestUpdateAdminProduct_RequiresConfirmedUpload(t *testing.T) {
+	cleanupDatabase()
+	product := createDraftProduct(t, nil, nil)
+	productPath := fmt.Sprintf("/api/admin/products/%d", product.ID)
+
+	unconfirmed := uploadModel(t, "cpu.glb", "glTF")
+	w := adminJSON("PUT", productPath, map[string]interface{}{"model_url": unconfirmed}, product.Version)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected an unconfirmed upload to be rejected, got %d: %s", w.Code, w.Body.String())
+	}
+
+	image := confirmUpload(t, uploadModel(t, "cpu.png", "\x89PNG\r\n\x1a\n"))
+	w = adminJSON("PUT", productPath, map[string]interface{}{"model_url": image.URL}, product.Version)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected an image to be rejected as a model, got %d: %s", w.Code, w.Body.String())
+	}
+
+	model := confirmUpload(t, unconfirmed)
+	w = adminJSON("PUT", productPath, map[string]interface{}{"model_url": model.URL, "thumbnail_url": image.URL}, product.Version)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	// URLs set before uploads were confirmed can be sent back unchanged
+	legacy := createTestProduct(t)
+	w = adminJSON("PUT", fmt.Sprintf("/api/admin/products/%d", legacy.ID), map[string]interface{}{"model_url": legacy.ModelURL, "price": 199.99}, legacy.Version)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected an unchanged model URL to be accepted, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// publicJSON sends a request to a public endpoint, as userID when set
+func publicJSON(method, path, userID string, body interface{}) *httptest.ResponseRecorder {
+	var reader *bytes.Reader
+	if body != nil {
+		jsonBody, _ := json.Marshal(body)
+		reader = bytes.NewReader(jsonBody)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if userID != "" {
+		req.Header.Set(middleware.HeaderClerkUserID, userID)
+	}
+	w := httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+	return w
+}
+
+func TestGenerateDownloadToken_Restricted(t *testing.T) {
+	cleanupDatabase()
+	published := createTestProduct(t)
+	draft := createDraftProduct(t, nil, nil)
+	testDB.Model(&published).Update("model_url", testStore.URL("published.glb"))
+	testDB.Model(&draft).Update("model_url", testStore.URL("draft.glb"))
+	retired := createDraftProduct(t, nil, nil)
+	testDB.Model(&retired).Updates(map[string]interface{}{"model_url": testStore.URL("mine.glb"), "status": models.ProductStatusArchived})
+	// Components are saved as sent: a forged model URL grants nothing
+	testDB.Create(&models.Build{UserID: "builder", Name: "Mine", Components: models.BuildComponents{
+		{ID: retired.ID, Name: "Old GPU", ModelURL: testStore.URL("mine.glb")},
+		{ID: published.ID, Name: "Forged", ModelURL: testStore.URL("draft.glb")},
+	}})
+
+	tests := []struct {
+		blob, userID string
+		status       int
+	}{
+		{"published.glb", "", http.StatusOK},
+		{"draft.glb", "", http.StatusNotFound},
+		{"draft.glb", "builder", http.StatusNotFound},
+		{"mine.glb", "", http.StatusNotFound},
+		{"mine.glb", "other", http.StatusNotFound},
+		{"mine.glb", "builder", http.StatusOK},
+		{"draft.glb", "admin", http.StatusOK},
+		{"unknown.glb", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		w := publicJSON("GET", "/api/download-token?blob="+tt.blob, tt.userID, nil)
+		if w.Code != tt.status {
+			t.Errorf("%s as %q: expected status %d, got %d: %s", tt.blob, tt.userID, tt.status, w.Code, w.Body.String())
+		}
+	}
+
+}
+
+func TestCreateDownloadTokens(t *testing.T) {
+	cleanupDatabase()
+	published := createTestProduct(t)
+	draft := createDraftProduct(t, nil, nil)
+	testDB.Model(&published).Update("model_url", testStore.URL("published.glb"))
+	testDB.Model(&draft).Update("model_url", testStore.URL("draft.glb"))
+
+	w := publicJSON("POST", "/api/download-tokens", "", map[string]interface{}{
+		"blobs": []string{"published.glb", "draft.glb", "published.glb"},
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var response struct {
+		Data   map[string]handlers.DownloadToken `json:"data"`
+		Denied []string                          `json:"denied"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if len(response.Data) != 1 || response.Data["published.glb"].DownloadURL == "" || !reflect.DeepEqual(response.Denied, []string{"draft.glb"}) {
+		t.Errorf("unexpected tokens: %s", w.Body.String())
+	}
+
+	if w := publicJSON("POST", "/api/download-tokens", "", map[string]interface{}{"blobs": []string{}}); w.Code != http.StatusBadRequest {
+		t.Errorf("expected an empty batch to be rejected, got %d", w.Code)
+	}
+}
+
+func TestServeModel(t *testing.T) {
+	cleanupDatabase()
+	product := createTestProduct(t)
+	blobURL := uploadModel(t, "gpu.glb", "glTF model content")
+	testDB.Model(&product).Update("model_url", blobURL)
+	modelPath := "/api/models/" + path.Base(blobURL)
+
+	w := publicJSON("GET", modelPath, "", nil)
+	if w.Code != http.StatusOK || w.Body.String() != "glTF model content" {
+		t.Fatalf("expected the model, got %d: %s", w.Code, w.Body.String())
+	}
+	etag := w.Header().Get("ETag")
+	if etag == "" || w.Header().Get("Content-Type") != "model/gltf-binary" || w.Header().Get("Cache-Control") != "private, max-age=3600" {
+		t.Errorf("unexpected headers: %v", w.Header())
+	}
+
+	req := httptest.NewRequest("GET", modelPath, nil)
+	req.Header.Set("Range", "bytes=0-3")
+	w = httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+	if w.Code != http.StatusPartialContent || w.Body.String() != "glTF" {
+		t.Errorf("expected the first 4 bytes, got %d: %q", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", modelPath, nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("expected status %d, got %d", http.StatusNotModified, w.Code)
+	}
+
+	draft := uploadModel(t, "draft.glb", "glTF draft")
+	if w := publicJSON("GET", "/api/models/"+path.Base(draft), "", nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected an unreferenced model to be hidden, got %d", w.Code)
+	}
+}
+
+func TestBlobGC(t *testing.T) {
+	cleanupDatabase()
+	ctx := context.Background()
+	for _, blob := range mustListBlobs(t) {
+		testStore.Delete(ctx, blob.Name)
+	}
+
+	product := createTestProduct(t)
+	productModel := uploadModel(t, "product.glb", "glTF")
+	buildModel := uploadModel(t, "build.glb", "glTF")
+	orphan := uploadModel(t, "orphan.glb", "glTF")
+	fresh := uploadModel(t, "fresh.glb", "glTF")
+	testDB.Model(&product).Update("model_url", productModel)
+	testDB.Create(&models.Build{UserID: "user", Name: "Snapshot", Components: models.BuildComponents{{ID: 1, Name: "Old GPU", ModelURL: buildModel}}})
+
+	// Age every blob but the fresh upload past the grace period
+	old := time.Now().Add(-48 * time.Hour)
+	for _, blobURL := range []string{productModel, buildModel, orphan} {
+		os.Chtimes(filepath.Join(testBlobDir, path.Base(blobURL)), old, old)
+	}
+
+	w := adminJSON("GET", "/api/admin/storage/gc?grace_hours=24", nil, 0)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var response struct {
+		Data blobgc.Report `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	report := response.Data
+	if !report.DryRun || report.Scanned != 4 || report.Referenced != 2 || report.Recent != 1 || len(report.Orphans) != 1 || report.Orphans[0].Name != path.Base(orphan) {
+		t.Fatalf("unexpected dry run report: %+v", report)
+	}
+	if len(mustListBlobs(t)) != 4 {
+		t.Error("expected the dry run to delete nothing")
+	}
+
+	if w := adminJSON("POST", "/api/admin/storage/gc?grace_hours=0", nil, 0); w.Code != http.StatusBadRequest {
+		t.Errorf("expected a grace period below the minimum to be rejected, got %d", w.Code)
+	}
+
+	w = adminJSON("POST", "/api/admin/storage/gc?grace_hours=24", nil, 0)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if !reflect.DeepEqual(response.Data.Deleted, []string{path.Base(orphan)}) {
+		t.Errorf("expected only the orphan to be deleted, got %v", response.Data.Deleted)
+	}
+	if _, err := testStore.Stat(ctx, path.Base(fresh)); err != nil {
+		t.Errorf("expected the fresh upload to be kept: %v", err)
+	}
+}
+
+func mustListBlobs(t *testing.T) []storage.BlobInfo {
+	blobs, err := testStore.List(context.Background(), "")
+	if err != nil {
+		t.Fatalf("failed to list blobs: %v", err)
+	}
+	return blobs
+}
+
+func TestExportProductModel_NoModel(t *testing.T) {
+	cleanupDatabase()
+	product := createDraftProduct(t, nil, nil)
+	testDB.Model(&product).Update("model_url", "")
+
+	w := adminJSON("GET", fmt.Sprintf("/api/admin/products/%d/anchors/export", product.ID), nil, 0)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+
+	w = adminJSON("GET", "/api/admin/products/999999/anchors/export", nil, 0)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d: %s", http.StatusNotFound, w.Code, w.Body.String())
+	}
+}
+
+func TestUpdateAdminProduct_AnalyzesModel(t *testing.T) {
+	cleanupDatabase()
+	product := createDraftProduct(t, nil, nil)
+	model := confirmUpload(t, uploadModel(t, "cube.gltf", cubeModel(4)))
+
+	path := fmt.Sprintf("/api/admin/products/%d", product.ID)
+	w := adminJSON("PUT", path, map[string]interface{}{"model_url": model.URL}, product.Version)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var response struct {
+		Data models.Product `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if meta := response.Data.ModelMetadata; meta == nil || meta.ModelURL != model.URL || !meta.Valid || meta.Triangles == 0 {
+		t.Fatalf("expected the new model to be analysed, got %+v", meta)
+	}
+
+	w = adminJSON("PUT", path, map[string]interface{}{"model_url": ""}, response.Data.Version)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var stored models.Product
+	testDB.First(&stored, product.ID)
+	if stored.ModelMetadata != nil || stored.ModelFlagged {
+		t.Errorf("expected metadata to be cleared with the model, got %+v", stored.ModelMetadata)
+	}
+}
+
+func TestUpdateFamily_AnalyzesInheritedModel(t *testing.T) {
+	cleanupDatabase()
+	family := createTestFamily(t)
+	variant := createDraftProduct(t, nil, nil)
+	testDB.Model(&variant).Updates(map[string]interface{}{"family_id": family.ID, "category": family.Category, "model_url": ""})
+	override := createDraftProduct(t, nil, nil)
+	testDB.Model(&override).Updates(map[string]interface{}{"family_id": family.ID, "category": family.Category})
+	model := confirmUpload(t, uploadModel(t, "ram.gltf", cubeModel(4)))
+
+	w := adminJSON("PUT", fmt.Sprintf("/api/admin/families/%d", family.ID), map[string]interface{}{"model_url": model.URL}, 0)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	testDB.First(&variant, variant.ID)
+	if meta := variant.ModelMetadata; meta == nil || meta.ModelURL != model.URL || !meta.Valid {
+		t.Errorf("expected the inherited model to be analysed, got %+v", meta)
+	}
+	testDB.First(&override, override.ID)
+	if override.ModelMetadata != nil {
+		t.Errorf("expected a variant with its own model to be left alone, got %+v", override.ModelMetadata)
+	}
+}
+
+func TestAnalyzeProductModel_NoModel(t *testing.T) {
+	cleanupDatabase()
+	product := createDraftProduct(t, nil, nil)
+	testDB.Model(&product).Update("model_url", "")
+
+	w := adminJSON("POST", fmt.Sprintf("/api/admin/products/%d/model/analyze", product.ID), nil, 0)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+}
+
+func TestGetAdminProducts_FilterByModel(t *testing.T) {
+	cleanupDatabase()
+	flagged := createTestProduct(t)
+	testDB.Model(&flagged).Updates(map[string]interface{}{
+		"model_metadata": models.ModelMetadata{ModelURL: flagged.ModelURL, Valid: true, Triangles: 2000000, OverBudget: models.StringList{"2000000 triangles exceed 500000"}},
+		"model_flagged":  true,
+	})
+	createTestMotherboard(t)
+
+	for filter, want := range map[string]uint{"flagged": flagged.ID, "unanalyzed": 0} {
+		w := adminJSON("GET", "/api/admin/products?model="+filter, nil, 0)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+
+		var response struct {
+			Data []models.Product `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if len(response.Data) != 1 {
+			t.Errorf("%s: expected 1 product, got %d", filter, len(response.Data))
+			continue
+		}
+		if want != 0 && response.Data[0].ID != want {
+			t.Errorf("%s: expected product %d, got %d", filter, want, response.Data[0].ID)
+		}
+		if filter == "flagged" && (response.Data[0].ModelMetadata == nil || response.Data[0].ModelMetadata.Triangles != 2000000) {
+			t.Errorf("expected model metadata in the listing, got %+v", response.Data[0].ModelMetadata)
+		}
+	}
+}
+
+func TestChangeProductStatus_InvalidModel(t *testing.T) {
+	cleanupDatabase()
+	product := createDraftProduct(t, models.TechnicalSpecs{"socket": "LGA1700"}, models.AnchorPoints{{Name: "cpu_bottom"}})
+	testDB.Model(&product).Updates(map[string]interface{}{
+		"model_metadata": models.ModelMetadata{ModelURL: product.ModelURL, Errors: models.StringList{"invalid glTF model"}},
+		"model_flagged":  true,
+	})
+
+	w := changeStatus(product, map[string]interface{}{"status": "published"})
+	if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), "3D model is invalid") {
+		t.Errorf("expected publishing to be blocked by the invalid model, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// cubeModel returns a glTF model of a size x size x size cube with its geometry
+// embedded as a data URI, so it can be rendered
+func cubeModel(size float32) string {
+	s := size / 2
+	buf := new(bytes.Buffer)
+	for i := 0; i < 8; i++ {
+		corner := []float32{-s, -s, -s}
+		for axis := range corner {
+			if i&(1<<axis) != 0 {
+				corner[axis] = s
+			}
+		}
+		binary.Write(buf, binary.LittleEndian, corner)
+	}
+	binary.Write(buf, binary.LittleEndian, []uint16{
+		0, 1, 3, 0, 3, 2, 4, 5, 7, 4, 7, 6, 0, 1, 5, 0, 5, 4,
+		2, 3, 7, 2, 7, 6, 0, 2, 6, 0, 6, 4, 1, 3, 7, 1, 7, 5,
+	})
+	return fmt.Sprintf(`{
+		"asset": {"version": "2.0"},
+		"scenes": [{"nodes": [0]}],
+		"nodes": [{"mesh": 0}],
+		"meshes": [{"primitives": [{"attributes": {"POSITION": 0}, "indices": 1}]}],
+		"accessors": [
+			{"bufferView": 0, "componentType": 5126, "count": 8, "type": "VEC3", "min": [%[1]g, %[1]g, %[1]g], "max": [%[2]g, %[2]g, %[2]g]},
+			{"bufferView": 1, "componentType": 5123, "count": 36, "type": "SCALAR"}
+		],
+		"bufferViews": [{"buffer": 0, "byteLength": 96}, {"buffer": 0, "byteOffset": 96, "byteLength": 72}],
+		"buffers": [{"byteLength": 168, "uri": "data:application/octet-stream;base64,%[3]s"}]
+	}`, -s, s, base64.StdEncoding.EncodeToString(buf.Bytes()))
+}
+
+func TestRenderProductThumbnail(t *testing.T) {
+	cleanupDatabase()
+	product := createTestProduct(t)
+	model := confirmUpload(t, uploadModel(t, "cube.gltf", cubeModel(4)))
+	testDB.Model(&product).Update("model_url", model.URL)
+
+	w := adminJSON("POST", fmt.Sprintf("/api/admin/products/%d/thumbnail/render?projection=fisheye", product.ID), nil, 0)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d for an unknown projection, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+
+	w = adminJSON("POST", fmt.Sprintf("/api/admin/products/%d/thumbnail/render?projection=orthographic", product.ID), nil, 0)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var response struct {
+		Data models.Product `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	thumbnail := response.Data.GeneratedThumbnailURL
+	if w.Header().Get("ETag") != fmt.Sprintf(`"%d"`, product.Version+1) {
+		t.Errorf("expected the new version's ETag, got %q", w.Header().Get("ETag"))
+	}
+
+	var asset models.Asset
+	if err := testDB.Where("url = ?", thumbnail).First(&asset).Error; err != nil {
+		t.Fatalf("expected the thumbnail %q to be an asset: %v", thumbnail, err)
+	}
+	if asset.Kind != models.MediaKindImage || asset.BlobName != asset.SHA256+".png" {
+		t.Errorf("expected a content-addressed image asset, got %+v", asset)
+	}
+
+	body, _, err := testStore.Open(context.Background(), asset.BlobName)
+	if err != nil {
+		t.Fatalf("expected the thumbnail to be stored: %v", err)
+	}
+	img, err := png.Decode(body)
+	body.Close()
+	if err != nil {
+		t.Fatalf("expected a PNG: %v", err)
+	}
+	if img.Bounds().Dx() != 512 || img.Bounds().Dy() != 512 {
+		t.Errorf("expected 512x512, got %v", img.Bounds())
+	}
+	if _, _, _, a := img.At(256, 256).RGBA(); a != 0xFFFF {
+		t.Error("expected the model in the centre")
+	}
+	if _, _, _, a := img.At(0, 0).RGBA(); a != 0 {
+		t.Error("expected a transparent background")
+	}
+
+	// Rendering the same model again reuses the asset
+	w = adminJSON("POST", fmt.Sprintf("/api/admin/products/%d/thumbnail/render?projection=orthographic", product.ID), nil, 0)
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if w.Code != http.StatusOK || response.Data.GeneratedThumbnailURL != thumbnail {
+		t.Errorf("expected the same thumbnail, got %d: %s", w.Code, w.Body.String())
+	}
+
+	refs, err := blobgc.References(testDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !refs[asset.BlobName] {
+		t.Error("expected the generated thumbnail to be referenced")
+	}
+}
+
+func TestRenderProductThumbnail_Unrenderable(t *testing.T) {
+	cleanupDatabase()
+	product := createTestProduct(t)
+	model := confirmUpload(t, uploadModel(t, "cube.gltf", cubeModel(4)))
+	testDB.Model(&product).Update("model_url", model.URL)
+
+	w := adminJSON("POST", fmt.Sprintf("/api/admin/products/%d/thumbnail/render", product.ID), nil, 0)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status %d for a model without geometry, got %d: %s", http.StatusUnprocessableEntity, w.Code, w.Body.String())
+	}
+
+	testDB.Model(&product).Update("model_url", "")
+	w = adminJSON("POST", fmt.Sprintf("/api/admin/products/%d/thumbnail/render", product.ID), nil, 0)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d without a model, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+}
+
+func TestGetBuildPreview(t *testing.T) {
+	cleanupDatabase()
+	caseModel := confirmUpload(t, uploadModel(t, "case.gltf", cubeModel(40)))
+	boardModel := confirmUpload(t, uploadModel(t, "board.gltf", cubeModel(20)))
+
+	tower := models.Product{Name: "Preview Case", SKU: "PREVIEW-CASE", Category: "CASE", ModelURL: caseModel.URL}
+	testDB.Create(&tower)
+	board := models.Product{Name: "Preview Board", SKU: "PREVIEW-BOARD", Category: "MOTHERBOARD", ModelURL: boardModel.URL}
+	testDB.Create(&board)
+	cpu := models.Product{Name: "Preview CPU", SKU: "PREVIEW-CPU", Category: "CPU", ModelURL: "https://example.com/cpu.glb"}
+	testDB.Create(&cpu)
+
+	build := models.Build{
+		UserID: "test-user",
+		Name:   "Preview Build",
+		Components: models.BuildComponents{
+			{ID: tower.ID, Name: "Case", Category: "CASE", ModelURL: caseModel.URL, AnchorPoints: models.AnchorPoints{
+				{Name: "mobo_mount_area", Position: models.Vector3{X: -5}, CompatibleTypes: []string{"mobo_backplate"}},
+			}},
+			{ID: board.ID, Name: "Board", Category: "MOTHERBOARD", ModelURL: boardModel.URL, AnchorPoints: models.AnchorPoints{
+				{Name: "mobo_backplate"},
+			}},
+			// Models outside the store are left out
+			{ID: cpu.ID, Name: "CPU", Category: "CPU", ModelURL: "https://example.com/cpu.glb"},
+		},
+	}
+	testDB.Create(&build)
+
+	w := publicJSON("GET", fmt.Sprintf("/api/user/builds/%d/preview", build.ID), "test-user", nil)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("expected a PNG, got %d: %s", w.Code, w.Body.String())
+	}
+	img, err := png.Decode(bytes.NewReader(w.Body.Bytes()))
+	if err != nil {
+		t.Fatalf("expected a PNG: %v", err)
+	}
+	if img.Bounds().Dx() != 1200 || img.Bounds().Dy() != 630 {
+		t.Errorf("expected 1200x630, got %v", img.Bounds())
+	}
+	if _, _, _, a := img.At(0, 0).RGBA(); a != 0xFFFF {
+		t.Error("expected an opaque share card")
+	}
+
+	// The preview is stored per build version and revalidated by ETag
+	if _, err := testStore.Stat(context.Background(), fmt.Sprintf("build-%d-%d-perspective.png", build.ID, build.Version)); err != nil {
+		t.Errorf("expected the preview to be stored: %v", err)
+	}
+	req := httptest.NewRequest("GET", fmt.Sprintf("/api/user/builds/%d/preview", build.ID), nil)
+	req.Header.Set(middleware.HeaderClerkUserID, "test-user")
+	req.Header.Set("If-None-Match", w.Header().Get("ETag"))
+	w = httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("expected status %d, got %d", http.StatusNotModified, w.Code)
+	}
+
+	w = publicJSON("GET", fmt.Sprintf("/api/user/builds/%d/preview", build.ID), "other-user", nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for another user's build, got %d", http.StatusNotFound, w.Code)
+	}
+
+	empty := models.Build{UserID: "test-user", Name: "Empty", Components: models.BuildComponents{{ID: 3, Name: "CPU", Category: "CPU"}}}
+	testDB.Create(&empty)
+	w = publicJSON("GET", fmt.Sprintf("/api/user/builds/%d/preview", empty.ID), "test-user", nil)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status %d without renderable models, got %d: %s", http.StatusUnprocessableEntity, w.Code, w.Body.String())
+	}
+
+	// Only the models of catalog products are rendered, not URLs stored in the build
+	forged := models.Build{UserID: "test-user", Name: "Forged", Components: models.BuildComponents{
+		{ID: cpu.ID, Name: "CPU", Category: "CPU", ModelURL: caseModel.URL},
+	}}
+	testDB.Create(&forged)
+	w = publicJSON("GET", fmt.Sprintf("/api/user/builds/%d/preview", forged.ID), "test-user", nil)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status %d for a model the catalog does not name, got %d: %s", http.StatusUnprocessableEntity, w.Code, w.Body.String())
+	}
+}
+
+func TestDimensionReport(t *testing.T) {
+	cleanupDatabase()
+	// A graphics card modelled lying flat (cm): 30 long, 4 thick, 12 tall, inserted along -Z
+	gpu := models.Product{
+		Name: "Test GPU", SKU: "TEST-GPU-DIM", Category: "GPU", ModelURL: "https://example.com/gpu.glb",
+		TechnicalSpecs: models.TechnicalSpecs{"length_mm": 305},
+		AnchorPoints:   models.AnchorPoints{{Name: "pcie_edge", Label: "pcie_edge", Direction: "input", ConnectionAxis: "Z_NEG"}},
+		ModelMetadata: &models.ModelMetadata{
+			ModelURL: "https://example.com/gpu.glb", Valid: true,
+			BoundsMin: models.Vector3{X: -15, Y: 0, Z: 0}, BoundsMax: models.Vector3{X: 15, Y: 4, Z: 12},
+		},
+	}
+	testDB.Create(&gpu)
+	tower := models.Product{
+		Name: "Test Case", SKU: "TEST-CASE-DIM", Category: "CASE", ModelURL: "https://example.com/case.glb",
+		TechnicalSpecs: models.TechnicalSpecs{"length_mm": "450 mm", "width_mm": 220, "height_mm": 480, "max_gpu_length_mm": 520, "max_cpu_cooler_height_mm": 170},
+		ModelMetadata: &models.ModelMetadata{
+			ModelURL: "https://example.com/case.glb", Valid: true,
+			BoundsMin: models.Vector3{X: -11, Y: 0, Z: -22.5}, BoundsMax: models.Vector3{X: 11, Y: 48, Z: 22.5},
+		},
+	}
+	testDB.Create(&tower)
+	unanalyzed := createTestProduct(t)
+
+	w := adminJSON("GET", "/api/admin/data-quality/dimensions", nil, 0)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var response struct {
+		Data    []handlers.DimensionCheck `json:"data"`
+		Summary handlers.DimensionSummary `json:"summary"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Summary != (handlers.DimensionSummary{Checked: 3, Measured: 2, Unmeasured: 1, Missing: 1, Mismatched: 1, Exceeding: 1}) {
+		t.Errorf("unexpected summary %+v", response.Summary)
+	}
+
+	checks := map[uint]handlers.DimensionCheck{}
+	for _, check := range response.Data {
+		checks[check.ProductID] = check
+	}
+	gpuCheck := checks[gpu.ID]
+	if gpuCheck.Dimensions == nil || *gpuCheck.Dimensions != (handlers.ProductDimensions{LengthMM: 300, WidthMM: 40, HeightMM: 120, Frame: "pcie_edge"}) {
+		t.Errorf("expected the card measured in its anchor frame, got %+v", gpuCheck.Dimensions)
+	}
+	issues := map[string]string{}
+	for _, issue := range gpuCheck.Issues {
+		issues[issue.Spec] = issue.Kind
+	}
+	if !reflect.DeepEqual(issues, map[string]string{"length_mm": "mismatch", "width_mm": "missing", "height_mm": "missing"}) {
+		t.Errorf("unexpected GPU issues %v", gpuCheck.Issues)
+	}
+	caseCheck := checks[tower.ID]
+	if len(caseCheck.Issues) != 1 || caseCheck.Issues[0].Spec != "max_gpu_length_mm" || caseCheck.Issues[0].Kind != "exceeds" {
+		t.Errorf("expected only the GPU clearance to exceed the case, got %+v", caseCheck.Issues)
+	}
+	if checks[unanalyzed.ID].Unmeasured == "" {
+		t.Error("expected the product without analysis to be listed as unmeasured")
+	}
+
+	// A wider tolerance accepts the card's length
+	w = adminJSON("GET", "/api/admin/data-quality/dimensions?tolerance_mm=10&category=GPU", nil, 0)
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if len(response.Data) != 1 || len(response.Data[0].Issues) != 2 {
+		t.Errorf("expected only missing specs within 10 mm, got %s", w.Body.String())
+	}
+	if w := adminJSON("GET", "/api/admin/data-quality/dimensions?tolerance_mm=-1", nil, 0); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d for a negative tolerance, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	w = adminJSON("POST", "/api/admin/data-quality/dimensions/autofill", map[string]interface{}{"product_ids": []uint{gpu.ID}}, 0)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var stored models.Product
+	testDB.First(&stored, gpu.ID)
+	if stored.TechnicalSpecs["width_mm"] != 40.0 || stored.TechnicalSpecs["height_mm"] != 120.0 || stored.TechnicalSpecs["length_mm"] != 305.0 {
+		t.Errorf("expected missing specs filled and existing ones kept, got %v", stored.TechnicalSpecs)
+	}
+	if stored.Version != gpu.Version+1 {
+		t.Errorf("expected the version to be bumped, got %d", stored.Version)
+	}
+}
+
+func TestBuildShares(t *testing.T) {
+	cleanupDatabase()
+	gpu := createDraftProduct(t, nil, nil)
+	testDB.Model(&gpu).Updates(map[string]interface{}{"model_url": testStore.URL("gpu.glb"), "status": models.ProductStatusArchived})
+	build := models.Build{
+		UserID:     "owner",
+		Name:       "Shared Build",
+		TotalPrice: 299.99,
+		Components: models.BuildComponents{{ID: gpu.ID, Name: "GPU", Category: "GPU", Price: 299.99, ModelURL: testStore.URL("gpu.glb")}},
+	}
+	testDB.Create(&build)
+	sharesPath := fmt.Sprintf("/api/user/builds/%d/shares", build.ID)
+
+	if w := publicJSON("POST", sharesPath, "other", nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for another user's build, got %d", http.StatusNotFound, w.Code)
+	}
+	past := time.Now().Add(-time.Hour)
+	if w := publicJSON("POST", sharesPath, "owner", map[string]interface{}{"expires_at": past}); w.Code != http.StatusBadRequest {
+		t.Errorf("expected a past expiry to be rejected, got %d", w.Code)
+	}
+
+	w := publicJSON("POST", sharesPath, "owner", nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var created struct {
+		Data       models.BuildShare `json:"data"`
+		Visibility string            `json:"visibility"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	share := created.Data
+	if len(share.Slug) != 22 || created.Visibility != models.BuildVisibilityUnlisted {
+		t.Fatalf("expected an unlisted build with a random slug, got %s", w.Body.String())
+	}
+
+	// Anyone with the link sees the build, but not its owner
+	sharedPath := "/api/shared/builds/" + share.Slug
+	for i := 1; i <= 2; i++ {
+		w = publicJSON("GET", sharedPath, "", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		if strings.Contains(w.Body.String(), "owner") || strings.Contains(w.Body.String(), "user_id") {
+			t.Errorf("expected the owner to be hidden: %s", w.Body.String())
+		}
+		var response struct {
+			Data handlers.SharedBuild `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if response.Data.Name != "Shared Build" || response.Data.TotalPrice != 299.99 || len(response.Data.Components) != 1 || response.Data.ViewCount != int64(i) {
+			t.Errorf("unexpected shared build: %s", w.Body.String())
+		}
+	}
+
+	// The viewer may load the shared models
+	if w := publicJSON("GET", "/api/download-token?blob=gpu.glb", "", nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d without the share, got %d", http.StatusNotFound, w.Code)
+	}
+	if w := publicJSON("GET", "/api/download-token?blob=gpu.glb&share="+share.Slug, "", nil); w.Code != http.StatusOK {
+		t.Errorf("expected status %d with the share, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	// Unlisted builds are not in the public list until made public
+	var list struct {
+		Data []handlers.SharedBuild  `json:"data"`
+		Meta handlers.PaginationMeta `json:"meta"`
+	}
+	w = publicJSON("GET", "/api/shared/builds", "", nil)
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if w.Code != http.StatusOK || len(list.Data) != 0 {
+		t.Errorf("expected no public builds, got %d: %s", w.Code, w.Body.String())
+	}
+	testDB.Model(&build).Update("visibility", models.BuildVisibilityPublic)
+	publicJSON("POST", sharesPath, "owner", nil)
+	w = publicJSON("GET", "/api/shared/builds", "", nil)
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if len(list.Data) != 1 || list.Meta.Total != 1 || list.Data[0].Slug == share.Slug {
+		t.Errorf("expected the build once, through its newest link: %s", w.Body.String())
+	}
+
+	w = publicJSON("GET", sharesPath, "owner", nil)
+	var shares struct {
+		Data []models.BuildShare `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &shares)
+	if len(shares.Data) != 2 || shares.Data[1].ViewCount != 2 || shares.Data[1].LastViewedAt == nil {
+		t.Errorf("expected both links with their views: %s", w.Body.String())
+	}
+
+	w = publicJSON("DELETE", fmt.Sprintf("%s/%d", sharesPath, share.ID), "owner", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if w := publicJSON("GET", sharedPath, "", nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected a revoked link to be gone, got %d", w.Code)
+	}
+
+	// Making the build private closes every link
+	testDB.Model(&build).Update("visibility", models.BuildVisibilityPrivate)
+	if w := publicJSON("GET", "/api/shared/builds/"+list.Data[0].Slug, "", nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected a private build to be hidden, got %d", w.Code)
+	}
+}
+
+func TestBuildShares_Expired(t *testing.T) {
+	cleanupDatabase()
+	build := models.Build{UserID: "owner", Name: "Expiring", Visibility: models.BuildVisibilityUnlisted}
+	testDB.Create(&build)
+	expired := time.Now().Add(-time.Minute)
+	share := models.BuildShare{BuildID: build.ID, Slug: "expired-link", ExpiresAt: &expired}
+	testDB.Create(&share)
+
+	if w := publicJSON("GET", "/api/shared/builds/expired-link", "", nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected an expired link to be gone, got %d", w.Code)
+	}
+	if w := publicJSON("GET", "/api/shared/builds/unknown", "", nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected an unknown link to be gone, got %d", w.Code)
+	}
+}
+
+func TestCloneBuild(t *testing.T) {
+	cleanupDatabase()
+	cpu := createTestProduct(t)
+	board := createTestMotherboard(t)
+	retired := createTestProduct(t)
+	build := models.Build{
+		UserID:     "owner",
+		Name:       "Original",
+		TotalPrice: 599.97,
+		Components: models.BuildComponents{
+			{ID: cpu.ID, Name: "Old CPU name", Category: "CPU", Price: 249.99, Quantity: 2},
+			{ID: board.ID, Name: board.Name, Category: "MOTHERBOARD", Price: board.Price, TechnicalSpecs: board.TechnicalSpecs},
+			{ID: retired.ID, Name: retired.Name, Category: "CPU", Price: retired.Price},
+		},
+	}
+	testDB.Create(&build)
+	testDB.Delete(&retired)
+
+	if w := publicJSON("POST", fmt.Sprintf("/api/user/builds/%d/clone", build.ID), "other", nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for another user's build, got %d", http.StatusNotFound, w.Code)
+	}
+
+	w := publicJSON("POST", fmt.Sprintf("/api/user/builds/%d/clone", build.ID), "owner", nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var response struct {
+		Data    models.Build               `json:"data"`
+		Changes []handlers.ComponentChange `json:"changes"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	clone := response.Data
+	if clone.ID == build.ID || clone.UserID != "owner" || clone.Name != "Original (copy)" || clone.ForkedFrom == nil || *clone.ForkedFrom != build.ID {
+		t.Errorf("unexpected clone: %s", w.Body.String())
+	}
+	if clone.Visibility != models.BuildVisibilityPrivate {
+		t.Errorf("expected a private clone, got %q", clone.Visibility)
+	}
+	// Re-hydrated from the catalog, without the retired product
+	if len(clone.Components) != 2 || clone.Components[0].Name != cpu.Name || clone.Components[0].Price != cpu.Price ||
+		clone.Components[0].Quantity != 2 || clone.Components[0].ModelURL != cpu.ModelURL || clone.Components[0].Category != "CPU" {
+		t.Errorf("unexpected components: %+v", clone.Components)
+	}
+	if clone.TotalPrice != 799.97 {
+		t.Errorf("expected the total at current prices, got %v", clone.TotalPrice)
+	}
+
+	if len(response.Changes) != 2 {
+		t.Fatalf("expected two changes, got %s", w.Body.String())
+	}
+	if c := response.Changes[0]; c.ProductID != cpu.ID || c.Missing || c.OldPrice != 249.99 || c.NewPrice != 299.99 || c.PriceDelta != 50 {
+		t.Errorf("unexpected price change: %+v", c)
+	}
+	if c := response.Changes[1]; c.ProductID != retired.ID || !c.Missing || c.Reason != handlers.MissingDeleted || c.Index != 2 {
+		t.Errorf("unexpected missing component: %+v", c)
+	}
+
+	// The source build is untouched
+	var source models.Build
+	testDB.First(&source, build.ID)
+	if len(source.Components) != 3 || source.Components[0].Price != 249.99 {
+		t.Errorf("expected the source build to be unchanged, got %+v", source.Components)
+	}
+}
+
+func TestForkSharedBuild(t *testing.T) {
+	cleanupDatabase()
+	cpu := createTestProduct(t)
+	build := models.Build{
+		UserID:     "owner",
+		Name:       "Shared",
+		Visibility: models.BuildVisibilityUnlisted,
+		Components: models.BuildComponents{{ID: cpu.ID, Name: cpu.Name, Category: "CPU", Price: cpu.Price}},
+	}
+	testDB.Create(&build)
+	share := models.BuildShare{BuildID: build.ID, Slug: "fork-me"}
+	testDB.Create(&share)
+
+	if w := publicJSON("POST", "/api/shared/builds/fork-me/fork", "", nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d when signed out, got %d", http.StatusUnauthorized, w.Code)
+	}
+
+	w := publicJSON("POST", "/api/shared/builds/fork-me/fork", "visitor", map[string]interface{}{"name": "My take"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var response struct {
+		Data    models.Build               `json:"data"`
+		Changes []handlers.ComponentChange `json:"changes"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Data.UserID != "visitor" || response.Data.Name != "My take" || len(response.Data.Components) != 1 || len(response.Changes) != 0 {
+		t.Errorf("unexpected fork: %s", w.Body.String())
+	}
+
+	testDB.Model(&share).Update("revoked_at", time.Now())
+	if w := publicJSON("POST", "/api/shared/builds/fork-me/fork", "visitor", nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected a revoked link to be gone, got %d", w.Code)
+	}
+}
+
+// userJSON sends a request as test-user, with If-Match when ifMatch is set
+func userJSON(method, path string, body interface{}, ifMatch uint) *httptest.ResponseRecorder {
+	jsonBody, _ := json.Marshal(body)
+	req := httptest.NewRequest(method, path, bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	if ifMatch != 0 {
+		req.Header.Set("If-Match", fmt.Sprintf(`"%d"`, ifMatch))
+	}
+	req.Header.Set(middleware.HeaderClerkUserID, "test-user")
+	w := httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+	return w
+}
+
+func TestBuildRevisions(t *testing.T) {
+	cleanupDatabase()
+	cpu := map[string]interface{}{"id": 1, "name": "CPU", "category": "CPU", "price": 300}
+	board := map[string]interface{}{"id": 2, "name": "Board", "category": "MOTHERBOARD", "price": 200}
+	ram := map[string]interface{}{"id": 3, "name": "RAM", "category": "RAM", "price": 50, "quantity": 2}
+
+	w := userJSON("POST", "/api/user/builds", map[string]interface{}{
+		"name":       "Experiment",
+		"components": []interface{}{cpu, board},
+	}, 0)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("failed to save build: %d %s", w.Code, w.Body.String())
+	}
+	var saved struct {
+		Data models.Build `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &saved)
+	build := saved.Data
+	buildPath := fmt.Sprintf("/api/user/builds/%d", build.ID)
+
+	// Swap the board for RAM and make the CPU cheaper
+	cheaper := map[string]interface{}{"id": 1, "name": "CPU", "category": "CPU", "price": 280}
+	w = userJSON("PUT", buildPath, map[string]interface{}{"components": []interface{}{cheaper, ram}}, build.Version)
+	if w.Code != http.StatusOK {
+		t.Fatalf("failed to update build: %d %s", w.Code, w.Body.String())
+	}
+	// Visibility alone is not a new revision
+	w = userJSON("PUT", buildPath, map[string]interface{}{"visibility": "unlisted"}, build.Version+1)
+	if w.Code != http.StatusOK {
+		t.Fatalf("failed to update visibility: %d %s", w.Code, w.Body.String())
+	}
+
+	w = userJSON("GET", buildPath+"/revisions", nil, 0)
+	var list struct {
+		Data    []models.BuildRevision `json:"data"`
+		Current uint                   `json:"current"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if w.Code != http.StatusOK || len(list.Data) != 2 || list.Data[0].Version != 2 || list.Data[1].Version != 1 || list.Current != 3 {
+		t.Fatalf("expected revisions 2 and 1 of version 3, got %d: %s", w.Code, w.Body.String())
+	}
+	if list.Data[0].TotalPrice != 380 || list.Data[1].TotalPrice != 500 {
+		t.Errorf("unexpected revision totals: %s", w.Body.String())
+	}
+
+	w = userJSON("GET", buildPath+"/revisions/diff?from=1&to=2", nil, 0)
+	var diff struct {
+		Data handlers.BuildDiff `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &diff)
+	d := diff.Data
+	if w.Code != http.StatusOK || d.TotalPriceDelta != -120 {
+		t.Fatalf("unexpected diff: %d %s", w.Code, w.Body.String())
+	}
+	if len(d.Added) != 1 || d.Added[0].ProductID != 3 || d.Added[0].PriceDelta != 100 {
+		t.Errorf("expected the RAM to be added, got %+v", d.Added)
+	}
+	if len(d.Removed) != 1 || d.Removed[0].ProductID != 2 || d.Removed[0].PriceDelta != -200 {
+		t.Errorf("expected the board to be removed, got %+v", d.Removed)
+	}
+	if len(d.Changed) != 1 || d.Changed[0].ProductID != 1 || d.Changed[0].PriceDelta != -20 || d.Changed[0].From.Price != 300 {
+		t.Errorf("expected the CPU price to change, got %+v", d.Changed)
+	}
+	if w := userJSON("GET", buildPath+"/revisions/diff?from=1&to=9", nil, 0); w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for an unknown revision, got %d", http.StatusNotFound, w.Code)
+	}
+
+	// Roll back to the first revision
+	if w := userJSON("POST", buildPath+"/revisions/1/rollback", nil, 2); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("expected status %d for a stale ETag, got %d", http.StatusPreconditionFailed, w.Code)
+	}
+	w = userJSON("POST", buildPath+"/revisions/1/rollback", nil, 3)
+	if w.Code != http.StatusOK {
+		t.Fatalf("failed to roll back: %d %s", w.Code, w.Body.String())
+	}
+	var restored models.Build
+	testDB.First(&restored, build.ID)
+	if restored.Version != 4 || len(restored.Components) != 2 || restored.Components[1].ID != 2 || restored.TotalPrice != 500 {
+		t.Errorf("expected the first revision restored, got %+v", restored)
+	}
+	if restored.Visibility != models.BuildVisibilityUnlisted {
+		t.Errorf("expected the visibility to be kept, got %q", restored.Visibility)
+	}
+	var count int64
+	testDB.Model(&models.BuildRevision{}).Where("build_id = ?", build.ID).Count(&count)
+	if count != 3 {
+		t.Errorf("expected the rollback to be recorded as a revision, got %d revisions", count)
+	}
+
+	if w := publicJSON("GET", buildPath+"/revisions", "other-user", nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for another user's build, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestBuildRevisions_Baseline(t *testing.T) {
+	cleanupDatabase()
+	// Saved before revisions were recorded
+	build := models.Build{UserID: "test-user", Name: "Legacy", Components: models.BuildComponents{}}
+	testDB.Create(&build)
+
+	w := userJSON("PUT", fmt.Sprintf("/api/user/builds/%d", build.ID), map[string]interface{}{"name": "Renamed"}, build.Version)
+	if w.Code != http.StatusOK {
+		t.Fatalf("failed to update build: %d %s", w.Code, w.Body.String())
+	}
+
+	var revisions []models.BuildRevision
+	testDB.Where("build_id = ?", build.ID).Order("version").Find(&revisions)
+	if len(revisions) != 2 || revisions[0].Name != "Legacy" || revisions[1].Name != "Renamed" {
+		t.Errorf("expected the original state to be kept, got %+v", revisions)
+	}
+}
+
+func TestLiveBuild_MissingReasons(t *testing.T) {
+	cleanupDatabase()
+	deleted := createTestProduct(t)
+	drafted := createTestProduct(t)
+	archived := createTestMotherboard(t)
+	build := models.Build{
+		UserID: "test-user",
+		Name:   "Gone parts",
+		Components: models.BuildComponents{
+			{ID: deleted.ID, Name: deleted.Name, Category: "CPU", Price: deleted.Price},
+			{ID: drafted.ID, Name: drafted.Name, Category: "CPU", Price: drafted.Price},
+			{ID: archived.ID, Name: archived.Name, Category: "MOTHERBOARD", Price: archived.Price},
+		},
+	}
+	testDB.Create(&build)
+	testDB.Delete(&deleted)
+	testDB.Model(&drafted).Update("status", models.ProductStatusDraft)
+	testDB.Model(&archived).Update("status", models.ProductStatusArchived)
+
+	w := userJSON("GET", fmt.Sprintf("/api/user/builds/%d?live=true", build.ID), nil, 0)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var live struct {
+		Data struct {
+			Changes []handlers.ComponentChange `json:"changes"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &live)
+
+	want := []string{handlers.MissingDeleted, handlers.MissingUnpublished, handlers.MissingUnpublished}
+	if len(live.Data.Changes) != len(want) {
+		t.Fatalf("expected %d missing components, got %s", len(want), w.Body.String())
+	}
+	for i, c := range live.Data.Changes {
+		if !c.Missing || c.Reason != want[i] {
+			t.Errorf("component %d: expected missing as %s, got %+v", i, want[i], c)
+		}
+	}
+}
+
+func TestRepriceBuild(t *testing.T) {
+	cleanupDatabase()
+	cpu := createTestProduct(t)
+	board := createTestMotherboard(t)
+	retired := createTestProduct(t)
+	build := models.Build{
+		UserID:     "test-user",
+		Name:       "Six months old",
+		TotalPrice: 749.97,
+		Components: models.BuildComponents{
+			{ID: cpu.ID, Name: cpu.Name, Category: "CPU", Price: 249.99, TechnicalSpecs: cpu.TechnicalSpecs},
+			{ID: board.ID, Name: board.Name, Category: "MOTHERBOARD", Price: board.Price, TechnicalSpecs: models.TechnicalSpecs{"socket": "AM5", "form_factor": "ATX"}},
+			{ID: retired.ID, Name: retired.Name, Category: "CPU", Price: 299.99, TechnicalSpecs: retired.TechnicalSpecs},
+		},
+	}
+	testDB.Create(&build)
+	testDB.Delete(&retired)
+	buildPath := fmt.Sprintf("/api/user/builds/%d", build.ID)
+
+	// The live view follows the catalog without saving it
+	w := userJSON("GET", buildPath+"?live=true", nil, 0)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var live struct {
+		Data struct {
+			Build      models.Build               `json:"build"`
+			Components models.BuildComponents     `json:"components"`
+			TotalPrice float64                    `json:"total_price"`
+			Changes    []handlers.ComponentChange `json:"changes"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &live)
+	if live.Data.TotalPrice != 799.97 || live.Data.Build.TotalPrice != 749.97 || live.Data.Components[0].Price != 299.99 || len(live.Data.Changes) != 3 {
+		t.Errorf("unexpected live view: %s", w.Body.String())
+	}
+	if c := live.Data.Changes[0]; c.PriceDelta != 50 || len(c.SpecsChanged) != 0 {
+		t.Errorf("expected the CPU price to drift, got %+v", c)
+	}
+	if c := live.Data.Changes[1]; c.PriceDelta != 0 || !reflect.DeepEqual(c.SpecsChanged, []string{"socket"}) {
+		t.Errorf("expected the board specs to drift, got %+v", c)
+	}
+	if c := live.Data.Changes[2]; !c.Missing || c.Index != 2 {
+		t.Errorf("expected the retired CPU to be missing, got %+v", c)
+	}
+
+	// Reporting only
+	w = userJSON("POST", buildPath+"/reprice", nil, 0)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"applied":false`) {
+		t.Fatalf("unexpected report: %d %s", w.Code, w.Body.String())
+	}
+	var saved models.Build
+	testDB.First(&saved, build.ID)
+	if saved.Version != build.Version || saved.TotalPrice != 749.97 {
+		t.Errorf("expected the build to be unchanged, got %+v", saved)
+	}
+
+	if w := userJSON("POST", buildPath+"/reprice", map[string]interface{}{"apply": true}, 0); w.Code != http.StatusPreconditionRequired {
+		t.Errorf("expected status %d without If-Match, got %d", http.StatusPreconditionRequired, w.Code)
+	}
+	w = userJSON("POST", buildPath+"/reprice", map[string]interface{}{"apply": true}, build.Version)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"applied":true`) {
+		t.Fatalf("failed to apply prices: %d %s", w.Code, w.Body.String())
+	}
+	testDB.First(&saved, build.ID)
+	if saved.Version != build.Version+1 || saved.TotalPrice != 799.97 || saved.Components[0].Price != 299.99 {
+		t.Errorf("expected the current prices to be saved, got %+v", saved)
+	}
+	// Specs and deleted products are kept as saved
+	if saved.Components[1].TechnicalSpecs["socket"] != "AM5" || len(saved.Components) != 3 {
+		t.Errorf("expected only prices to change, got %+v", saved.Components)
+	}
+	var revisions int64
+	testDB.Model(&models.BuildRevision{}).Where("build_id = ?", build.ID).Count(&revisions)
+	if revisions != 2 {
+		t.Errorf("expected the repricing to be recorded as a revision, got %d revisions", revisions)
+	}
+}
+
+func TestBlobGC_BuildRevisions(t *testing.T) {
+	cleanupDatabase()
+	ctx := context.Background()
+	for _, blob := range mustListBlobs(t) {
+		testStore.Delete(ctx, blob.Name)
+	}
+
+	// The build swapped its GPU: the old model is only referenced by a revision
+	oldModel := uploadModel(t, "old-gpu.glb", "glTF")
+	newModel := uploadModel(t, "new-gpu.glb", "glTF")
+	deletedModel := uploadModel(t, "deleted.glb", "glTF")
+	build := models.Build{UserID: "user", Name: "Swapped", Components: models.BuildComponents{{ID: 2, Name: "New GPU", ModelURL: newModel}}}
+	testDB.Create(&build)
+	testDB.Create(&models.BuildRevision{BuildID: build.ID, Version: 1, Name: "Swapped", Components: models.BuildComponents{{ID: 1, Name: "Old GPU", ModelURL: oldModel}}})
+	// Revisions of deleted builds can no longer be rolled back to
+	deleted := models.Build{UserID: "user", Name: "Deleted"}
+	testDB.Create(&deleted)
+	testDB.Create(&models.BuildRevision{BuildID: deleted.ID, Version: 1, Name: "Deleted", Components: models.BuildComponents{{ID: 3, Name: "GPU", ModelURL: deletedModel}}})
+	testDB.Delete(&deleted)
+
+	old := time.Now().Add(-48 * time.Hour)
+	for _, blobURL := range []string{oldModel, newModel, deletedModel} {
+		os.Chtimes(filepath.Join(testBlobDir, path.Base(blobURL)), old, old)
+	}
+
+	w := adminJSON("POST", "/api/admin/storage/gc?grace_hours=24", nil, 0)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var response struct {
+		Data blobgc.Report `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if !reflect.DeepEqual(response.Data.Deleted, []string{path.Base(deletedModel)}) {
+		t.Errorf("expected only the deleted build's model to be collected, got %v", response.Data.Deleted)
+	}
+	if _, err := testStore.Stat(ctx, path.Base(oldModel)); err != nil {
+		t.Errorf("expected the model of the revision to be kept: %v", err)
+	}
+}
+
+func TestDownloadToken_ThumbnailSizes(t *testing.T) {
+	cleanupDatabase()
+	family := models.ProductFamily{Name: "Family", Category: "cpu", ThumbnailSizes: models.ImageSizes{"list": testStore.URL("family-list.jpg")}}
+	testDB.Create(&family)
+	published := createTestProduct(t)
+	draft := createDraftProduct(t, nil, nil)
+	testDB.Model(&published).Updates(map[string]interface{}{
+		"thumbnail_sizes": models.ImageSizes{"list": testStore.URL("published-list.jpg"), "card": testStore.URL("published-card.jpg")},
+		"family_id":       family.ID,
+	})
+	testDB.Model(&draft).Update("thumbnail_sizes", models.ImageSizes{"list": testStore.URL("draft-list.jpg")})
+
+	for blob, status := range map[string]int{
+		"published-list.jpg": http.StatusOK,
+		"published-card.jpg": http.StatusOK,
+		"family-list.jpg":    http.StatusOK,
+		"draft-list.jpg":     http.StatusNotFound,
+	} {
+		if w := publicJSON("GET", "/api/download-token?blob="+blob, "", nil); w.Code != status {
+			t.Errorf("%s: expected status %d, got %d: %s", blob, status, w.Code, w.Body.String())
+		}
+	}
+
+	w := publicJSON("POST", "/api/download-tokens", "", map[string]interface{}{
+		"blobs": []string{"published-list.jpg", "draft-list.jpg"},
+	})
+	var response struct {
+		Data   map[string]handlers.DownloadToken `json:"data"`
+		Denied []string                          `json:"denied"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if len(response.Data) != 1 || response.Data["published-list.jpg"].DownloadURL == "" || !reflect.DeepEqual(response.Denied, []string{"draft-list.jpg"}) {
+		t.Errorf("unexpected tokens: %s", w.Body.String())
+	}
+}
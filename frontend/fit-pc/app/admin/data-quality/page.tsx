@@ -0,0 +1,46 @@
+import DimensionReport, { DimensionCheck, DimensionSummary } from "@/components/admin/DimensionReport";
+import { auth } from "@clerk/nextjs/server";
+
+const BACKEND_URL = process.env.BACKEND_URL || "http://localhost:8081";
+
+const emptySummary: DimensionSummary = { checked: 0, measured: 0, unmeasured: 0, missing: 0, mismatched: 0, exceeding: 0 };
+
+async function getDimensionReport(
+    token: string | null
+): Promise<{ data: DimensionCheck[]; summary: DimensionSummary; tolerance_mm: number }> {
+    try {
+        const response = await fetch(`${BACKEND_URL}/api/admin/data-quality/dimensions`, {
+            headers: {
+                "Content-Type": "application/json",
+                ...(token ? { Authorization: `Bearer ${token}` } : {}),
+            },
+            cache: "no-store",
+        });
+
+        if (!response.ok) {
+            console.error("Failed to fetch the dimension report:", response.status);
+            return { data: [], summary: emptySummary, tolerance_mm: 0 };
+        }
+        return await response.json();
+    } catch (error) {
+        console.error("Error fetching the dimension report:", error);
+        return { data: [], summary: emptySummary, tolerance_mm: 0 };
+    }
+}
+
+export default async function DataQualityPage() {
+    const { getToken } = await auth();
+    const token = await getToken();
+
+    const report = await getDimensionReport(token);
+
+    return (
+        <div className="flex flex-col gap-6">
+            <div>
+                <h1 className="text-3xl font-bold tracking-tight">Data Quality</h1>
+                <p className="text-muted-foreground">Dimension specs compared with the measured 3D models</p>
+            </div>
+            <DimensionReport checks={report.data} summary={report.summary} toleranceMM={report.tolerance_mm} />
+        </div>
+    );
+}
@@ -26,6 +26,10 @@ export default async function AdminLayout({
                 <Link href="/" className="flex items-center gap-2 font-semibold text-lg">
                     FitPC Admin
                 </Link>
+                <nav className="flex items-center gap-4 text-sm text-muted-foreground">
+                    <Link href="/admin" className="hover:text-foreground">Products</Link>
+                    <Link href="/admin/data-quality" className="hover:text-foreground">Data Quality</Link>
+                </nav>
                 <div className="ml-auto flex items-center gap-4">
                     <ModeToggle />
                     <div className="flex items-center gap-2">
@@ -0,0 +1,5 @@
+import { proxyRequest } from "@/lib/api-proxy";
+
+export async function POST(request: Request) {
+    return proxyRequest(request, "/api/admin/data-quality/dimensions/autofill");
+}
@@ -0,0 +1,41 @@
+import { auth } from "@clerk/nextjs/server";
+import { NextResponse } from "next/server";
+
+const BACKEND_URL = process.env.BACKEND_URL || "http://localhost:8081";
+
+// Streams the product's model with its anchor nodes (binary, so not via proxyRequest)
+export async function GET(_request: Request, { params }: { params: Promise<{ id: string }> }) {
+  const { id } = await params;
+  const { getToken } = await auth();
+  const token = await getToken();
+
+  if (!token) {
+    return NextResponse.json({ error: "Unauthorized" }, { status: 401 });
+  }
+
+  const res = await fetch(`${BACKEND_URL}/api/admin/products/${id}/anchors/export`, {
+    headers: {
+      Authorization: `Bearer ${token}`,
+    },
+  });
+
+  if (!res.ok) {
+    const error = await res.text();
+    console.error("Backend error:", res.status, error);
+    return new NextResponse(error, {
+      status: res.status,
+      headers: { "Content-Type": "application/json" },
+    });
+  }
+
+  const model = await res.arrayBuffer();
+
+  return new NextResponse(model, {
+    status: 200,
+    headers: {
+      "Content-Type": res.headers.get("Content-Type") || "model/gltf-binary",
+      "Content-Disposition": res.headers.get("Content-Disposition") || "attachment",
+      "Content-Length": model.byteLength.toString(),
+    },
+  });
+}
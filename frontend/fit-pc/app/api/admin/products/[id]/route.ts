@@ -10,6 +10,11 @@ export async function PUT(request: Request, { params }: { params: Promise<{ id:
     return proxyRequest(request, `/api/admin/products/${id}`);
 }
 
+export async function PATCH(request: Request, { params }: { params: Promise<{ id: string }> }) {
+    const { id } = await params;
+    return proxyRequest(request, `/api/admin/products/${id}`);
+}
+
 export async function DELETE(request: Request, { params }: { params: Promise<{ id: string }> }) {
     const { id } = await params;
     return proxyRequest(request, `/api/admin/products/${id}`);
@@ -0,0 +1,6 @@
+import { proxyRequest } from "@/lib/api-proxy";
+
+export async function POST(request: Request, { params }: { params: Promise<{ id: string }> }) {
+    const { id } = await params;
+    return proxyRequest(request, `/api/admin/products/${id}/status`);
+}
@@ -37,7 +37,7 @@ export async function POST(request: Request) {
             );
         }
 
-        const { upload_url, blob_url } = await tokenResponse.json();
+        const { upload_url, blob_name } = await tokenResponse.json();
 
         // Step 2: Upload the file to Azure (server-side, no CORS issues)
         const fileBuffer = await file.arrayBuffer();
@@ -58,8 +58,29 @@ export async function POST(request: Request) {
             );
         }
 
-        // Step 3: Return the blob URL to the client
-        return NextResponse.json({ blob_url });
+        // Step 3: Confirm the upload, so the backend verifies and registers it as an asset
+        const completeResponse = await fetch(
+            `${BACKEND_BASE_URL}/api/admin/uploads/${encodeURIComponent(blob_name)}/complete`,
+            {
+                method: "POST",
+                headers: {
+                    Authorization: token ? `Bearer ${token}` : "",
+                },
+            }
+        );
+
+        if (!completeResponse.ok) {
+            const { error, details } = await completeResponse.json().catch(() => ({}));
+            return NextResponse.json(
+                { error: details ? `${error}: ${details}` : error || "Failed to confirm upload" },
+                { status: completeResponse.status }
+            );
+        }
+
+        // Step 4: Return the asset URL to the client. Assets are stored under the hash of
+        // their content, so an identical file resolves to the existing asset.
+        const { data: asset } = await completeResponse.json();
+        return NextResponse.json({ blob_url: asset.url });
     } catch (error) {
         console.error("Upload error:", error);
         return NextResponse.json(
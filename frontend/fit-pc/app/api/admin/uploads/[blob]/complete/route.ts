@@ -0,0 +1,6 @@
+import { proxyRequest } from "@/lib/api-proxy";
+
+export async function POST(request: Request, { params }: { params: Promise<{ blob: string }> }) {
+    const { blob } = await params;
+    return proxyRequest(request, `/api/admin/uploads/${encodeURIComponent(blob)}/complete`);
+}
@@ -0,0 +1,10 @@
+import { proxyRequest } from "@/lib/api-proxy";
+import { NextRequest } from "next/server";
+
+export async function POST(
+    request: NextRequest,
+    { params }: { params: Promise<{ id: string; version: string }> }
+) {
+    const { id, version } = await params;
+    return proxyRequest(request, `/api/user/builds/${id}/revisions/${version}/rollback`);
+}
@@ -0,0 +1,10 @@
+import { proxyRequest } from "@/lib/api-proxy";
+import { NextRequest } from "next/server";
+
+export async function GET(
+    request: NextRequest,
+    { params }: { params: Promise<{ id: string }> }
+) {
+    const { id } = await params;
+    return proxyRequest(request, `/api/user/builds/${id}/revisions`);
+}
@@ -0,0 +1,10 @@
+import { proxyRequest } from "@/lib/api-proxy";
+import { NextRequest } from "next/server";
+
+export async function DELETE(
+    request: NextRequest,
+    { params }: { params: Promise<{ id: string; shareId: string }> }
+) {
+    const { id, shareId } = await params;
+    return proxyRequest(request, `/api/user/builds/${id}/shares/${shareId}`);
+}
@@ -0,0 +1,56 @@
+import { auth } from "@clerk/nextjs/server";
+import { NextRequest, NextResponse } from "next/server";
+
+const BACKEND_URL = process.env.BACKEND_URL || "http://localhost:8081";
+
+export async function GET(
+    req: NextRequest,
+    { params }: { params: Promise<{ blob: string }> }
+) {
+    const { blob } = await params;
+
+    try {
+        // Stream the model from the backend, which caches it and handles Range
+        // and conditional requests. Published models are public; the user's token
+        // also unlocks the models of their builds (and any model for admins).
+        const { getToken } = await auth();
+        const token = await getToken();
+        const headers: Record<string, string> = {};
+        if (token) headers.Authorization = `Bearer ${token}`;
+        for (const name of ["range", "if-none-match", "if-modified-since", "if-range"]) {
+            const value = req.headers.get(name);
+            if (value) headers[name] = value;
+        }
+
+        const response = await fetch(
+            `${BACKEND_URL}/api/models/${encodeURIComponent(blob)}`,
+            { headers }
+        );
+
+        if (!response.ok && response.status !== 304) {
+            const error = await response.text();
+            console.error("Backend error:", response.status, error);
+            return NextResponse.json(
+                { error: `Failed to fetch model: ${error}` },
+                { status: response.status }
+            );
+        }
+
+        const responseHeaders = new Headers();
+        for (const name of ["content-type", "content-length", "content-range", "accept-ranges", "etag", "last-modified", "cache-control"]) {
+            const value = response.headers.get(name);
+            if (value) responseHeaders.set(name, value);
+        }
+
+        return new NextResponse(response.body, {
+            status: response.status,
+            headers: responseHeaders,
+        });
+    } catch (error) {
+        console.error("Error proxying model:", error);
+        return NextResponse.json(
+            { error: `Failed to fetch model: ${error instanceof Error ? error.message : String(error)}` },
+            { status: 500 }
+        );
+    }
+}
@@ -0,0 +1,10 @@
+import { proxyRequest } from "@/lib/api-proxy";
+import { NextRequest } from "next/server";
+
+export async function POST(
+    request: NextRequest,
+    { params }: { params: Promise<{ slug: string }> }
+) {
+    const { slug } = await params;
+    return proxyRequest(request, `/api/shared/builds/${slug}/fork`);
+}
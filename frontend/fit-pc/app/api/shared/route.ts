@@ -0,0 +1,5 @@
+import { proxyRequest } from "@/lib/api-proxy";
+
+export async function GET(request: Request) {
+    return proxyRequest(request, "/api/shared/builds");
+}
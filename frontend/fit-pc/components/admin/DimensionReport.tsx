@@ -0,0 +1,155 @@
+"use client";
+
+import { useState } from "react";
+import Link from "next/link";
+import { useRouter } from "next/navigation";
+import axios from "axios";
+import { toast } from "sonner";
+import {
+    Table,
+    TableBody,
+    TableCell,
+    TableHead,
+    TableHeader,
+    TableRow,
+} from "@/components/ui/table";
+import { Badge } from "@/components/ui/badge";
+import { Button } from "@/components/ui/button";
+import { Ruler } from "lucide-react";
+
+export interface DimensionIssue {
+    spec: string;
+    kind: "missing" | "mismatch" | "exceeds";
+    spec_value?: number;
+    model_value: number;
+    message: string;
+}
+
+export interface DimensionCheck {
+    product_id: number;
+    name: string;
+    sku: string;
+    category: string;
+    dimensions: {
+        length_mm: number;
+        width_mm: number;
+        height_mm: number;
+        frame: string;
+    } | null;
+    unmeasured?: string;
+    issues: DimensionIssue[];
+}
+
+export interface DimensionSummary {
+    checked: number;
+    measured: number;
+    unmeasured: number;
+    missing: number;
+    mismatched: number;
+    exceeding: number;
+}
+
+const issueVariants: Record<DimensionIssue["kind"], "secondary" | "destructive" | "outline"> = {
+    missing: "secondary",
+    mismatch: "destructive",
+    exceeds: "destructive",
+};
+
+export default function DimensionReport({
+    checks,
+    summary,
+    toleranceMM,
+}: {
+    checks: DimensionCheck[];
+    summary: DimensionSummary;
+    toleranceMM: number;
+}) {
+    const router = useRouter();
+    const [filling, setFilling] = useState(false);
+
+    // Autofill Action: sets missing dimension specs from the models, never overwriting existing ones
+    const autofill = async () => {
+        setFilling(true);
+        try {
+            const { data } = await axios.post("/api/admin/data-quality/dimensions/autofill");
+            toast.success(data.message);
+            if (data.data.conflicts.length > 0) {
+                toast.warning(`${data.data.conflicts.length} products changed meanwhile; run again`);
+            }
+            router.refresh();
+        } catch (error) {
+            console.error(error);
+            toast.error("Failed to fill dimension specs");
+        } finally {
+            setFilling(false);
+        }
+    };
+
+    return (
+        <div className="flex flex-col gap-4">
+            <div className="flex items-center justify-between">
+                <p className="text-sm text-muted-foreground">
+                    {summary.measured} of {summary.checked} models measured ({summary.unmeasured} not analysed) ·{" "}
+                    {summary.missing} with missing specs · {summary.mismatched} off by more than {toleranceMM} mm ·{" "}
+                    {summary.exceeding} cases with impossible clearances
+                </p>
+                <Button onClick={autofill} disabled={filling || summary.missing === 0}>
+                    <Ruler className="mr-2 h-4 w-4" /> Fill Missing Specs
+                </Button>
+            </div>
+            <div className="rounded-md border bg-background">
+                <Table>
+                    <TableHeader>
+                        <TableRow>
+                            <TableHead>Product</TableHead>
+                            <TableHead>Category</TableHead>
+                            <TableHead>Model (L × W × H mm)</TableHead>
+                            <TableHead>Issues</TableHead>
+                        </TableRow>
+                    </TableHeader>
+                    <TableBody>
+                        {checks.length ? (
+                            checks.map((check) => (
+                                <TableRow key={check.product_id}>
+                                    <TableCell>
+                                        <Link href={`/admin/${check.product_id}/edit`} className="font-medium hover:underline">
+                                            {check.name}
+                                        </Link>
+                                        <div className="text-xs text-muted-foreground">{check.sku}</div>
+                                    </TableCell>
+                                    <TableCell>{check.category}</TableCell>
+                                    <TableCell>
+                                        {check.dimensions ? (
+                                            <>
+                                                {check.dimensions.length_mm} × {check.dimensions.width_mm} × {check.dimensions.height_mm}
+                                                <div className="text-xs text-muted-foreground">frame: {check.dimensions.frame}</div>
+                                            </>
+                                        ) : (
+                                            <span className="text-muted-foreground">{check.unmeasured}</span>
+                                        )}
+                                    </TableCell>
+                                    <TableCell>
+                                        <div className="flex flex-col gap-1">
+                                            {check.issues.map((issue) => (
+                                                <div key={issue.spec} className="flex items-center gap-2 text-sm">
+                                                    <Badge variant={issueVariants[issue.kind]}>{issue.kind}</Badge>
+                                                    {issue.message}
+                                                </div>
+                                            ))}
+                                        </div>
+                                    </TableCell>
+                                </TableRow>
+                            ))
+                        ) : (
+                            <TableRow>
+                                <TableCell colSpan={4} className="h-24 text-center">
+                                    All dimension specs agree with the models.
+                                </TableCell>
+                            </TableRow>
+                        )}
+                    </TableBody>
+                </Table>
+            </div>
+        </div>
+    );
+}
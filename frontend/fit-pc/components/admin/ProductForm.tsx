@@ -4,7 +4,7 @@ import { useState, useMemo } from "react";
 import { useForm, Controller } from "react-hook-form";
 import { zodResolver } from "@hookform/resolvers/zod";
 import axios from "axios";
-import { Loader2, UploadCloud, Pencil } from "lucide-react";
+import { Loader2, UploadCloud, Pencil, Import, Download } from "lucide-react";
 import { toast } from "sonner";
 import { useRouter } from "next/navigation";
 
@@ -65,10 +65,25 @@ interface BackendAnchorPoint {
 interface ProductFormProps {
     initialData?: ProductValues & {
         id: string;
+        version?: number;
         anchor_points?: BackendAnchorPoint[];
     };
 }
 
+// Convert backend anchor points to the editor's anchor format
+function toEditorAnchors(points?: BackendAnchorPoint[]): Anchor[] {
+    return (points || []).map((ap, index) => ({
+        id: `anchor-${index}-${Date.now()}`,
+        type: ap.name as AnchorType,  // Backend uses 'name', frontend uses 'type'
+        label: ap.label || `Anchor ${index + 1}`,
+        position: [ap.position.x, ap.position.y, ap.position.z] as [number, number, number],
+        rotation: [ap.rotation.x, ap.rotation.y, ap.rotation.z] as [number, number, number],
+        direction: (ap.direction || 'output') as AnchorDirection,
+        connectionAxis: (ap.connection_axis || 'Y_NEG') as ConnectionAxis,
+        compatibleWith: (ap.compatible_types || []) as AnchorType[],
+    }));
+}
+
 export default function ProductForm({ initialData }: ProductFormProps) {
     const router = useRouter();
     const [isUploading, setIsUploading] = useState(false);
@@ -80,21 +95,8 @@ export default function ProductForm({ initialData }: ProductFormProps) {
     const [editorOpen, setEditorOpen] = useState(false);
 
     // Initialize anchors from initialData if editing
-    const [anchors, setAnchors] = useState<Anchor[]>(() => {
-        if (initialData?.anchor_points && initialData.anchor_points.length > 0) {
-            return initialData.anchor_points.map((ap, index) => ({
-                id: `anchor-${index}-${Date.now()}`,
-                type: ap.name as AnchorType,  // Backend uses 'name', frontend uses 'type'
-                label: ap.label || `Anchor ${index + 1}`,
-                position: [ap.position.x, ap.position.y, ap.position.z] as [number, number, number],
-                rotation: [ap.rotation.x, ap.rotation.y, ap.rotation.z] as [number, number, number],
-                direction: (ap.direction || 'output') as AnchorDirection,
-                connectionAxis: (ap.connection_axis || 'Y_NEG') as ConnectionAxis,
-                compatibleWith: (ap.compatible_types || []) as AnchorType[],
-            }));
-        }
-        return [];
-    });
+    const [anchors, setAnchors] = useState<Anchor[]>(() => toEditorAnchors(initialData?.anchor_points));
+    const [isImporting, setIsImporting] = useState(false);
 
     // Create object URL for the 3D preview
     const modelPreviewUrl = useMemo(() => {
@@ -151,6 +153,47 @@ export default function ProductForm({ initialData }: ProductFormProps) {
         toast.success(`Saved ${savedAnchors.length} anchor points`);
     };
 
+    // Validate a newly uploaded model on the server; problems flag the product but do not block saving
+    const analyzeModel = async (productId: string) => {
+        try {
+            const { data } = await axios.post<{
+                message: string;
+                flagged: boolean;
+                data: { errors?: string[]; warnings?: string[]; over_budget?: string[] };
+            }>(`/api/admin/products/${productId}/model/analyze`);
+            if (data.flagged) {
+                toast.warning(data.message, {
+                    description: [...(data.data.errors || []), ...(data.data.over_budget || [])].join("; "),
+                });
+            }
+            data.data.warnings?.forEach((warning) => toast.warning(warning));
+        } catch (error) {
+            console.error(error);
+            toast.error("The model could not be analysed. Retry from the product list.");
+        }
+    };
+
+    // Read anchor empties (anchor_* nodes) from the stored model; applied when the product is saved
+    const handleImportAnchors = async () => {
+        if (!initialData) return;
+        setIsImporting(true);
+        try {
+            const { data } = await axios.post<{
+                data: { anchor_points: BackendAnchorPoint[]; added: string[]; updated: string[]; warnings: string[] };
+            }>(`/api/admin/products/${initialData.id}/anchors/import`);
+            const proposal = data.data;
+            setAnchors(toEditorAnchors(proposal.anchor_points));
+            toast.success(`Imported anchors: ${proposal.added.length} new, ${proposal.updated.length} updated. Save the product to apply them.`);
+            proposal.warnings.forEach((warning) => toast.warning(warning));
+        } catch (error) {
+            console.error(error);
+            const message = axios.isAxiosError(error) ? error.response?.data?.error : undefined;
+            toast.error(message || "Failed to import anchors from the model");
+        } finally {
+            setIsImporting(false);
+        }
+    };
+
     // Upload file to Azure Blob via Next.js proxy (avoids CORS)
     const uploadToAzure = async (file: File): Promise<string> => {
         const formData = new FormData();
@@ -221,22 +264,34 @@ export default function ProductForm({ initialData }: ProductFormProps) {
                 })),
             };
 
+            let productId = initialData?.id;
             if (initialData) {
                 // Edit mode
-                await axios.put(`/api/admin/products/${initialData.id}`, productData);
+                // Send the version we loaded so concurrent edits are rejected instead of overwritten
+                await axios.put(`/api/admin/products/${initialData.id}`, productData, {
+                    headers: { "If-Match": `"${initialData.version ?? 0}"` },
+                });
                 toast.success("Product updated successfully");
             } else {
                 // Create mode
-                await axios.post("/api/admin/products", productData);
-                toast.success("Product created successfully");
+                const { data } = await axios.post<{ data: { id: number } }>("/api/admin/products", productData);
+                productId = String(data.data.id);
+                toast.success("Product created as a draft. Publish it from the product list when it is ready.");
                 form.reset();
                 setModelFile(null);
                 setAnchors([]);
             }
+            if (modelFile && productId) {
+                await analyzeModel(productId);
+            }
             router.refresh();
             router.push('/admin');
         } catch (error) {
             console.error(error);
+            if (axios.isAxiosError(error) && error.response?.status === 412) {
+                toast.error("This product was modified by someone else. Reload the page to see the latest version.");
+                return;
+            }
             toast.error(initialData ? "Failed to update product" : "Failed to create product");
         } finally {
             setIsSubmitting(false);
@@ -587,6 +642,30 @@ export default function ProductForm({ initialData }: ProductFormProps) {
                                 <Pencil className="w-4 h-4 mr-2" />
                                 {anchors.length > 0 ? 'Edit Anchors' : 'Add Anchors'}
                             </Button>
+                            {initialData?.model_url && !modelFile && (
+                                <Button
+                                    type="button"
+                                    variant="outline"
+                                    onClick={handleImportAnchors}
+                                    disabled={isImporting || isUploading || isSubmitting}
+                                >
+                                    {isImporting ? (
+                                        <Loader2 className="w-4 h-4 mr-2 animate-spin" />
+                                    ) : (
+                                        <Import className="w-4 h-4 mr-2" />
+                                    )}
+                                    Import from Model
+                                </Button>
+                            )}
+                            {/* Saved anchors as anchor_* nodes, to check their placement in Blender */}
+                            {initialData?.model_url && !modelFile && (
+                                <Button type="button" variant="outline" asChild>
+                                    <a href={`/api/admin/products/${initialData.id}/anchors/export`} download>
+                                        <Download className="w-4 h-4 mr-2" />
+                                        Download with Anchors
+                                    </a>
+                                </Button>
+                            )}
                         </div>
                     )}
 
@@ -37,7 +37,7 @@ import {
     AlertDialogTitle,
 } from "@/components/ui/alert-dialog";
 
-import { MoreHorizontal, Edit, Trash2, ArrowUpDown } from "lucide-react";
+import { MoreHorizontal, Edit, Trash2, ArrowUpDown, Send, Archive, Copy, ScanSearch, Camera } from "lucide-react";
 import Link from "next/link";
 import { useRouter, useSearchParams, usePathname } from "next/navigation";
 import { ProductValues } from "@/lib/validators/product";
@@ -45,8 +45,25 @@ import axios from "axios";
 import { toast } from "sonner";
 import { Badge } from "@/components/ui/badge";
 
-// Extended type to include ID
-export type Product = ProductValues & { id: string };
+// Extended type to include ID and publishing state
+export type Product = ProductValues & {
+    id: string;
+    status?: "draft" | "in_review" | "published" | "archived";
+    publish_at?: string | null;
+    model_flagged?: boolean;
+    model_metadata?: {
+        valid: boolean;
+        errors?: string[];
+        over_budget?: string[];
+    } | null;
+};
+
+const STATUS_LABELS: Record<string, string> = {
+    draft: "Draft",
+    in_review: "In review",
+    published: "Published",
+    archived: "Archived",
+};
 
 interface ProductsTableProps {
     data: Product[];
@@ -105,6 +122,63 @@ export default function ProductsTable({ data, pageCount }: ProductsTableProps) {
         }
     }
 
+    // Status Action
+    const changeStatus = async (id: string, status: string) => {
+        try {
+            await axios.post(`/api/admin/products/${id}/status`, { status });
+            toast.success(`Product ${STATUS_LABELS[status].toLowerCase()}`);
+            router.refresh();
+        } catch (error) {
+            console.error(error);
+            const problems: string[] | undefined = axios.isAxiosError(error) ? error.response?.data?.problems : undefined;
+            if (problems?.length) {
+                toast.error(`Cannot publish: ${problems.join(", ")}`);
+            } else {
+                toast.error("Failed to change product status");
+            }
+        }
+    };
+
+    // Model Analysis Action: validates the stored model and flags it if invalid or over budget
+    const analyzeModel = async (id: string) => {
+        try {
+            const { data } = await axios.post(`/api/admin/products/${id}/model/analyze`);
+            if (data.flagged) {
+                toast.warning(data.message);
+            } else {
+                toast.success(data.message);
+            }
+            router.refresh();
+        } catch (error) {
+            console.error(error);
+            toast.error("Failed to analyse the model");
+        }
+    };
+
+    // Thumbnail Action: renders a preview of the stored model on the server
+    const renderThumbnail = async (id: string) => {
+        try {
+            await axios.post(`/api/admin/products/${id}/thumbnail/render`);
+            toast.success("Thumbnail rendered");
+            router.refresh();
+        } catch (error) {
+            console.error(error);
+            toast.error("Failed to render the thumbnail");
+        }
+    };
+
+    // Clone Action: the copy is a draft with a placeholder SKU, opened for editing
+    const cloneProduct = async (id: string) => {
+        try {
+            const { data } = await axios.post(`/api/admin/products/${id}/clone`);
+            toast.success("Product duplicated as a draft");
+            router.push(`/admin/${data.data.id}/edit`);
+        } catch (error) {
+            console.error(error);
+            toast.error("Failed to duplicate product");
+        }
+    };
+
     // Columns Configuration
     const columns: ColumnDef<Product>[] = [
         {
@@ -131,6 +205,30 @@ export default function ProductsTable({ data, pageCount }: ProductsTableProps) {
             header: "Category",
             cell: ({ row }) => <Badge variant="secondary">{row.original.category}</Badge>
         },
+        {
+            accessorKey: "status",
+            header: "Status",
+            cell: ({ row }) => {
+                const { status = "published", publish_at } = row.original;
+                const scheduled = status === "published" && publish_at && new Date(publish_at) > new Date();
+                const { model_flagged, model_metadata } = row.original;
+                return (
+                    <div className="flex gap-1">
+                        <Badge variant={status === "published" ? "default" : "outline"}>
+                            {scheduled ? "Scheduled" : STATUS_LABELS[status]}
+                        </Badge>
+                        {model_flagged && (
+                            <Badge
+                                variant="destructive"
+                                title={[...(model_metadata?.errors || []), ...(model_metadata?.over_budget || [])].join("\n")}
+                            >
+                                {model_metadata?.valid === false ? "Invalid model" : "Over budget"}
+                            </Badge>
+                        )}
+                    </div>
+                );
+            },
+        },
         {
             accessorKey: "sku",
             header: "SKU",
@@ -179,6 +277,29 @@ export default function ProductsTable({ data, pageCount }: ProductsTableProps) {
                                     <Edit className="mr-2 h-4 w-4" /> Edit
                                 </Link>
                             </DropdownMenuItem>
+                            <DropdownMenuItem onClick={() => cloneProduct(product.id)}>
+                                <Copy className="mr-2 h-4 w-4" /> Duplicate
+                            </DropdownMenuItem>
+                            {product.model_url && (
+                                <DropdownMenuItem onClick={() => analyzeModel(product.id)}>
+                                    <ScanSearch className="mr-2 h-4 w-4" /> Analyze Model
+                                </DropdownMenuItem>
+                            )}
+                            {product.model_url && (
+                                <DropdownMenuItem onClick={() => renderThumbnail(product.id)}>
+                                    <Camera className="mr-2 h-4 w-4" /> Render Thumbnail
+                                </DropdownMenuItem>
+                            )}
+                            {product.status !== "published" && product.status !== "archived" && (
+                                <DropdownMenuItem onClick={() => changeStatus(product.id, "published")}>
+                                    <Send className="mr-2 h-4 w-4" /> Publish
+                                </DropdownMenuItem>
+                            )}
+                            {product.status !== "archived" && (
+                                <DropdownMenuItem onClick={() => changeStatus(product.id, "archived")}>
+                                    <Archive className="mr-2 h-4 w-4" /> Archive
+                                </DropdownMenuItem>
+                            )}
                             <DropdownMenuItem
                                 onClick={() => setDeleteId(product.id)}
                                 className="text-red-600 focus:text-red-600"
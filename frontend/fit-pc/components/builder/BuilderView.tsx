@@ -52,6 +52,8 @@ export default function BuilderView() {
     const [showLoadPanel, setShowLoadPanel] = useState(false);
     const [currentBuildId, setCurrentBuildId] = useState<number | null>(null);
     const [currentBuildName, setCurrentBuildName] = useState<string | null>(null);
+    // Version of the loaded build, sent as If-Match so saves never overwrite newer changes
+    const [currentBuildVersion, setCurrentBuildVersion] = useState<number | null>(null);
 
     const currentStep = BUILD_STEPS[currentStepIndex];
     const isStepComplete = (stepId: BuildStepId) => !!buildState[stepId];
@@ -101,7 +103,7 @@ export default function BuilderView() {
         }));
     };
 
-    const handleLoadBuild = (buildId: number, buildName: string, components: SelectedComponent[]) => {
+    const handleLoadBuild = (buildId: number, buildName: string, components: SelectedComponent[], version: number) => {
         // Reset build state
         const newBuildState: BuildState = {};
 
@@ -118,19 +120,22 @@ export default function BuilderView() {
         setBuildState(newBuildState);
         setCurrentBuildId(buildId);
         setCurrentBuildName(buildName);
+        setCurrentBuildVersion(version);
         setCurrentStepIndex(0);
         setShowLoadPanel(false); // Close the load panel after loading
     };
 
-    const handleSaveSuccess = (buildId: number, buildName: string) => {
+    const handleSaveSuccess = (buildId: number, buildName: string, version: number) => {
         setCurrentBuildId(buildId);
         setCurrentBuildName(buildName);
+        setCurrentBuildVersion(version);
     };
 
     const handleNewBuild = () => {
         setBuildState({});
         setCurrentBuildId(null);
         setCurrentBuildName(null);
+        setCurrentBuildVersion(null);
         setCurrentStepIndex(0);
     };
 
@@ -335,6 +340,7 @@ export default function BuilderView() {
                 buildState={buildState}
                 currentBuildId={currentBuildId}
                 currentBuildName={currentBuildName}
+                currentBuildVersion={currentBuildVersion}
                 onSaveSuccess={handleSaveSuccess}
             />
         </div>
@@ -25,7 +25,7 @@ interface Build {
 interface LoadBuildDialogProps {
     open: boolean;
     onOpenChange: (open: boolean) => void;
-    onLoadBuild: (buildId: number, buildName: string, components: SelectedComponent[]) => void;
+    onLoadBuild: (buildId: number, buildName: string, components: SelectedComponent[], version: number) => void;
 }
 
 export default function LoadBuildDialog({
@@ -98,7 +98,7 @@ export default function LoadBuildDialog({
                 quantity: c.quantity,
             }));
 
-            onLoadBuild(build.id, build.name, selectedComponents);
+            onLoadBuild(build.id, build.name, selectedComponents, build.version);
             onOpenChange(false);
         } catch (err) {
             setError(err instanceof Error ? err.message : "Failed to load build");
@@ -17,7 +17,7 @@ interface Build {
 }
 
 interface LoadBuildPanelProps {
-    onLoadBuild: (buildId: number, buildName: string, components: SelectedComponent[]) => void;
+    onLoadBuild: (buildId: number, buildName: string, components: SelectedComponent[], version: number) => void;
     onBack: () => void;
 }
 
@@ -85,7 +85,7 @@ export default function LoadBuildPanel({
                 quantity: c.quantity,
             }));
 
-            onLoadBuild(build.id, build.name, selectedComponents);
+            onLoadBuild(build.id, build.name, selectedComponents, build.version);
         } catch (err) {
             setError(err instanceof Error ? err.message : "Failed to load build");
         } finally {
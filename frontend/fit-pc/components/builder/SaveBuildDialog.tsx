@@ -21,7 +21,8 @@ interface SaveBuildDialogProps {
     buildState: BuildState;
     currentBuildId: number | null;
     currentBuildName: string | null;
-    onSaveSuccess: (buildId: number, buildName: string) => void;
+    currentBuildVersion: number | null;
+    onSaveSuccess: (buildId: number, buildName: string, version: number) => void;
 }
 
 export default function SaveBuildDialog({
@@ -30,6 +31,7 @@ export default function SaveBuildDialog({
     buildState,
     currentBuildId,
     currentBuildName,
+    currentBuildVersion,
     onSaveSuccess,
 }: SaveBuildDialogProps) {
     const [name, setName] = useState(currentBuildName || "");
@@ -69,15 +71,23 @@ export default function SaveBuildDialog({
             const url = isUpdate ? `/api/builds/${currentBuildId}` : "/api/builds";
             const method = isUpdate ? "PUT" : "POST";
 
+            const headers: Record<string, string> = { "Content-Type": "application/json" };
+            if (isUpdate) {
+                headers["If-Match"] = `"${currentBuildVersion ?? 0}"`;
+            }
+
             const response = await fetch(url, {
                 method,
-                headers: { "Content-Type": "application/json" },
+                headers,
                 body: JSON.stringify({
                     name: name.trim(),
                     components: components,
                 }),
             });
 
+            if (response.status === 412) {
+                throw new Error("This build was changed in another session. Reload it or save as a new build.");
+            }
             if (!response.ok) {
                 const data = await response.json();
                 throw new Error(data.error || "Failed to save build");
@@ -86,7 +96,7 @@ export default function SaveBuildDialog({
             const data = await response.json();
             const savedBuild = data.data || data;
 
-            onSaveSuccess(savedBuild.id, name.trim());
+            onSaveSuccess(savedBuild.id, name.trim(), savedBuild.version);
             onOpenChange(false);
         } catch (err) {
             setError(err instanceof Error ? err.message : "Failed to save build");
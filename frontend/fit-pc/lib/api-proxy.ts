@@ -25,8 +25,10 @@ export async function proxyRequest(request: Request, targetEndpoint: string) {
     const finalUrl = `${BACKEND_BASE_URL}${targetEndpoint}${searchParams ? `?${searchParams}` : ""}`;
 
     // Prepare Headers
+    // Keep JSON-based content types such as application/merge-patch+json intact
+    const contentType = request.headers.get("Content-Type");
     const headers: HeadersInit = {
-        "Content-Type": "application/json",
+        "Content-Type": contentType?.includes("json") ? contentType : "application/json",
         "Accept": "application/json",
     };
 
@@ -34,6 +36,14 @@ export async function proxyRequest(request: Request, targetEndpoint: string) {
         headers["Authorization"] = `Bearer ${token}`;
     }
 
+    // Forward conditional request headers used for optimistic concurrency
+    for (const name of ["If-Match", "If-None-Match"]) {
+        const value = request.headers.get(name);
+        if (value) {
+            headers[name] = value;
+        }
+    }
+
     // Prepare Body
     let body = undefined;
     const method = request.method;
@@ -55,6 +65,13 @@ export async function proxyRequest(request: Request, targetEndpoint: string) {
             body,
         });
 
+        const etag = response.headers.get("ETag");
+        const responseHeaders: HeadersInit = etag ? { ETag: etag } : {};
+
+        if (response.status === 304) {
+            return new NextResponse(null, { status: 304, headers: responseHeaders });
+        }
+
         // Handle non-JSON responses or empty bodies
         const responseText = await response.text();
         let data;
@@ -66,10 +83,10 @@ export async function proxyRequest(request: Request, targetEndpoint: string) {
 
         if (!response.ok) {
             console.error(`[Proxy Error] ${method} ${finalUrl}: ${response.status}`, data);
-            return NextResponse.json(data, { status: response.status });
+            return NextResponse.json(data, { status: response.status, headers: responseHeaders });
         }
 
-        return NextResponse.json(data, { status: 200 });
+        return NextResponse.json(data, { status: 200, headers: responseHeaders });
 
     } catch (error) {
         console.error("[Proxy Fatal Error]", error);
@@ -3,6 +3,7 @@ import axios from 'axios';
 interface UploadTokenResponse {
     upload_url: string;
     blob_url: string;
+    blob_name: string;
 }
 
 /**
@@ -11,10 +12,12 @@ interface UploadTokenResponse {
  * Flow:
  * 1. Request SAS Token (upload_url) from Backend.
  * 2. PUT file content to Azure directly.
- * 3. Return the public/accessible blob_url.
+ * 3. Confirm the upload, so the backend verifies it and registers it as an asset.
+ * 4. Return the asset URL. Assets are stored under the hash of their content, so
+ *    identical files resolve to the same URL.
  * 
  * @param file The Browser File object to upload.
- * @returns Promise resolving to the final Blob URL.
+ * @returns Promise resolving to the asset URL.
  */
 export async function uploadFileToAzure(file: File): Promise<string> {
     const filename = encodeURIComponent(file.name);
@@ -26,7 +29,7 @@ export async function uploadFileToAzure(file: File): Promise<string> {
             `/api/admin/upload-token?filename=${filename}`
         );
 
-        const { upload_url, blob_url } = tokenResponse.data;
+        const { upload_url, blob_url, blob_name } = tokenResponse.data;
 
         if (!upload_url || !blob_url) {
             throw new Error("Invalid response from upload-token endpoint.");
@@ -40,7 +43,12 @@ export async function uploadFileToAzure(file: File): Promise<string> {
             },
         });
 
-        return blob_url;
+        // 3. Confirm the upload (only confirmed uploads can be used as models or thumbnails)
+        const completeResponse = await axios.post<{ data: { url: string } }>(
+            `/api/admin/uploads/${encodeURIComponent(blob_name)}/complete`
+        );
+
+        return completeResponse.data.data.url;
     } catch (error: any) {
         console.error("Azure Upload Failed:", error);
 
@@ -1,21 +1,26 @@
 /**
- * Converts Azure Blob Storage URL to proxied URL to bypass CORS
- * @param url Original blob storage URL
- * @returns Proxied URL or original if not a blob URL
+ * Converts a stored model URL to the proxied model route, which streams the blob
+ * from the backend by name whatever the storage backend
+ * @param url Model URL as stored on a product, family or build
+ * @returns Proxied URL, or the URL as-is if it is already proxied
  */
 export function getProxiedModelUrl(url: string | undefined | null): string {
     if (!url) return '';
 
-    // If it's already a local URL or proxy URL, return as-is
-    if (url.startsWith('/api/') || url.startsWith('http://localhost') || url.startsWith('http://127.0.0.1')) {
+    // Already proxied
+    if (url.startsWith('/api/')) {
         return url;
     }
 
-    // If it's an Azure Blob Storage URL, proxy it
-    if (url.includes('blob.core.windows.net')) {
-        return `/api/proxy-model?url=${encodeURIComponent(url)}`;
+    // Model URLs point at blobs of the configured store (Azure or the backend's
+    // local store, whose reads are signed); the blob name is the last path segment
+    try {
+        const blobName = new URL(url).pathname.split('/').pop();
+        if (blobName) {
+            return `/api/models/${encodeURIComponent(decodeURIComponent(blobName))}`;
+        }
+    } catch {
+        // Not an absolute URL
     }
-
-    // Return original URL for other cases
     return url;
 }